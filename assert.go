@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// generateAssert генерирует assert. Условие вида a and b проверяется по частям,
+// чтобы сообщение указывало на невыполненную часть. Проверки обёрнуты
+// в if gopyAssertions: в сборке -release компилятор Go их удаляет
+func (g *Generator) generateAssert(stmt *ast.AssertStatement, inFunction bool) (string, error) {
+	message := "nil"
+	if stmt.Message != nil {
+		code, err := g.generateExpressionWithCast(stmt.Message, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		message = code
+	}
+	g.useHelper("gopyAssert")
+
+	var out strings.Builder
+	out.WriteString("\tif gopyAssertions {\n")
+	for _, cond := range conjuncts(stmt.Condition) {
+		check, err := g.generateAssertCheck(cond, message, stmt.Token.Line, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(indentCode(check))
+	}
+	out.WriteString("\t}\n")
+	return out.String(), nil
+}
+
+// generateAssertCheck генерирует проверку одного условия. Операнды сравнения
+// вычисляются один раз во временные переменные, и их значения попадают
+// в сообщение об ошибке
+func (g *Generator) generateAssertCheck(cond ast.Expression, message string, line int, inFunction bool) (string, error) {
+	var out strings.Builder
+	// Условие записывается так, как оно выглядит в Gopy, с исходными именами
+	source := g.sourceMessage(sourceText(cond))
+	operands := []string{}
+	if infix, ok := cond.(*ast.InfixExpression); ok && infix.Operator != "and" && infix.Operator != "or" {
+		hoist := func(expr ast.Expression) (ast.Expression, error) {
+			if isConstant(expr) {
+				return expr, nil
+			}
+			code, err := g.generateExpressionWithCast(expr, inFunction, false)
+			if err != nil {
+				return nil, err
+			}
+			g.tempCounter++
+			tmp := &ast.Identifier{Token: infix.Token, Value: fmt.Sprintf("gopyTmp%d", g.tempCounter)}
+			out.WriteString(fmt.Sprintf("\t%s := %s\n", tmp.Value, code))
+			g.variableTypes[tmp.Value] = g.staticType(expr)
+			operands = append(operands, fmt.Sprintf("%q, %s", g.sourceMessage(sourceText(expr)), tmp.Value))
+			return tmp, nil
+		}
+		left, err := hoist(infix.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := hoist(infix.Right)
+		if err != nil {
+			return "", err
+		}
+		cond = &ast.InfixExpression{Token: infix.Token, Left: left, Operator: infix.Operator, Right: right}
+	}
+
+	code, err := g.generateExpressionWithCast(cond, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	args := append([]string{fmt.Sprint(line), fmt.Sprintf("%q", source), message}, operands...)
+	out.WriteString(fmt.Sprintf("\tif !(%s) {\n\t\tpanic(gopyAssert(%s))\n\t}\n", g.truthy(code, cond), strings.Join(args, ", ")))
+	return out.String(), nil
+}
+
+// conjuncts разбивает условие a and b and c на части
+func conjuncts(cond ast.Expression) []ast.Expression {
+	if infix, ok := cond.(*ast.InfixExpression); ok && infix.Operator == "and" {
+		return append(conjuncts(infix.Left), conjuncts(infix.Right)...)
+	}
+	return []ast.Expression{cond}
+}
+
+// sourceText возвращает текст выражения Gopy для сообщения assert. В отличие
+// от String, скобки остаются только вокруг вложенных бинарных выражений
+func sourceText(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		operand := func(e ast.Expression) string {
+			if _, ok := e.(*ast.InfixExpression); ok {
+				return "(" + sourceText(e) + ")"
+			}
+			return sourceText(e)
+		}
+		return operand(expr.Left) + " " + expr.Operator + " " + operand(expr.Right)
+	case *ast.IndexExpression:
+		return sourceText(expr.Left) + "[" + sourceText(expr.Index) + "]"
+	}
+	return expr.String()
+}
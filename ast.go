@@ -81,6 +81,47 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// RaiseStatement представляет raise <ошибка> или raise без значения внутри except
+type RaiseStatement struct {
+	Token token.Token // токен 'raise'
+	Value Expression  // nil — повторно выбросить перехваченную ошибку
+}
+
+func (rs *RaiseStatement) statementNode()       {}
+func (rs *RaiseStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *RaiseStatement) String() string {
+	if rs.Value == nil {
+		return "raise"
+	}
+	return "raise " + rs.Value.String()
+}
+
+// AssertStatement представляет assert <условие> [, <сообщение>]
+type AssertStatement struct {
+	Token     token.Token // токен 'assert'
+	Condition Expression
+	Message   Expression // nil, если сообщение не указано
+}
+
+func (as *AssertStatement) statementNode()       {}
+func (as *AssertStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssertStatement) String() string {
+	if as.Message == nil {
+		return "assert " + as.Condition.String()
+	}
+	return "assert " + as.Condition.String() + ", " + as.Message.String()
+}
+
+// ImportStatement представляет import <путь>: import strings, import math/rand
+type ImportStatement struct {
+	Token token.Token // токен 'import'
+	Path  string      // путь пакета через /
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string       { return "import " + is.Path }
+
 // AssignmentStatement представляет присваивание переменной: <name> = <value>;
 type AssignmentStatement struct {
 	Token token.Token // токен '='
@@ -158,9 +199,12 @@ func (b *Boolean) String() string       { return b.Token.Literal }
 
 // FunctionLiteral представляет объявление функции
 type FunctionLiteral struct {
-	Token      token.Token // токен 'def'
-	Parameters []*Identifier
-	Body       *BlockStatement
+	Token          token.Token // токен 'def'
+	Parameters     []*Identifier
+	ParameterTypes map[string]*Identifier // типы параметров (только для указанных)
+	ReturnType     *Identifier            // nil, если тип результата не указан
+	ReturnTypes    []*Identifier          // типы результатов для -> (int, str)
+	Body           *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -177,11 +221,82 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	out.WriteString(resultAnnotation(fl.ReturnType, fl.ReturnTypes))
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// FunctionStatement представляет объявление именованной функции: def <name>(...) ...
+type FunctionStatement struct {
+	Token    token.Token // токен 'def'
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode()       {}
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionStatement) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range fs.Function.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("def ")
+	out.WriteString(fs.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(resultAnnotation(fs.Function.ReturnType, fs.Function.ReturnTypes))
+	out.WriteString(fs.Function.Body.String())
+
+	return out.String()
+}
+
+// resultAnnotation форматирует аннотацию результата функции: "-> int " или "-> (int, str) "
+func resultAnnotation(single *Identifier, multiple []*Identifier) string {
+	if len(multiple) > 0 {
+		types := []string{}
+		for _, t := range multiple {
+			types = append(types, t.String())
+		}
+		return "-> (" + strings.Join(types, ", ") + ") "
+	}
+	if single != nil {
+		return "-> " + single.String() + " "
+	}
+	return ""
+}
+
+// LambdaExpression представляет анонимную функцию: lambda x, y: x + y
+type LambdaExpression struct {
+	Token      token.Token // токен 'lambda'
+	Parameters []*Identifier
+	Body       Expression
+}
+
+func (le *LambdaExpression) expressionNode()      {}
+func (le *LambdaExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *LambdaExpression) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range le.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("lambda")
+	if len(params) > 0 {
+		out.WriteString(" " + strings.Join(params, ", "))
+	}
+	out.WriteString(": ")
+	out.WriteString(le.Body.String())
+
+	return out.String()
+}
+
 // PrefixExpression представляет префиксное выражение (например, -5, !true)
 type PrefixExpression struct {
 	Token    token.Token // Оператор префикса, например token.BANG
@@ -242,6 +357,19 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// KeywordArgument представляет именованный аргумент вызова: name=value
+type KeywordArgument struct {
+	Token token.Token // токен имени аргумента
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode()      {}
+func (ka *KeywordArgument) TokenLiteral() string { return ka.Token.Literal }
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + "=" + ka.Value.String()
+}
+
 // IfExpression представляет условное выражение (if/else)
 type IfExpression struct {
 	Token       token.Token // токен 'if'
@@ -267,6 +395,20 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// ConditionalExpression представляет условное выражение в одну строку: a if cond else b
+type ConditionalExpression struct {
+	Token       token.Token // токен 'if'
+	Consequence Expression
+	Condition   Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	return "(" + ce.Consequence.String() + " if " + ce.Condition.String() + " else " + ce.Alternative.String() + ")"
+}
+
 // BlockStatement представляет блок инструкций (тело if/else, циклов, функций)
 type BlockStatement struct {
 	Token      token.Token // токен '{'
@@ -305,6 +447,138 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// Виды включений
+const (
+	ListComprehension      = "list"
+	DictComprehension      = "dict"
+	SetComprehension       = "set"
+	GeneratorComprehension = "generator"
+)
+
+// ComprehensionExpression представляет включение: [x * 2 for x in xs if x > 0],
+// {k: v for k in ks}, {x for x in xs} или генераторное выражение (x for x in xs)
+type ComprehensionExpression struct {
+	Token   token.Token // токен '[', '{' или '('
+	Kind    string      // ListComprehension, DictComprehension, SetComprehension или GeneratorComprehension
+	Key     Expression  // ключ словаря; nil для остальных видов
+	Element Expression
+	Clauses []*ComprehensionClause
+}
+
+func (ce *ComprehensionExpression) expressionNode()      {}
+func (ce *ComprehensionExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ComprehensionExpression) String() string {
+	var out bytes.Buffer
+	open, close := "[", "]"
+	switch ce.Kind {
+	case DictComprehension, SetComprehension:
+		open, close = "{", "}"
+	case GeneratorComprehension:
+		open, close = "(", ")"
+	}
+	out.WriteString(open)
+	if ce.Key != nil {
+		out.WriteString(ce.Key.String() + ": ")
+	}
+	out.WriteString(ce.Element.String())
+	for _, c := range ce.Clauses {
+		out.WriteString(" " + c.String())
+	}
+	out.WriteString(close)
+	return out.String()
+}
+
+// ComprehensionClause представляет часть включения: for <targets> in <iterable> if <condition>...
+type ComprehensionClause struct {
+	Token      token.Token   // токен 'for'
+	Targets    []*Identifier // переменные цикла; несколько переменных распаковывают элемент
+	Iterable   Expression
+	Conditions []Expression
+}
+
+func (cc *ComprehensionClause) TokenLiteral() string { return cc.Token.Literal }
+func (cc *ComprehensionClause) String() string {
+	var out bytes.Buffer
+	targets := []string{}
+	for _, t := range cc.Targets {
+		targets = append(targets, t.String())
+	}
+	out.WriteString("for " + strings.Join(targets, ", ") + " in " + cc.Iterable.String())
+	for _, cond := range cc.Conditions {
+		out.WriteString(" if " + cond.String())
+	}
+	return out.String()
+}
+
+// TupleLiteral представляет кортеж: a, b или (a, b)
+type TupleLiteral struct {
+	Token    token.Token // первый токен кортежа
+	Elements []Expression
+}
+
+func (tl *TupleLiteral) expressionNode()      {}
+func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TupleLiteral) String() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString(")")
+	return out.String()
+}
+
+// StarredExpression представляет цель распаковки со звёздочкой: *rest
+type StarredExpression struct {
+	Token token.Token // токен '*'
+	Value Expression
+}
+
+func (se *StarredExpression) expressionNode()      {}
+func (se *StarredExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *StarredExpression) String() string {
+	return "*" + se.Value.String()
+}
+
+// HashLiteral представляет литерал словаря: {key: value, ...}
+type HashLiteral struct {
+	Token  token.Token // токен '{'
+	Keys   []Expression
+	Values []Expression // Values[i] соответствует Keys[i]
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for i, key := range hl.Keys {
+		pairs = append(pairs, key.String()+": "+hl.Values[i].String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// SetLiteral представляет литерал множества: {a, b, ...}
+type SetLiteral struct {
+	Token    token.Token // токен '{'
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
 // IndexExpression представляет выражение индексации (например, myArray[0])
 type IndexExpression struct {
 	Token token.Token // токен '['
@@ -324,6 +598,37 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression представляет срез: <left>[<start>:<stop>:<step>], любая граница может отсутствовать
+type SliceExpression struct {
+	Token token.Token // токен '['
+	Left  Expression
+	Start Expression // nil, если не указано
+	Stop  Expression // nil, если не указано
+	Step  Expression // nil, если не указано
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.Stop != nil {
+		out.WriteString(se.Stop.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
 // ForStatement представляет цикл for
 type ForStatement struct {
 	Token    token.Token // токен 'for'
@@ -349,7 +654,8 @@ func (fs *ForStatement) String() string {
 type ClassStatement struct {
 	Token    token.Token // токен 'class'
 	Name     *Identifier
-	Fields   []*Identifier // новые поля
+	Base     *Identifier // вид ошибки, от которого наследует класс ошибки; nil — обычный класс
+	Fields   []*ClassField
 	Methods  []*MethodStatement
 }
 
@@ -359,7 +665,13 @@ func (cs *ClassStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString("class ")
 	out.WriteString(cs.Name.String())
+	if cs.Base != nil {
+		out.WriteString("(" + cs.Base.String() + ")")
+	}
 	out.WriteString("\n")
+	for _, f := range cs.Fields {
+		out.WriteString("    " + f.String() + "\n")
+	}
 	for _, m := range cs.Methods {
 		out.WriteString(m.String())
 	}
@@ -368,12 +680,36 @@ func (cs *ClassStatement) String() string {
 
 func (cs *ClassStatement) expressionNode() {}
 
+// ClassField представляет поле класса: <name>[: <type>] [= <default>]
+type ClassField struct {
+	Token   token.Token // токен имени поля
+	Name    *Identifier
+	Type    *Identifier // nil, если тип не указан
+	Default Expression  // nil, если значение по умолчанию не задано
+}
+
+func (cf *ClassField) TokenLiteral() string { return cf.Token.Literal }
+func (cf *ClassField) String() string {
+	var out bytes.Buffer
+	out.WriteString(cf.Name.String())
+	if cf.Type != nil {
+		out.WriteString(": " + cf.Type.String())
+	}
+	if cf.Default != nil {
+		out.WriteString(" = " + cf.Default.String())
+	}
+	return out.String()
+}
+
 // MethodStatement представляет объявление метода внутри класса
 type MethodStatement struct {
-	Token      token.Token // токен 'def'
-	Name       *Identifier
-	Parameters []*Identifier
-	Body       *BlockStatement
+	Token          token.Token // токен 'def'
+	Name           *Identifier
+	Parameters     []*Identifier
+	ParameterTypes map[string]*Identifier // типы параметров (только для указанных)
+	ReturnType     *Identifier            // nil, если тип результата не указан
+	ReturnTypes    []*Identifier          // типы результатов для -> (int, str)
+	Body           *BlockStatement
 }
 
 func (ms *MethodStatement) statementNode()       {}
@@ -389,10 +725,62 @@ func (ms *MethodStatement) String() string {
 	}
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	out.WriteString(resultAnnotation(ms.ReturnType, ms.ReturnTypes))
 	out.WriteString(ms.Body.String())
 	return out.String()
 }
 
+// InterfaceStatement представляет объявление интерфейса: interface <name> ...
+type InterfaceStatement struct {
+	Token   token.Token // токен 'interface'
+	Name    *Identifier
+	Methods []*MethodSignature
+}
+
+func (is *InterfaceStatement) statementNode()       {}
+func (is *InterfaceStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *InterfaceStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("interface ")
+	out.WriteString(is.Name.String())
+	out.WriteString("\n")
+	for _, m := range is.Methods {
+		out.WriteString("    " + m.String() + "\n")
+	}
+	return out.String()
+}
+
+// MethodSignature представляет метод интерфейса без тела: def <name>(...) -> <type>
+type MethodSignature struct {
+	Token          token.Token // токен 'def'
+	Name           *Identifier
+	Parameters     []*Identifier
+	ParameterTypes map[string]*Identifier
+	ReturnType     *Identifier
+}
+
+func (ms *MethodSignature) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MethodSignature) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ms.Parameters {
+		param := p.String()
+		if typ, ok := ms.ParameterTypes[p.Value]; ok {
+			param += ": " + typ.String()
+		}
+		params = append(params, param)
+	}
+	out.WriteString("def ")
+	out.WriteString(ms.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if ms.ReturnType != nil {
+		out.WriteString(" -> " + ms.ReturnType.String())
+	}
+	return out.String()
+}
+
 // DotExpression представляет обращение через точку: obj.field или obj.method()
 type DotExpression struct {
 	Token    token.Token // токен '.'
@@ -408,4 +796,322 @@ func (de *DotExpression) String() string {
 	out.WriteString(".")
 	out.WriteString(de.Right.String())
 	return out.String()
-}
\ No newline at end of file
+}
+
+// MatchStatement представляет сопоставление с образцом: match <subject> с ветками case
+type MatchStatement struct {
+	Token   token.Token // токен 'match'
+	Subject Expression
+	Cases   []*MatchCase
+}
+
+func (ms *MatchStatement) statementNode()       {}
+func (ms *MatchStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MatchStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("match ")
+	out.WriteString(ms.Subject.String())
+	out.WriteString(" {\n")
+	for _, c := range ms.Cases {
+		out.WriteString(c.String())
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// MatchCase представляет ветку case <pattern> [if <guard>]
+type MatchCase struct {
+	Token   token.Token // токен 'case'
+	Pattern Pattern
+	Guard   Expression // nil, если условия нет
+	Body    *BlockStatement
+}
+
+func (mc *MatchCase) TokenLiteral() string { return mc.Token.Literal }
+func (mc *MatchCase) String() string {
+	var out bytes.Buffer
+	out.WriteString("case ")
+	out.WriteString(mc.Pattern.String())
+	if mc.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(mc.Guard.String())
+	}
+	out.WriteString(" ")
+	out.WriteString(mc.Body.String())
+	return out.String()
+}
+
+// Pattern представляет образец ветки case
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// LiteralPattern сопоставляет значение с литералом: 1, -1, "start", true
+type LiteralPattern struct {
+	Token token.Token
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// CapturePattern совпадает с любым значением и связывает его с именем
+type CapturePattern struct {
+	Token token.Token
+	Name  *Identifier
+}
+
+func (cp *CapturePattern) patternNode()         {}
+func (cp *CapturePattern) TokenLiteral() string { return cp.Token.Literal }
+func (cp *CapturePattern) String() string       { return cp.Name.String() }
+
+// WildcardPattern (_) совпадает с любым значением, ничего не связывая
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// OrPattern совпадает, если совпала хотя бы одна из альтернатив: 1 | 2
+type OrPattern struct {
+	Token        token.Token // токен '|'
+	Alternatives []Pattern
+}
+
+func (op *OrPattern) patternNode()         {}
+func (op *OrPattern) TokenLiteral() string { return op.Token.Literal }
+func (op *OrPattern) String() string {
+	alternatives := []string{}
+	for _, a := range op.Alternatives {
+		alternatives = append(alternatives, a.String())
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+// ClassPattern проверяет класс объекта и сопоставляет его поля: User(name=n).
+// Позиционные образцы относятся к полям в порядке их объявления
+type ClassPattern struct {
+	Token     token.Token // имя класса
+	Class     *Identifier
+	Arguments []Pattern
+	Keywords  []*KeywordPattern
+}
+
+func (cp *ClassPattern) patternNode()         {}
+func (cp *ClassPattern) TokenLiteral() string { return cp.Token.Literal }
+func (cp *ClassPattern) String() string {
+	args := []string{}
+	for _, a := range cp.Arguments {
+		args = append(args, a.String())
+	}
+	for _, k := range cp.Keywords {
+		args = append(args, k.String())
+	}
+	return cp.Class.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// KeywordPattern — образец поля в образце класса: name=n
+type KeywordPattern struct {
+	Token   token.Token // имя поля
+	Name    *Identifier
+	Pattern Pattern
+}
+
+func (kp *KeywordPattern) TokenLiteral() string { return kp.Token.Literal }
+func (kp *KeywordPattern) String() string       { return kp.Name.String() + "=" + kp.Pattern.String() }
+
+// ListPattern сопоставляет список поэлементно: [first, *rest]
+type ListPattern struct {
+	Token    token.Token // токен '['
+	Elements []Pattern
+}
+
+func (lp *ListPattern) patternNode()         {}
+func (lp *ListPattern) TokenLiteral() string { return lp.Token.Literal }
+func (lp *ListPattern) String() string {
+	elements := []string{}
+	for _, el := range lp.Elements {
+		elements = append(elements, el.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// StarPattern связывает оставшиеся элементы списка: *rest
+type StarPattern struct {
+	Token token.Token // токен '*'
+	Name  *Identifier
+}
+
+func (sp *StarPattern) patternNode()         {}
+func (sp *StarPattern) TokenLiteral() string { return sp.Token.Literal }
+func (sp *StarPattern) String() string       { return "*" + sp.Name.String() }
+
+// TryStatement представляет try с ветками except и необязательным finally
+type TryStatement struct {
+	Token    token.Token // токен 'try'
+	Body     *BlockStatement
+	Handlers []*ExceptClause
+	Finally  *BlockStatement // nil, если finally нет
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(ts.Body.String())
+	for _, h := range ts.Handlers {
+		out.WriteString(h.String())
+	}
+	if ts.Finally != nil {
+		out.WriteString("finally ")
+		out.WriteString(ts.Finally.String())
+	}
+	return out.String()
+}
+
+// ExceptClause представляет ветку except [Вид, ...] [as name]
+type ExceptClause struct {
+	Token token.Token // токен 'except'
+	Kinds []Expression // пусто — перехватываются все ошибки
+	Name  *Identifier  // nil, если as нет
+	Body  *BlockStatement
+}
+
+func (ec *ExceptClause) TokenLiteral() string { return ec.Token.Literal }
+func (ec *ExceptClause) String() string {
+	var out bytes.Buffer
+	out.WriteString("except")
+	kinds := []string{}
+	for _, k := range ec.Kinds {
+		kinds = append(kinds, k.String())
+	}
+	if len(kinds) > 0 {
+		out.WriteString(" " + strings.Join(kinds, ", "))
+	}
+	if ec.Name != nil {
+		out.WriteString(" as " + ec.Name.String())
+	}
+	out.WriteString(" ")
+	out.WriteString(ec.Body.String())
+	return out.String()
+}
+
+// OrElseExpression представляет value or else default: default вычисляется,
+// только если при вычислении value произошла ошибка
+type OrElseExpression struct {
+	Token   token.Token // токен 'or else'
+	Value   Expression
+	Default Expression
+}
+
+func (oe *OrElseExpression) expressionNode()      {}
+func (oe *OrElseExpression) TokenLiteral() string { return oe.Token.Literal }
+func (oe *OrElseExpression) String() string {
+	return "(" + oe.Value.String() + " or else " + oe.Default.String() + ")"
+}
+
+// GoStatement представляет запуск функции в горутине: go f(x)
+type GoStatement struct {
+	Token token.Token // токен 'go'
+	Call  *CallExpression
+}
+
+func (gs *GoStatement) statementNode()       {}
+func (gs *GoStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GoStatement) String() string       { return "go " + gs.Call.String() }
+
+// DeferStatement представляет отложенный вызов: defer f.close(). Вызов выполняется
+// при выходе из функции или из блока with/try
+type DeferStatement struct {
+	Token token.Token // токен 'defer'
+	Call  *CallExpression
+}
+
+func (ds *DeferStatement) statementNode()       {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string       { return "defer " + ds.Call.String() }
+
+// SendStatement представляет отправку значения в канал: ch <- v
+type SendStatement struct {
+	Token   token.Token // токен '<-'
+	Channel Expression
+	Value   Expression
+}
+
+func (ss *SendStatement) statementNode()       {}
+func (ss *SendStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SendStatement) String() string {
+	return ss.Channel.String() + " <- " + ss.Value.String()
+}
+
+// ReceiveExpression представляет получение значения из канала: <-ch
+type ReceiveExpression struct {
+	Token   token.Token // токен '<-'
+	Channel Expression
+}
+
+func (re *ReceiveExpression) expressionNode()      {}
+func (re *ReceiveExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *ReceiveExpression) String() string       { return "<-" + re.Channel.String() }
+
+// SelectStatement представляет ожидание первой готовой операции с каналами
+type SelectStatement struct {
+	Token token.Token // токен 'select'
+	Cases []*SelectCase
+}
+
+func (ss *SelectStatement) statementNode()       {}
+func (ss *SelectStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SelectStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("select {\n")
+	for _, c := range ss.Cases {
+		out.WriteString(c.String())
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// SelectCase представляет ветку select: case v = <-ch, case <-ch, case ch <- v
+// или default
+type SelectCase struct {
+	Token token.Token // токен 'case' или 'default'
+	Comm  Statement   // nil для default
+	Body  *BlockStatement
+}
+
+func (sc *SelectCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SelectCase) String() string {
+	if sc.Comm == nil {
+		return "default " + sc.Body.String()
+	}
+	return "case " + sc.Comm.String() + " " + sc.Body.String()
+}
+
+// WithStatement представляет with <значение> [as <имя>]: блок, на время которого
+// значение захватывается и после которого гарантированно освобождается
+type WithStatement struct {
+	Token token.Token // токен 'with'
+	Value Expression
+	Name  *Identifier // nil, если as нет
+	Body  *BlockStatement
+}
+
+func (ws *WithStatement) statementNode()       {}
+func (ws *WithStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WithStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("with ")
+	out.WriteString(ws.Value.String())
+	if ws.Name != nil {
+		out.WriteString(" as " + ws.Name.String())
+	}
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+	return out.String()
+}
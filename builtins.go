@@ -0,0 +1,476 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// builtin описывает встроенную функцию Gopy: сколько аргументов она принимает,
+// какого они типа, какой тип у результата и как генерируется её вызов
+type builtin struct {
+	minArgs int
+	maxArgs int // -1 — без ограничения
+	// params — допустимые типы позиционных аргументов через "|", последний
+	// относится ко всем следующим аргументам; "" — любой тип
+	params []string
+	// keywords — именованные аргументы и их типы; anyKeywords разрешает любые
+	keywords    map[string]string
+	anyKeywords bool
+	// result — тип результата Gopy; resultOf вычисляет его по аргументам вызова
+	result   string
+	resultOf func(g *Generator, call *ast.CallExpression) string
+	generate func(g *Generator, call *ast.CallExpression, inFunction bool) (string, error)
+}
+
+// builtins — встроенные функции Gopy. Заполняется в init, потому что генерация
+// вызовов сама обращается к реестру
+var builtins map[string]*builtin
+
+// methods — методы встроенных типов Gopy по имени типа: s.split(), xs.append(x).
+// Заполняется в init файлов с методами каждого типа
+var methods = map[string]map[string]*builtin{}
+
+func init() {
+	builtins = map[string]*builtin{
+		"print": {maxArgs: -1, keywords: map[string]string{"sep": "str", "end": "str"},
+			generate: (*Generator).generatePrint},
+		"len": {minArgs: 1, maxArgs: 1, params: []string{"str|list|tuple|dict|set|chan"},
+			result: "int", generate: (*Generator).generateLen},
+		"input": {maxArgs: 1, params: []string{"str"}, result: "str", generate: (*Generator).generateInput},
+		"str":   {maxArgs: 1, result: "str", generate: (*Generator).generateStr},
+		"int": {maxArgs: 1, params: []string{"int|float|str|bool"},
+			result: "int", generate: (*Generator).generateInt},
+		"float": {maxArgs: 1, params: []string{"int|float|str|bool"},
+			result: "float", generate: (*Generator).generateFloat},
+		"abs": {minArgs: 1, maxArgs: 1, params: []string{"int|float"},
+			resultOf: numericResult, generate: (*Generator).generateAbs},
+		"min": {minArgs: 1, maxArgs: -1, resultOf: extremeResult, generate: (*Generator).generateExtreme},
+		"max": {minArgs: 1, maxArgs: -1, resultOf: extremeResult, generate: (*Generator).generateExtreme},
+		"sum": {minArgs: 1, maxArgs: 1, params: []string{"list|tuple"}, result: "int", generate: (*Generator).generateSum},
+		"sorted": {minArgs: 1, maxArgs: 1, params: []string{"list|tuple"},
+			keywords: map[string]string{"key": "func", "reverse": "bool"},
+			result:   "list", generate: (*Generator).generateSorted},
+
+		"chan":  {maxArgs: 1, params: []string{"int"}, result: "chan", generate: (*Generator).generateChan},
+		"close": {minArgs: 1, maxArgs: 1, params: []string{"chan"}, generate: (*Generator).generateClose},
+		"Lock":  {result: "Lock", generate: (*Generator).generateLock},
+		"parallel_map": {minArgs: 2, maxArgs: 3, params: []string{"func", "list", "int"},
+			keywords: map[string]string{"workers": "int"},
+			result:   "list", generate: (*Generator).generateParallelMap},
+		"open": {minArgs: 1, maxArgs: 2, params: []string{"str"}, result: "file", generate: (*Generator).generateOpen},
+	}
+}
+
+// lookupBuiltin возвращает встроенную функцию, которую вызывает fn. Переменная,
+// функция или класс с тем же именем скрывают встроенную функцию
+func (g *Generator) lookupBuiltin(fn ast.Expression) (string, *builtin) {
+	ident, ok := fn.(*ast.Identifier)
+	if !ok {
+		return "", nil
+	}
+	b := builtins[ident.Value]
+	if b == nil || g.functionSignatures[ident.Value] != nil || g.isClass(ident.Value) {
+		return "", nil
+	}
+	if _, declared := g.lookupVariable(ident.Value); declared {
+		return "", nil
+	}
+	return ident.Value, b
+}
+
+// lookupMethod возвращает метод встроенного типа, который вызывает fn: для s.split
+// при s типа str — "str.split". known сообщает, что у типа получателя есть методы
+// встроенного типа, даже если метода с таким именем нет
+func (g *Generator) lookupMethod(fn ast.Expression) (name string, method *builtin, known bool) {
+	dot, ok := fn.(*ast.DotExpression)
+	if !ok || dot.Right == nil {
+		return "", nil, false
+	}
+	typ := g.staticType(dot.Left)
+	if typ == "" {
+		typ = g.guessMethodType(dot.Right.Value)
+	}
+	typeMethods, known := methods[typ]
+	if !known {
+		return "", nil, false
+	}
+	return typ + "." + dot.Right.Value, typeMethods[dot.Right.Value], true
+}
+
+// guessMethodType возвращает встроенный тип для метода значения неизвестного типа:
+// w.upper() относится к строке, если upper есть только у строк и ни один класс
+// не объявляет такой метод
+func (g *Generator) guessMethodType(name string) string {
+	for _, class := range g.declaredClasses {
+		if findMethod(class, name) != nil {
+			return ""
+		}
+	}
+	found := ""
+	for typ, typeMethods := range methods {
+		if typeMethods[name] != nil {
+			if found != "" {
+				return ""
+			}
+			found = typ
+		}
+	}
+	return found
+}
+
+// hasBuiltinMethods сообщает, что метод dot генерирует generateCall:
+// у файлов и встроенных типов нет методов Go с теми же именами, а функции
+// пакетов Go проверяются по их сигнатурам
+func (g *Generator) hasBuiltinMethods(dot *ast.DotExpression) bool {
+	if g.staticType(dot.Left) == "file" {
+		return true
+	}
+	if _, isGo, _ := g.goMember(dot); isGo {
+		return true
+	}
+	_, _, known := g.lookupMethod(dot)
+	return known
+}
+
+// generateBuiltin проверяет число и типы аргументов встроенной функции
+// и генерирует её вызов
+func (g *Generator) generateBuiltin(name string, b *builtin, call *ast.CallExpression, inFunction bool) (string, error) {
+	positional := positionalArguments(call)
+	if len(positional) < b.minArgs || (b.maxArgs >= 0 && len(positional) > b.maxArgs) {
+		return "", fmt.Errorf("%s ожидает %s аргументов, получено %d", name, arityText(b.minArgs, b.maxArgs), len(positional))
+	}
+	for i, arg := range positional {
+		if len(b.params) == 0 {
+			break
+		}
+		param := b.params[len(b.params)-1]
+		if i < len(b.params) {
+			param = b.params[i]
+		}
+		if err := g.checkBuiltinArgument(name, fmt.Sprint(i+1), param, arg); err != nil {
+			return "", err
+		}
+	}
+	for _, arg := range call.Arguments {
+		kw, ok := arg.(*ast.KeywordArgument)
+		if !ok {
+			continue
+		}
+		param, known := b.keywords[kw.Name.Value]
+		if !known && !b.anyKeywords {
+			return "", fmt.Errorf("%s: неизвестный аргумент %s", name, kw.Name.Value)
+		}
+		if err := g.checkBuiltinArgument(name, kw.Name.Value, param, kw.Value); err != nil {
+			return "", err
+		}
+	}
+	return b.generate(g, call, inFunction)
+}
+
+// checkBuiltinArgument проверяет, что тип аргумента входит в допустимые типы param
+func (g *Generator) checkBuiltinArgument(name, label, param string, arg ast.Expression) error {
+	typ := g.staticType(arg)
+	if param == "" || typ == "" {
+		return nil
+	}
+	allowed := strings.Split(param, "|")
+	for _, t := range allowed {
+		if t == typ {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: аргумент %s должен иметь тип %s, получено %s", name, label, strings.Join(allowed, " или "), typ)
+}
+
+// arityText описывает допустимое число аргументов: 1, от 0 до 1, не менее 1
+func arityText(min, max int) string {
+	switch {
+	case min == max:
+		return fmt.Sprint(min)
+	case max < 0:
+		return fmt.Sprintf("не менее %d", min)
+	}
+	return fmt.Sprintf("от %d до %d", min, max)
+}
+
+// positionalArguments возвращает аргументы вызова без именованных
+func positionalArguments(call *ast.CallExpression) []ast.Expression {
+	args := []ast.Expression{}
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.KeywordArgument); !ok {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// keywordArgument возвращает значение именованного аргумента или nil
+func keywordArgument(call *ast.CallExpression, name string) ast.Expression {
+	for _, arg := range call.Arguments {
+		if kw, ok := arg.(*ast.KeywordArgument); ok && kw.Name.Value == name {
+			return kw.Value
+		}
+	}
+	return nil
+}
+
+// sequenceValue возвращает элементы списка или кортежа как []interface{}
+func (g *Generator) sequenceValue(code string, expr ast.Expression) string {
+	if g.staticType(expr) == "tuple" {
+		return code + ".gopyItems()"
+	}
+	return g.coerce(code, expr, "list") + ".items"
+}
+
+// numericResult — тип результата abs: float для float, иначе int
+func numericResult(g *Generator, call *ast.CallExpression) string {
+	if args := positionalArguments(call); len(args) == 1 && g.staticType(args[0]) == "float" {
+		return "float"
+	}
+	return "int"
+}
+
+// extremeResult — тип результата min и max: общий тип аргументов, если их
+// несколько и он известен, иначе неизвестный тип элемента последовательности
+func extremeResult(g *Generator, call *ast.CallExpression) string {
+	args := positionalArguments(call)
+	if len(args) < 2 {
+		return ""
+	}
+	typ := g.staticType(args[0])
+	for _, arg := range args[1:] {
+		if g.staticType(arg) != typ {
+			return ""
+		}
+	}
+	if typ == "int" || typ == "float" || typ == "str" {
+		return typ
+	}
+	return ""
+}
+
+// generatePrint генерирует print(a, b, sep=" ", end="\n"). Значения, кроме строк
+// и целых чисел, форматируются как в Python: [1, 'a'], True, None
+func (g *Generator) generatePrint(call *ast.CallExpression, inFunction bool) (string, error) {
+	sep, end := keywordArgument(call, "sep"), keywordArgument(call, "end")
+	values := []string{}
+	for _, arg := range positionalArguments(call) {
+		code, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		typ := g.staticType(arg)
+		if typ != "str" && (typ != "int" || sep != nil || end != nil) {
+			g.useHelper("gopyStr")
+			code = fmt.Sprintf("gopyStr(%s)", code)
+		}
+		values = append(values, code)
+	}
+	if sep == nil && end == nil {
+		return fmt.Sprintf("fmt.Println(%s)", strings.Join(values, ", ")), nil
+	}
+
+	options := []string{`" "`, `"\n"`}
+	for i, expr := range []ast.Expression{sep, end} {
+		if expr == nil {
+			continue
+		}
+		code, err := g.generateExpressionWithCast(expr, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		options[i] = g.coerce(code, expr, "str")
+	}
+	g.imports["strings"] = true
+	return fmt.Sprintf("fmt.Print(strings.Join([]string{%s}, %s) + %s)", strings.Join(values, ", "), options[0], options[1]), nil
+}
+
+// generateLen генерирует len(x). Длина строки считается в символах, а не в байтах
+func (g *Generator) generateLen(call *ast.CallExpression, inFunction bool) (string, error) {
+	arg := call.Arguments[0]
+	code, err := g.generateExpressionWithCast(arg, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	switch g.staticType(arg) {
+	case "":
+		g.useHelper("gopyLen")
+		return fmt.Sprintf("gopyLen(%s, %d)", code, call.Token.Line), nil
+	case "str":
+		return fmt.Sprintf("int64(len([]rune(%s)))", code), nil
+	case "list":
+		return fmt.Sprintf("int64(len(%s.items))", code), nil
+	case "tuple":
+		return fmt.Sprintf("int64(len(%s.gopyItems()))", code), nil
+	}
+	return fmt.Sprintf("int64(len(%s))", code), nil
+}
+
+// generateInput генерирует input(prompt): строка читается из стандартного ввода
+func (g *Generator) generateInput(call *ast.CallExpression, inFunction bool) (string, error) {
+	prompt := `""`
+	if len(call.Arguments) == 1 {
+		code, err := g.generateExpressionWithCast(call.Arguments[0], inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		prompt = g.coerce(code, call.Arguments[0], "str")
+	}
+	g.useHelper("gopyInput")
+	return fmt.Sprintf("gopyInput(%s, %d)", prompt, call.Token.Line), nil
+}
+
+// generateStr генерирует str(x): значение форматируется так же, как в print
+func (g *Generator) generateStr(call *ast.CallExpression, inFunction bool) (string, error) {
+	if len(call.Arguments) == 0 {
+		return `""`, nil
+	}
+	arg := call.Arguments[0]
+	code, err := g.generateExpressionWithCast(arg, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	switch g.staticType(arg) {
+	case "str":
+		return code, nil
+	case "int":
+		return fmt.Sprintf("fmt.Sprint(%s)", code), nil
+	}
+	g.useHelper("gopyStr")
+	return fmt.Sprintf("gopyStr(%s)", code), nil
+}
+
+// generateInt генерирует int(x): дробная часть отбрасывается, строка разбирается
+// как десятичное число
+func (g *Generator) generateInt(call *ast.CallExpression, inFunction bool) (string, error) {
+	if len(call.Arguments) == 0 {
+		return "int64(0)", nil
+	}
+	arg := call.Arguments[0]
+	code, err := g.generateExpressionWithCast(arg, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	switch g.staticType(arg) {
+	case "int":
+		return g.intValue(code, arg), nil
+	case "float":
+		return fmt.Sprintf("int64(%s)", code), nil
+	}
+	g.useHelper("gopyToInt")
+	return fmt.Sprintf("gopyToInt(%s, %d)", code, call.Token.Line), nil
+}
+
+// generateFloat генерирует float(x)
+func (g *Generator) generateFloat(call *ast.CallExpression, inFunction bool) (string, error) {
+	if len(call.Arguments) == 0 {
+		return "float64(0)", nil
+	}
+	arg := call.Arguments[0]
+	code, err := g.generateExpressionWithCast(arg, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	switch g.staticType(arg) {
+	case "int":
+		return fmt.Sprintf("float64(%s)", code), nil
+	case "float":
+		return code, nil
+	}
+	g.useHelper("gopyToFloat")
+	return fmt.Sprintf("gopyToFloat(%s, %d)", code, call.Token.Line), nil
+}
+
+// generateAbs генерирует abs(x); значение неизвестного типа считается целым
+func (g *Generator) generateAbs(call *ast.CallExpression, inFunction bool) (string, error) {
+	arg := call.Arguments[0]
+	code, err := g.generateExpressionWithCast(arg, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	if g.staticType(arg) != "float" {
+		code = g.intValue(code, arg)
+	}
+	g.useHelper("gopyAbs")
+	return fmt.Sprintf("gopyAbs(%s)", code), nil
+}
+
+// generateExtreme генерирует min и max. Значения одного известного типа
+// сравниваются функцией-литералом с этим типом (встроенные min и max Go
+// появились только в Go 1.21), остальные — во время выполнения
+func (g *Generator) generateExtreme(call *ast.CallExpression, inFunction bool) (string, error) {
+	name := call.Function.String()
+	args := call.Arguments
+	values := []string{}
+	for _, arg := range args {
+		code, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, code)
+	}
+
+	if typ := extremeResult(g, call); typ != "" {
+		if typ == "int" {
+			for i, arg := range args {
+				values[i] = g.intValue(values[i], arg)
+			}
+		}
+		goTyp, op := goTypes[typ], "<"
+		if name == "max" {
+			op = ">"
+		}
+		return fmt.Sprintf("func(values ...%s) %s { m := values[0]; for _, v := range values[1:] { if v %s m { m = v } }; return m }(%s)",
+			goTyp, goTyp, op, strings.Join(values, ", ")), nil
+	}
+	items := ""
+	if len(args) == 1 {
+		if typ := g.staticType(args[0]); typ != "" && typ != "list" && typ != "tuple" {
+			return "", fmt.Errorf("%s: аргумент 1 должен иметь тип list или tuple, получено %s", name, typ)
+		}
+		items = g.sequenceValue(values[0], args[0])
+	} else {
+		for i, arg := range args {
+			values[i] = g.boxValue(values[i], arg)
+		}
+		items = fmt.Sprintf("[]interface{}{%s}", strings.Join(values, ", "))
+	}
+	g.useHelper("gopyExtreme")
+	return fmt.Sprintf("gopyExtreme(%q, %s, %t, %d)", name, items, name == "max", call.Token.Line), nil
+}
+
+// generateSum генерирует sum(xs) для списка или кортежа целых
+func (g *Generator) generateSum(call *ast.CallExpression, inFunction bool) (string, error) {
+	arg := call.Arguments[0]
+	code, err := g.generateExpressionWithCast(arg, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	g.useHelper("gopySum")
+	return fmt.Sprintf("gopySum(%s, %d)", g.sequenceValue(code, arg), call.Token.Line), nil
+}
+
+// generateSorted генерирует sorted(xs, key=None, reverse=false): возвращает
+// новый отсортированный список, исходный не меняется
+func (g *Generator) generateSorted(call *ast.CallExpression, inFunction bool) (string, error) {
+	arg := positionalArguments(call)[0]
+	code, err := g.generateExpressionWithCast(arg, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	key, reverse := "nil", "false"
+	if expr := keywordArgument(call, "key"); expr != nil {
+		if key, err = g.generateExpressionWithCast(expr, inFunction, false); err != nil {
+			return "", err
+		}
+	}
+	if expr := keywordArgument(call, "reverse"); expr != nil {
+		if reverse, err = g.generateExpressionWithCast(expr, inFunction, false); err != nil {
+			return "", err
+		}
+		reverse = g.coerce(reverse, expr, "bool")
+	}
+	g.useHelper("gopySorted")
+	return fmt.Sprintf("gopySorted(%s, %s, %s, %d)", g.sequenceValue(code, arg), key, reverse, call.Token.Line), nil
+}
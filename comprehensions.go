@@ -0,0 +1,288 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// generateComprehension генерирует включение как немедленно вызываемую функцию Go
+// с вложенными циклами. Генераторное выражение вне sum/any/all вычисляется в список
+func (g *Generator) generateComprehension(comp *ast.ComprehensionExpression) (string, error) {
+	g.enterClosure()
+	defer g.leaveClosure()
+
+	var typ, init string
+	switch comp.Kind {
+	case ast.DictComprehension:
+		typ, init = "map[interface{}]interface{}", "map[interface{}]interface{}{}"
+	case ast.SetComprehension:
+		typ, init = "map[interface{}]struct{}", "map[interface{}]struct{}{}"
+	default:
+		g.useHelper("gopyList")
+		typ, init = "*gopyList", "gopyNewList()"
+	}
+
+	loops, err := g.generateLoops(comp.Clauses, func(indent string) (string, error) {
+		element, err := g.generateExpressionWithCast(comp.Element, true, false)
+		if err != nil {
+			return "", err
+		}
+		element = g.boxValue(element, comp.Element)
+		var add string
+		switch comp.Kind {
+		case ast.DictComprehension:
+			key, err := g.generateExpressionWithCast(comp.Key, true, false)
+			if err != nil {
+				return "", err
+			}
+			add = fmt.Sprintf("gopyResult[%s] = %s", g.boxValue(key, comp.Key), element)
+		case ast.SetComprehension:
+			add = fmt.Sprintf("gopyResult[%s] = struct{}{}", element)
+		default:
+			add = fmt.Sprintf("gopyResult.append(%s)", element)
+		}
+		return indent + add + "\n", nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("func() %s {\n\tgopyResult := %s\n%s\treturn gopyResult\n}()", typ, init, loops), nil
+}
+
+// generateReduction встраивает генераторное выражение, переданное в sum, any или all,
+// прямо в цикл: промежуточный список не создаётся, а any и all останавливаются
+// на первом подходящем элементе
+func (g *Generator) generateReduction(name string, comp *ast.ComprehensionExpression) (string, error) {
+	g.enterClosure()
+	defer g.leaveClosure()
+
+	loops, err := g.generateLoops(comp.Clauses, func(indent string) (string, error) {
+		element, err := g.generateExpressionWithCast(comp.Element, true, false)
+		if err != nil {
+			return "", err
+		}
+		switch name {
+		case "sum":
+			return fmt.Sprintf("%sgopyTotal += %s\n", indent, g.intValue(element, comp.Element)), nil
+		case "any":
+			return fmt.Sprintf("%sif %s {\n%s\treturn true\n%s}\n", indent, g.truthy(element, comp.Element), indent, indent), nil
+		default:
+			return fmt.Sprintf("%sif !%s {\n%s\treturn false\n%s}\n", indent, g.truthy(element, comp.Element), indent, indent), nil
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	switch name {
+	case "sum":
+		return fmt.Sprintf("func() int64 {\n\tgopyTotal := int64(0)\n%s\treturn gopyTotal\n}()", loops), nil
+	case "any":
+		return fmt.Sprintf("func() bool {\n%s\treturn false\n}()", loops), nil
+	default:
+		return fmt.Sprintf("func() bool {\n%s\treturn true\n}()", loops), nil
+	}
+}
+
+// isReduction сообщает, что вызов — sum, any или all от генераторного выражения
+func (g *Generator) isReduction(call *ast.CallExpression) (string, *ast.ComprehensionExpression, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || len(call.Arguments) != 1 || g.functionSignatures[ident.Value] != nil {
+		return "", nil, false
+	}
+	if _, declared := g.lookupVariable(ident.Value); declared {
+		return "", nil, false
+	}
+	comp, ok := call.Arguments[0].(*ast.ComprehensionExpression)
+	if !ok || comp.Kind != ast.GeneratorComprehension {
+		return "", nil, false
+	}
+	switch ident.Value {
+	case "sum", "any", "all":
+		return ident.Value, comp, true
+	}
+	return "", nil, false
+}
+
+// generateLoops генерирует вложенные циклы и условия частей включения;
+// inner генерирует тело самого внутреннего цикла с заданным отступом
+func (g *Generator) generateLoops(clauses []*ast.ComprehensionClause, inner func(indent string) (string, error)) (string, error) {
+	var out strings.Builder
+	indent := "\t"
+	closing := []string{}
+	for _, clause := range clauses {
+		header, unpack, err := g.generateLoopHeader(clause)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(indent + header + " {\n")
+		closing = append(closing, indent+"}\n")
+		indent += "\t"
+		for _, line := range unpack {
+			out.WriteString(indent + line + "\n")
+		}
+		for _, cond := range clause.Conditions {
+			code, err := g.generateExpressionWithCast(cond, true, false)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(fmt.Sprintf("%sif %s {\n", indent, g.truthy(code, cond)))
+			closing = append(closing, indent+"}\n")
+			indent += "\t"
+		}
+	}
+	body, err := inner(indent)
+	if err != nil {
+		return "", err
+	}
+	out.WriteString(body)
+	for i := len(closing) - 1; i >= 0; i-- {
+		out.WriteString(closing[i])
+	}
+	return out.String(), nil
+}
+
+// generateLoopHeader генерирует заголовок цикла for для части включения.
+// range(...) превращается в счётчик, списки обходятся напрямую, остальные
+// значения — через gopyIter. Несколько переменных цикла распаковывают элемент
+func (g *Generator) generateLoopHeader(clause *ast.ComprehensionClause) (string, []string, error) {
+	if call, ok := clause.Iterable.(*ast.CallExpression); ok && g.isRangeCall(call) {
+		if len(clause.Targets) != 1 {
+			return "", nil, fmt.Errorf("range нельзя распаковать в %d переменных", len(clause.Targets))
+		}
+		return g.generateRangeHeader(clause.Targets[0].Value, call, true)
+	}
+
+	iterable, err := g.generateExpressionWithCast(clause.Iterable, true, false)
+	if err != nil {
+		return "", nil, err
+	}
+	item := clause.Targets[0].Value
+	if len(clause.Targets) > 1 {
+		g.tempCounter++
+		item = fmt.Sprintf("gopyItem%d", g.tempCounter)
+	}
+
+	var header string
+	switch g.staticType(clause.Iterable) {
+	case "list":
+		header = fmt.Sprintf("for _, %s := range %s.items", item, iterable)
+	case "dict", "set":
+		header = fmt.Sprintf("for %s := range %s", item, iterable)
+	default:
+		g.useHelper("gopyIter")
+		header = fmt.Sprintf("for _, %s := range gopyIter(%s, %d)", item, iterable, clause.Token.Line)
+	}
+
+	unpack := []string{}
+	if len(clause.Targets) > 1 {
+		g.tempCounter++
+		tmp := fmt.Sprintf("gopyTmp%d", g.tempCounter)
+		g.useHelper("gopyUnpack")
+		unpack = append(unpack, fmt.Sprintf("%s := gopyUnpack(%s, %d, -1)", tmp, item, len(clause.Targets)))
+		names, values := []string{}, []string{}
+		for i, target := range clause.Targets {
+			names = append(names, target.Value)
+			values = append(values, fmt.Sprintf("%s[%d]", tmp, i))
+		}
+		unpack = append(unpack, fmt.Sprintf("%s := %s", strings.Join(names, ", "), strings.Join(values, ", ")))
+	}
+	for _, target := range clause.Targets {
+		g.variableTypes[target.Value] = ""
+	}
+	return header, unpack, nil
+}
+
+// isRangeCall сообщает, что вызывается встроенная range, а не функция пользователя
+func (g *Generator) isRangeCall(call *ast.CallExpression) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || ident.Value != "range" || g.functionSignatures["range"] != nil {
+		return false
+	}
+	_, declared := g.lookupVariable("range")
+	return !declared && len(call.Arguments) >= 1 && len(call.Arguments) <= 3
+}
+
+// generateRangeHeader генерирует счётчик для range(stop), range(start, stop) и range(start, stop, step).
+// Внутри функций счётчик имеет тип int64, в main — int, как целые переменные main
+func (g *Generator) generateRangeHeader(name string, call *ast.CallExpression, inFunction bool) (string, []string, error) {
+	args := []string{}
+	for _, arg := range call.Arguments {
+		code, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return "", nil, err
+		}
+		if inFunction {
+			code = g.intValue(code, arg)
+		} else {
+			code = g.mainIntValue(code, arg)
+		}
+		args = append(args, code)
+	}
+	if name == "_" {
+		g.tempCounter++
+		name = fmt.Sprintf("gopyI%d", g.tempCounter)
+	}
+	g.variableTypes[name] = "int"
+
+	start := "int64(0)"
+	if !inFunction {
+		start = "0"
+	}
+	switch len(args) {
+	case 1:
+		return fmt.Sprintf("for %s := %s; %s < %s; %s++", name, start, name, args[0], name), nil, nil
+	case 2:
+		return fmt.Sprintf("for %s := %s; %s < %s; %s++", name, args[0], name, args[1], name), nil, nil
+	}
+	return fmt.Sprintf("for %s := %s; (%s > 0 && %s < %s) || (%s < 0 && %s > %s); %s += %s",
+		name, args[0], args[2], name, args[1], args[2], name, args[1], name, args[2]), nil, nil
+}
+
+// intValue приводит значение к int64: значения неизвестного типа — утверждением
+// типа, целые значения main (тип int) — преобразованием
+func (g *Generator) intValue(code string, expr ast.Expression) string {
+	if g.staticType(expr) == "" {
+		return fmt.Sprintf("%s.(int64)", code)
+	}
+	if strings.HasPrefix(code, "int64(") {
+		return code
+	}
+	return fmt.Sprintf("int64(%s)", code)
+}
+
+// mainIntValue приводит значение к int — типу целых переменных main. Литералы
+// (и отрицательные тоже) остаются как есть, значения неизвестного типа — утверждением типа int64
+func (g *Generator) mainIntValue(code string, expr ast.Expression) string {
+	if prefix, ok := expr.(*ast.PrefixExpression); ok && prefix.Operator == "-" {
+		expr = prefix.Right
+	}
+	if _, ok := expr.(*ast.IntegerLiteral); ok {
+		return code
+	}
+	if g.staticType(expr) == "" {
+		return fmt.Sprintf("int(%s.(int64))", code)
+	}
+	return fmt.Sprintf("int(%s)", code)
+}
+
+// boxValue приводит целое значение к int64 перед сохранением в контейнер:
+// элементы контейнеров имеют тип interface{}, а внутри функций такие значения
+// приводятся к int64, тогда как целые литералы Go получают тип int
+func (g *Generator) boxValue(code string, expr ast.Expression) string {
+	if g.staticType(expr) == "int" && !strings.HasPrefix(code, "int64(") {
+		return fmt.Sprintf("int64(%s)", code)
+	}
+	return code
+}
+
+// truthy возвращает условие Go для значения по правилам истинности Python
+func (g *Generator) truthy(code string, expr ast.Expression) string {
+	if g.staticType(expr) == "bool" {
+		return code
+	}
+	g.useHelper("gopyTruthy")
+	return fmt.Sprintf("gopyTruthy(%s)", code)
+}
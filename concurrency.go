@@ -0,0 +1,221 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// generateChan генерирует создание канала: chan() — небуферизованный,
+// chan(n) — с буфером на n значений
+func (g *Generator) generateChan(call *ast.CallExpression, inFunction bool) (string, error) {
+	if len(call.Arguments) == 0 {
+		return "make(chan interface{})", nil
+	}
+	size, err := g.generateExpressionWithCast(call.Arguments[0], inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("make(chan interface{}, %s)", g.intValue(size, call.Arguments[0])), nil
+}
+
+// generateClose генерирует закрытие канала: после close(ch) цикл for v in ch завершается
+func (g *Generator) generateClose(call *ast.CallExpression, inFunction bool) (string, error) {
+	ch, err := g.generateChannel(call.Arguments[0], inFunction)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("close(%s)", ch), nil
+}
+
+// generateChannel генерирует выражение, которое должно быть каналом
+func (g *Generator) generateChannel(expr ast.Expression, inFunction bool) (string, error) {
+	if typ := g.staticType(expr); typ != "" && typ != "chan" {
+		return "", fmt.Errorf("%s не является каналом: тип %s", expr.String(), typ)
+	}
+	code, err := g.generateExpressionWithCast(expr, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	return g.coerce(code, expr, "chan"), nil
+}
+
+// generateSend генерирует отправку в канал: ch <- v
+func (g *Generator) generateSend(stmt *ast.SendStatement, inFunction bool) (string, error) {
+	ch, err := g.generateChannel(stmt.Channel, inFunction)
+	if err != nil {
+		return "", err
+	}
+	val, err := g.generateExpressionWithCast(stmt.Value, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s <- %s", ch, g.boxValue(val, stmt.Value)), nil
+}
+
+// generateReceive генерирует получение из канала: <-ch
+func (g *Generator) generateReceive(expr *ast.ReceiveExpression, inFunction bool) (string, error) {
+	ch, err := g.generateChannel(expr.Channel, inFunction)
+	if err != nil {
+		return "", err
+	}
+	return "<-" + ch, nil
+}
+
+// generateGo генерирует go f(x). Аргументы вычисляются до запуска горутины,
+// как в Go, а сама горутина учитывается в gopyTasks, чтобы main дождалась её
+// завершения. Ошибка в горутине завершает программу так же, как ошибка в main
+func (g *Generator) generateGo(stmt *ast.GoStatement, inFunction bool) (string, error) {
+	var out strings.Builder
+	g.useHelper("gopyTasks")
+	g.useHelper("gopyReport")
+	out.WriteString("\tgopyTasks.Add(1)\n")
+
+	call := &ast.CallExpression{Token: stmt.Call.Token, Function: stmt.Call.Function}
+	for _, arg := range stmt.Call.Arguments {
+		value := arg
+		kw, isKeyword := arg.(*ast.KeywordArgument)
+		if isKeyword {
+			value = kw.Value
+		}
+		if !isConstant(value) {
+			code, err := g.generateExpressionWithCast(value, inFunction, false)
+			if err != nil {
+				return "", err
+			}
+			g.tempCounter++
+			tmp := &ast.Identifier{Token: stmt.Token, Value: fmt.Sprintf("gopyTmp%d", g.tempCounter)}
+			out.WriteString(fmt.Sprintf("\t%s := %s\n", tmp.Value, code))
+			g.variableTypes[tmp.Value] = g.staticType(value)
+			value = tmp
+		}
+		if isKeyword {
+			value = &ast.KeywordArgument{Token: kw.Token, Name: kw.Name, Value: value}
+		}
+		call.Arguments = append(call.Arguments, value)
+	}
+
+	code, err := g.generateExpressionWithCast(call, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	out.WriteString("\tgo func() {\n\t\tdefer gopyTasks.Done()\n\t\tdefer gopyReport()\n")
+	out.WriteString(fmt.Sprintf("\t\t%s\n\t}()\n", code))
+	return out.String(), nil
+}
+
+// isConstant сообщает, что значение — литерал, который можно вычислить в горутине
+func isConstant(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+// generateSelect генерирует select. Имя, которому присваивается полученное
+// значение, объявляется внутри ветки, если раньше его не было
+func (g *Generator) generateSelect(stmt *ast.SelectStatement, inFunction bool) (string, error) {
+	var out strings.Builder
+	out.WriteString("\tselect {\n")
+	hasDefault := false
+	for _, c := range stmt.Cases {
+		restore := func() {}
+		var assign string
+		switch comm := c.Comm.(type) {
+		case nil:
+			if hasDefault {
+				return "", fmt.Errorf("строка %d: в select может быть только одна ветка default", c.Token.Line)
+			}
+			hasDefault = true
+			out.WriteString("\tdefault:\n")
+		case *ast.SendStatement:
+			send, err := g.generateSend(comm, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(fmt.Sprintf("\tcase %s:\n", send))
+		case *ast.ExpressionStatement:
+			recv, err := g.generateReceive(comm.Expression.(*ast.ReceiveExpression), inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(fmt.Sprintf("\tcase %s:\n", recv))
+		case *ast.AssignmentStatement:
+			recv, err := g.generateReceive(comm.Value.(*ast.ReceiveExpression), inFunction)
+			if err != nil {
+				return "", err
+			}
+			target, ok := comm.Name.(*ast.Identifier)
+			if !ok {
+				return "", fmt.Errorf("строка %d: в ветке select значение можно присвоить только переменной", c.Token.Line)
+			}
+			typ, declared := g.lookupVariable(target.Value)
+			declared = declared || (g.currentResults == nil && g.declaredVariables[target.Value])
+			switch {
+			case !declared && !usesName(c.Body, target.Value):
+				out.WriteString(fmt.Sprintf("\tcase %s:\n", recv))
+			case !declared:
+				out.WriteString(fmt.Sprintf("\tcase %s := %s:\n", target.Value, recv))
+				restore = g.bindPattern([]patternBinding{{name: target.Value}})
+			case typ == "":
+				out.WriteString(fmt.Sprintf("\tcase %s = %s:\n", target.Value, recv))
+			default:
+				// Значение из канала приводится к типу существующей переменной
+				g.tempCounter++
+				tmp := fmt.Sprintf("gopyTmp%d", g.tempCounter)
+				out.WriteString(fmt.Sprintf("\tcase %s := %s:\n", tmp, recv))
+				assign = fmt.Sprintf("\t\t%s = %s\n", target.Value, g.assertType(tmp, "", typ))
+			}
+		}
+		body, err := g.generateBlockStatementWithCast(c.Body, inFunction)
+		restore()
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(assign)
+		out.WriteString(indentCode(body))
+	}
+	out.WriteString("\t}\n")
+	return out.String(), nil
+}
+
+// generateLock генерирует создание мьютекса: Lock()
+func (g *Generator) generateLock(call *ast.CallExpression, inFunction bool) (string, error) {
+	g.imports["sync"] = true
+	return "&sync.Mutex{}", nil
+}
+
+// generateParallelMap генерирует parallel_map(fn, xs, workers=8): fn применяется
+// к элементам xs в workers горутинах, результаты возвращаются в порядке элементов.
+// Первая ошибка отменяет ещё не начатые вызовы и передаётся вызывающему коду
+func (g *Generator) generateParallelMap(call *ast.CallExpression, inFunction bool) (string, error) {
+	positional := positionalArguments(call)
+	workers := keywordArgument(call, "workers")
+	if len(positional) == 3 {
+		if workers != nil {
+			return "", fmt.Errorf("parallel_map: число workers задано дважды")
+		}
+		workers = positional[2]
+	}
+
+	fn, err := g.generateExpressionWithCast(positional[0], inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	items, err := g.generateExpressionWithCast(positional[1], inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	items = g.sequenceValue(items, positional[1])
+	count := "8"
+	if workers != nil {
+		count, err = g.generateExpressionWithCast(workers, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		count = g.intValue(count, workers)
+	}
+	g.useHelper("gopyParallelMap")
+	return fmt.Sprintf("gopyParallelMap(%s, %s, %s, %d)", fn, items, count, call.Token.Line), nil
+}
@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// isConditional сообщает, что выражение выбирает значение по условию:
+// a if cond else b или блочный if, использованный как значение
+func isConditional(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.ConditionalExpression, *ast.IfExpression:
+		return true
+	}
+	return false
+}
+
+// conditionalBranch — ветка условного значения: инструкции, которые выполняются
+// перед вычислением значения, и само значение (nil — ветки нет, значение None)
+type conditionalBranch struct {
+	statements []ast.Statement
+	value      ast.Expression
+}
+
+// conditionalParts раскладывает условное значение на условие и две ветки.
+// Значение ветки блочного if — её последнее выражение
+func conditionalParts(expr ast.Expression) (ast.Expression, conditionalBranch, conditionalBranch, error) {
+	switch expr := expr.(type) {
+	case *ast.ConditionalExpression:
+		return expr.Condition, conditionalBranch{value: expr.Consequence}, conditionalBranch{value: expr.Alternative}, nil
+	case *ast.IfExpression:
+		consequence, err := blockBranch(expr.Consequence)
+		if err != nil {
+			return nil, conditionalBranch{}, conditionalBranch{}, err
+		}
+		alternative := conditionalBranch{}
+		if expr.Alternative != nil {
+			alternative, err = blockBranch(expr.Alternative)
+			if err != nil {
+				return nil, conditionalBranch{}, conditionalBranch{}, err
+			}
+		}
+		return expr.Condition, consequence, alternative, nil
+	}
+	return nil, conditionalBranch{}, conditionalBranch{}, fmt.Errorf("выражение %s не является условным", expr.String())
+}
+
+func blockBranch(block *ast.BlockStatement) (conditionalBranch, error) {
+	n := len(block.Statements)
+	if n > 0 {
+		if last, ok := block.Statements[n-1].(*ast.ExpressionStatement); ok {
+			return conditionalBranch{statements: block.Statements[:n-1], value: last.Expression}, nil
+		}
+	}
+	return conditionalBranch{}, fmt.Errorf("ветка if, используемая как значение, должна заканчиваться выражением")
+}
+
+// conditionalType возвращает тип условного значения: общий тип веток
+// или пустую строку, если ветки различаются
+func (g *Generator) conditionalType(expr ast.Expression) string {
+	_, consequence, alternative, err := conditionalParts(expr)
+	if err != nil || consequence.value == nil || alternative.value == nil {
+		return ""
+	}
+	typ := g.staticType(consequence.value)
+	if typ != g.staticType(alternative.value) {
+		return ""
+	}
+	return typ
+}
+
+// generateConditionalValue генерирует условное значение внутри выражения:
+// результат каждой ветки записывается во временную переменную, и условие
+// вычисляется ровно в том месте выражения, где оно записано
+func (g *Generator) generateConditionalValue(expr ast.Expression, inFunction bool) (string, error) {
+	typ := g.conditionalType(expr)
+	goTyp, err := g.goType(typ)
+	if err != nil {
+		return "", err
+	}
+	g.tempCounter++
+	tmp := fmt.Sprintf("gopyTmp%d", g.tempCounter)
+	store, err := g.generateConditionalStore(tmp, typ, expr, inFunction)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("func() %s {\n\tvar %s %s\n%s\treturn %s\n}()", goTyp, tmp, goTyp, store, tmp), nil
+}
+
+// generateConditionalAssignment генерирует x = a if c else b без вложенной функции:
+// переменная объявляется заранее, а ветки if присваивают ей значение
+func (g *Generator) generateConditionalAssignment(name string, value ast.Expression, declared bool, inFunction bool) (string, error) {
+	var out strings.Builder
+	typ, _ := g.lookupVariable(name)
+	if !declared {
+		typ = g.conditionalType(value)
+		goTyp, err := g.goType(typ)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(fmt.Sprintf("\tvar %s %s\n", name, goTyp))
+	}
+	store, err := g.generateConditionalStore(name, typ, value, inFunction)
+	if err != nil {
+		return "", err
+	}
+	if !declared {
+		g.variableTypes[name] = typ
+		delete(g.localFunctions, name)
+	}
+	out.WriteString(store)
+	return out.String(), nil
+}
+
+// generateConditionalStore генерирует if, каждая ветка которого записывает своё
+// значение в target. Вложенные условные значения становятся вложенными if
+func (g *Generator) generateConditionalStore(target string, typ string, expr ast.Expression, inFunction bool) (string, error) {
+	cond, consequence, alternative, err := conditionalParts(expr)
+	if err != nil {
+		return "", err
+	}
+	condition, err := g.generateExpressionWithCast(cond, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	then, err := g.generateBranch(target, typ, consequence, inFunction)
+	if err != nil {
+		return "", err
+	}
+	otherwise, err := g.generateBranch(target, typ, alternative, inFunction)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("\tif %s {\n%s\t} else {\n%s\t}\n", g.truthy(condition, cond), then, otherwise), nil
+}
+
+func (g *Generator) generateBranch(target string, typ string, branch conditionalBranch, inFunction bool) (string, error) {
+	var out strings.Builder
+	if len(branch.statements) > 0 {
+		code, err := g.generateBlockStatementWithCast(&ast.BlockStatement{Statements: branch.statements}, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(indentCode(code))
+	}
+	if branch.value != nil && isConditional(branch.value) {
+		code, err := g.generateConditionalStore(target, typ, branch.value, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(indentCode(code))
+		return out.String(), nil
+	}
+	val := "nil"
+	if branch.value != nil {
+		code, err := g.generateExpressionWithCast(branch.value, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		val = g.storeValue(code, branch.value, typ)
+	}
+	out.WriteString(fmt.Sprintf("\t\t%s = %s\n", target, val))
+	return out.String(), nil
+}
+
+// storeValue приводит значение к типу временной переменной typ, в которую оно записывается
+func (g *Generator) storeValue(code string, value ast.Expression, typ string) string {
+	_, literal := value.(*ast.IntegerLiteral)
+	switch {
+	case typ == "int" && !literal:
+		// Целые переменные main имеют тип int, а временная переменная — int64
+		return g.intValue(code, value)
+	case typ == "":
+		return g.boxValue(code, value)
+	}
+	return g.coerce(code, value, typ)
+}
+
+// indentCode сдвигает каждую строку сгенерированного кода на одну табуляцию
+func indentCode(code string) string {
+	lines := strings.SplitAfter(code, "\n")
+	var out strings.Builder
+	for _, line := range lines {
+		if line != "" && line != "\n" {
+			out.WriteString("\t")
+		}
+		out.WriteString(line)
+	}
+	return out.String()
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"sort"
+	"strings"
+)
+
+// errorKind возвращает переменную Go с видом ошибки: встроенным (ValueError)
+// или объявленным классом ошибки
+func (g *Generator) errorKind(name string) (string, bool) {
+	if kind, ok := errorKinds[name]; ok {
+		return kind, true
+	}
+	if g.isErrorClass(name) {
+		return "gopyKind" + name, true
+	}
+	return "", false
+}
+
+// isErrorClass сообщает, что класс объявлен как класс ошибки: class ParseError(ValueError)
+func (g *Generator) isErrorClass(name string) bool {
+	class := g.declaredClasses[name]
+	return class != nil && class.Base != nil
+}
+
+// declareErrorClass проверяет базовый вид класса ошибки, добавляет классу поле
+// message и генерирует переменную с его видом. Вид класса продолжает цепочку
+// базового вида, поэтому except ValueError перехватывает и ParseError
+func (g *Generator) declareErrorClass(class *ast.ClassStatement) error {
+	parent, ok := errorKinds[class.Base.Value]
+	if !ok {
+		names := []string{}
+		for name := range errorKinds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("класс %s может наследовать только встроенный вид ошибки (%s), а не %s",
+			class.Name.Value, strings.Join(names, ", "), class.Base.Value)
+	}
+	if findField(class, "message") != nil {
+		return fmt.Errorf("поле message класса ошибки %s объявлено автоматически", class.Name.Value)
+	}
+	// Сообщение — первый аргумент конструктора, а хранится оно во встроенной gopyError
+	message := &ast.Identifier{Token: class.Name.Token, Value: "message"}
+	class.Fields = append([]*ast.ClassField{{
+		Token: class.Name.Token,
+		Name:  message,
+		Type:  &ast.Identifier{Token: class.Name.Token, Value: "str"},
+	}}, class.Fields...)
+
+	g.useHelper("gopyError")
+	g.functions.WriteString(fmt.Sprintf("var gopyKind%s = &gopyErrorKind{name: %q, parent: %s}\n\n",
+		class.Name.Value, g.sourceName(class.Name.Value), parent))
+	return nil
+}
+
+// generateErrorValue генерирует создание встроенной ошибки: ValueError("плохой ввод")
+func (g *Generator) generateErrorValue(kind string, call *ast.CallExpression, inFunction bool) (string, error) {
+	g.useHelper("gopyError")
+	switch len(call.Arguments) {
+	case 0:
+		return fmt.Sprintf("gopyErrorf(%s, 0, \"\")", kind), nil
+	case 1:
+		msg, err := g.generateExpressionWithCast(call.Arguments[0], inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("gopyErrorf(%s, 0, \"%%v\", %s)", kind, msg), nil
+	}
+	return "", fmt.Errorf("%s принимает не более одного аргумента, получено %d", call.Function.String(), len(call.Arguments))
+}
+
+// generateRaise генерирует raise: ошибка передаётся через panic, а строка raise
+// запоминается в ней для сообщения об ошибке
+func (g *Generator) generateRaise(stmt *ast.RaiseStatement, inFunction bool) (string, error) {
+	if stmt.Value == nil {
+		if g.reraise == "" {
+			return "", fmt.Errorf("строка %d: raise без ошибки допустим только внутри except", stmt.Token.Line)
+		}
+		return fmt.Sprintf("\tpanic(%s)\n", g.reraise), nil
+	}
+
+	value := stmt.Value
+	// raise ValueError — то же, что raise ValueError()
+	if ident, ok := value.(*ast.Identifier); ok {
+		if _, isKind := g.errorKind(ident.Value); isKind {
+			value = &ast.CallExpression{Token: ident.Token, Function: ident}
+		}
+	}
+	if typ := g.staticType(value); typ != "" && !g.isErrorClass(typ) {
+		return "", fmt.Errorf("строка %d: raise ожидает ошибку, получено %s", stmt.Token.Line, typ)
+	}
+	code, err := g.generateExpressionWithCast(value, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	g.useHelper("gopyRaise")
+	return fmt.Sprintf("\tpanic(gopyRaise(%s, %d))\n", code, stmt.Token.Line), nil
+}
+
+// needsReturn сообщает, что функции без return нужен return в конце.
+// Тело, которое заканчивается raise, завершается panic и return не требует
+func needsReturn(stmts []ast.Statement) bool {
+	if n := len(stmts); n > 0 {
+		if _, ok := stmts[n-1].(*ast.RaiseStatement); ok {
+			return false
+		}
+	}
+	return !containsReturn(stmts)
+}
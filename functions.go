@@ -0,0 +1,197 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// generateFunctionLiteral генерирует функцию-литерал Go (замыкание). Тело видит
+// переменные объемлющих функций, а присваивание внутри тела, как в Python,
+// создаёт новую локальную переменную
+func (g *Generator) generateFunctionLiteral(fn *ast.FunctionLiteral) (string, error) {
+	g.enterClosure()
+	defer g.leaveClosure()
+
+	params, err := g.generateParameters(fn.Parameters, fn.ParameterTypes)
+	if err != nil {
+		return "", err
+	}
+	results := g.currentResults
+	g.currentResults = resultTypes(fn.ReturnType, fn.ReturnTypes, fn.Body)
+	defer func() { g.currentResults = results }()
+	result, err := g.goResult(g.currentResults)
+	if err != nil {
+		return "", err
+	}
+
+	restore := g.enterBody(fn.Body)
+	body, err := g.generateBlockStatementWithCast(fn.Body, true)
+	restore()
+	if err != nil {
+		return "", err
+	}
+	if needsReturn(fn.Body.Statements) {
+		if fn.ReturnType != nil || len(fn.ReturnTypes) > 0 {
+			return "", fmt.Errorf("функция должна возвращать значение типа %s", resultList(g.currentResults))
+		}
+		body += "\treturn nil\n"
+	}
+
+	return fmt.Sprintf("func(%s) %s {\n%s}", params, result, body), nil
+}
+
+// generateLambda генерирует lambda-выражение как функцию Go из одного return
+func (g *Generator) generateLambda(lambda *ast.LambdaExpression) (string, error) {
+	g.enterClosure()
+	defer g.leaveClosure()
+
+	params, err := g.generateParameters(lambda.Parameters, nil)
+	if err != nil {
+		return "", err
+	}
+	body, err := g.generateExpressionWithCast(lambda.Body, true, false)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("func(%s) interface{} { return %s }", params, body), nil
+}
+
+// generateFunctionValue объявляет переменную-функцию. Переменная объявляется
+// до присваивания, чтобы функция могла вызывать саму себя
+func (g *Generator) generateFunctionValue(name string, fn *ast.FunctionLiteral) (string, error) {
+	typ, err := g.functionType(fn)
+	if err != nil {
+		return "", err
+	}
+	g.variableTypes[name] = "func"
+	g.localFunctions[name] = fn
+
+	val, err := g.generateFunctionLiteral(fn)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("\tvar %s %s\n\t%s = %s\n", name, typ, name, val), nil
+}
+
+// declareFunctions заранее регистрирует сигнатуры всех функций верхнего уровня,
+// чтобы вызовы до объявления проверялись так же, как и после него
+func (g *Generator) declareFunctions(stmts []ast.Statement) error {
+	for _, stmt := range stmts {
+		var name string
+		var fn *ast.FunctionLiteral
+		switch stmt := stmt.(type) {
+		case *ast.FunctionStatement:
+			name, fn = stmt.Name.Value, stmt.Function
+		case *ast.LetStatement:
+			lit, ok := stmt.Value.(*ast.FunctionLiteral)
+			if !ok {
+				continue
+			}
+			name, fn = stmt.Name.Value, lit
+		default:
+			continue
+		}
+		if _, ok := g.functionSignatures[name]; ok {
+			return fmt.Errorf("функция %s уже объявлена", name)
+		}
+		g.functionSignatures[name] = fn
+	}
+	return nil
+}
+
+// generateNestedFunction генерирует def внутри функции как замыкание
+func (g *Generator) generateNestedFunction(stmt *ast.FunctionStatement) (string, error) {
+	if _, ok := g.variableTypes[stmt.Name.Value]; ok {
+		return "", fmt.Errorf("функция %s уже объявлена", stmt.Name.Value)
+	}
+	return g.generateFunctionValue(stmt.Name.Value, stmt.Function)
+}
+
+// generateLocalAssignment генерирует присваивание переменной внутри блока:
+// первое присваивание в функции объявляет переменную через :=, целая переменная
+// функции объявляется как int64
+func (g *Generator) generateLocalAssignment(name string, value ast.Expression, inFunction bool) (string, error) {
+	_, declared := g.variableTypes[name]
+	if fn, ok := value.(*ast.FunctionLiteral); ok && !declared {
+		return g.generateFunctionValue(name, fn)
+	}
+	if isConditional(value) {
+		return g.generateConditionalAssignment(name, value, declared, inFunction)
+	}
+
+	val, err := g.generateExpressionWithCast(value, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	if declared {
+		if typ, _ := g.lookupVariable(name); typ == "" {
+			val = g.boxValue(val, value)
+		}
+		return fmt.Sprintf("\t%s = %s\n", name, val), nil
+	}
+	g.variableTypes[name] = g.staticType(value)
+	delete(g.localFunctions, name)
+	if inFunction && g.variableTypes[name] == "int" {
+		val = g.intValue(val, value)
+	}
+	return fmt.Sprintf("\t%s := %s\n", name, val), nil
+}
+
+// functionType возвращает тип Go для функции-литерала: func(interface{}) interface{}
+func (g *Generator) functionType(fn *ast.FunctionLiteral) (string, error) {
+	params := []string{}
+	for _, p := range fn.Parameters {
+		typ, err := g.goType(typeName(fn.ParameterTypes[p.Value]))
+		if err != nil {
+			return "", err
+		}
+		params = append(params, typ)
+	}
+	result, err := g.goResult(resultTypes(fn.ReturnType, fn.ReturnTypes, fn.Body))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("func(%s) %s", strings.Join(params, ", "), result), nil
+}
+
+// enterClosure открывает область видимости вложенной функции
+func (g *Generator) enterClosure() {
+	g.outerTypes = append(g.outerTypes, g.variableTypes)
+	g.variableTypes = make(map[string]string)
+}
+
+// leaveClosure возвращается в область видимости объемлющей функции
+func (g *Generator) leaveClosure() {
+	g.variableTypes = g.outerTypes[len(g.outerTypes)-1]
+	g.outerTypes = g.outerTypes[:len(g.outerTypes)-1]
+}
+
+// lookupVariable ищет тип переменной в текущей области видимости, а затем
+// в областях объемлющих функций (захваченные переменные)
+func (g *Generator) lookupVariable(name string) (string, bool) {
+	if typ, ok := g.variableTypes[name]; ok {
+		return typ, true
+	}
+	for i := len(g.outerTypes) - 1; i >= 0; i-- {
+		if typ, ok := g.outerTypes[i][name]; ok {
+			return typ, true
+		}
+	}
+	return "", false
+}
+
+// isDynamicCallee сообщает, что вызываемое значение — функция, тип которой
+// неизвестен при трансляции: переменная без известного типа, элемент списка
+// или словаря, результат другого вызова
+func (g *Generator) isDynamicCallee(fn ast.Expression) bool {
+	switch fn := fn.(type) {
+	case *ast.Identifier:
+		typ, ok := g.lookupVariable(fn.Value)
+		return ok && typ != "func"
+	case *ast.IndexExpression, *ast.CallExpression:
+		return true
+	}
+	return false
+}
@@ -2,9 +2,14 @@ package generator
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"go/types"
 	"gopy/ast"
+	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Generator struct {
@@ -13,35 +18,189 @@ type Generator struct {
 	mainBody  bytes.Buffer
 	// Нам все еще нужно отслеживать переменные, чтобы использовать = или :=
 	declaredVariables map[string]bool
-	declaredClasses   map[string]bool
+	declaredClasses   map[string]*ast.ClassStatement
+	declaredInterfaces map[string]*ast.InterfaceStatement
+	// Сигнатуры функций верхнего уровня для проверки вызовов
+	functionSignatures map[string]*ast.FunctionLiteral
+	// Известные на этапе трансляции типы переменных (имена типов Gopy)
+	variableTypes map[string]string
+	// Класс, методы которого генерируются в данный момент
+	currentClass string
+	// Типы результатов генерируемой функции (типы Gopy)
+	currentResults []string
+	// Счётчик временных переменных gopyTmpN
+	tempCounter int
+	// Области видимости объемлющих функций для замыканий (от внешней к внутренней)
+	outerTypes []map[string]string
+	// Сигнатуры функций, сохранённых в переменные текущей функции
+	localFunctions map[string]*ast.FunctionLiteral
+	// Используемые вспомогательные функции времени выполнения и их импорты
+	helpers map[string]bool
+	imports map[string]bool
+	// Длины кортежей, для которых генерируются структуры gopyTupleN
+	tupleArities map[int]bool
+	// Импортированные пакеты Go по именам и типы Go, которые получили значения
+	// Gopy (ключ — запись типа в сгенерированном коде)
+	goImporter   types.Importer
+	goPackages   map[string]*types.Package
+	goValueTypes map[string]types.Type
+	// Имена, связанные образцом case, в условии ветки: имя -> выражение Go
+	patternAliases map[string]string
+	// return внутри генерируемого try или with и последняя инструкция тела функции
+	tryReturn     *tryReturn
+	tailStatement ast.Statement
+	// Предупреждения, не мешающие трансляции
+	warnings []string
+	// Имя исходного файла Gopy для сообщений об ошибках времени выполнения
+	sourceFile string
+	// Каталог, в котором import ищет модули Gopy, и загруженные модули по путям
+	moduleDir string
+	modules   map[string]*module
+	// Имена Go, которыми заменены занятые в Go имена Gopy: type_ -> type
+	goNames map[string]string
+	// Имя пакета Go в режиме библиотеки; пустая строка — программа с main
+	library string
+	// Выражение Go с перехваченной ошибкой для raise без значения внутри except
+	reraise string
+	// Сборка без проверок assert
+	release bool
 }
 
 func New() *Generator {
 	return &Generator{
 		declaredVariables: make(map[string]bool),
-		declaredClasses:   make(map[string]bool),
+		declaredClasses:   make(map[string]*ast.ClassStatement),
+		declaredInterfaces: make(map[string]*ast.InterfaceStatement),
+		functionSignatures: make(map[string]*ast.FunctionLiteral),
+		variableTypes:     make(map[string]string),
+		localFunctions:    make(map[string]*ast.FunctionLiteral),
+		helpers:           make(map[string]bool),
+		imports:           make(map[string]bool),
+		tupleArities:      make(map[int]bool),
+		goPackages:        make(map[string]*types.Package),
+		goValueTypes:      make(map[string]types.Type),
+		patternAliases:    make(map[string]string),
+		modules:           make(map[string]*module),
+		goNames:           make(map[string]string),
 	}
 }
 
+// SetSourceFile задаёт имя исходного файла, которое попадает в сообщения об ошибках
+func (g *Generator) SetSourceFile(name string) {
+	g.sourceFile = name
+}
+
+// SetRelease включает сборку без проверок assert
+func (g *Generator) SetRelease(release bool) {
+	g.release = release
+}
+
+// Warnings возвращает предупреждения, найденные при генерации кода
+func (g *Generator) Warnings() []string {
+	warnings := make([]string, len(g.warnings))
+	for i, warning := range g.warnings {
+		warnings[i] = g.sourceMessage(warning)
+	}
+	return warnings
+}
+
+// Generate транслирует программу в код Go. Сообщения об ошибках называют
+// имена так, как они записаны в Gopy. Generate изменяет AST программы: имена
+// из модулей Gopy и имена, занятые в Go, в нём заменяются именами Go
 func (g *Generator) Generate(node ast.Node) (string, error) {
+	code, err := g.generate(node)
+	if err != nil {
+		return "", errors.New(g.sourceMessage(err.Error()))
+	}
+	return code, nil
+}
+
+func (g *Generator) generate(node ast.Node) (string, error) {
 	program, ok := node.(*ast.Program)
 	if !ok {
 		return "", fmt.Errorf("неподдерживаемый тип узла: %T", node)
 	}
 
+	// Объявления импортированных модулей Gopy генерируются до программы
+	main := strings.TrimSuffix(g.sourceFile, filepath.Ext(g.sourceFile))
+	if err := g.loadModules(program, []string{main}); err != nil {
+		return "", err
+	}
+	g.mangleNames(program)
+
+	// Библиотека, как и модуль, состоит только из объявлений
+	if g.library != "" {
+		for _, stmt := range program.Statements {
+			if _, ok := declarationName(stmt); !ok {
+				return "", fmt.Errorf("строка %d: библиотека может содержать только объявления def, class, interface и import", lineOf(stmt))
+			}
+		}
+	}
+
+	// Функции верхнего уровня можно вызывать до их объявления
+	if err := g.declareFunctions(program.Statements); err != nil {
+		return "", err
+	}
+
 	for _, stmt := range program.Statements {
 		err := g.generateStatement(stmt)
 		if err != nil {
 			return "", err
 		}
 	}
+	api := ""
+	if g.library != "" {
+		var err error
+		if api, err = g.generateLibraryAPI(program); err != nil {
+			return "", err
+		}
+	} else if g.helpers["gopyError"] {
+		// Неперехваченная ошибка Gopy завершает программу сообщением с файлом и строкой
+		g.useHelper("gopyReport")
+	}
 
 	var out bytes.Buffer
-	out.WriteString("package main\n\n")
-	out.WriteString("import (\n\t\"fmt\"\n)\n\n")
+	if g.library != "" {
+		out.WriteString(fmt.Sprintf("package %s\n\n", g.library))
+	} else {
+		out.WriteString("package main\n\n")
+	}
+	out.WriteString("import (\n\t\"fmt\"\n")
+	for _, imp := range sortedKeys(g.imports) {
+		if imp != "fmt" {
+			out.WriteString(fmt.Sprintf("\t%q\n", imp))
+		}
+	}
+	out.WriteString(")\n\n")
+	for _, name := range sortedKeys(g.helpers) {
+		if code := runtimeHelpers[name].code; code != "" {
+			out.WriteString(code + "\n")
+		}
+	}
+	if g.helpers["gopyTuple"] {
+		out.WriteString(g.tupleTypes())
+	}
+	if g.helpers["gopyError"] {
+		out.WriteString(fmt.Sprintf("const gopySourceFile = %q\n\n", g.sourceFile))
+	}
+	if g.helpers["gopyAssert"] {
+		// В сборке -release проверки остаются в исходном коде, но компилятор Go их удаляет
+		out.WriteString(fmt.Sprintf("const gopyAssertions = %t\n\n", !g.release))
+	}
 	out.WriteString(g.functions.String()) // Сначала все функции
+	if g.library != "" {
+		out.WriteString(api)
+		return out.String(), nil
+	}
 	out.WriteString("func main() {\n")
+	if g.helpers["gopyReport"] {
+		out.WriteString("\tdefer gopyReport()\n")
+	}
 	out.WriteString(g.mainBody.String()) // Затем тело main
+	if g.helpers["gopyTasks"] {
+		// Программа завершается после всех запущенных горутин
+		out.WriteString("\tgopyTasks.Wait()\n")
+	}
 	out.WriteString("}\n")
 
 	return out.String(), nil
@@ -51,6 +210,23 @@ func (g *Generator) generateStatement(stmt ast.Statement) error {
 	switch stmt := stmt.(type) {
 	case *ast.AssignmentStatement:
 		switch name := stmt.Name.(type) {
+		case *ast.IndexExpression:
+			code, err := g.generateIndexAssignment(name, stmt.Value, false)
+			if err != nil {
+				return err
+			}
+			g.mainBody.WriteString(code)
+			return nil
+		case *ast.TupleLiteral:
+			code, declared, err := g.generateTupleAssignment(name, stmt.Value, false)
+			if err != nil {
+				return err
+			}
+			for _, v := range declared {
+				g.declaredVariables[v] = true
+			}
+			g.mainBody.WriteString(code)
+			return nil
 		case *ast.DotExpression:
 			left, err := g.generateExpression(name.Left)
 			if err != nil {
@@ -61,20 +237,30 @@ func (g *Generator) generateStatement(stmt ast.Statement) error {
 			if err != nil {
 				return err
 			}
+			val, err = g.generateGoFieldValue(name, stmt.Value, val)
+			if err != nil {
+				return err
+			}
 			g.mainBody.WriteString(fmt.Sprintf("\t%s.%s = %s\n", left, right, val))
 			return nil
 		case *ast.Identifier:
-			// как раньше
-			if call, ok := stmt.Value.(*ast.CallExpression); ok {
-				if ident, ok := call.Function.(*ast.Identifier); ok && len(call.Arguments) == 0 && g.isClass(ident.Value) {
-					if !g.declaredVariables[name.Value] {
-						g.declaredVariables[name.Value] = true
-						g.mainBody.WriteString(fmt.Sprintf("\t%s := &%s{}\n", name.Value, ident.Value))
-					} else {
-						g.mainBody.WriteString(fmt.Sprintf("\t%s = &%s{}\n", name.Value, ident.Value))
-					}
-					return nil
+			if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && !g.declaredVariables[name.Value] {
+				g.declaredVariables[name.Value] = true
+				code, err := g.generateFunctionValue(name.Value, fn)
+				if err != nil {
+					return err
+				}
+				g.mainBody.WriteString(code)
+				return nil
+			}
+			if isConditional(stmt.Value) {
+				code, err := g.generateConditionalAssignment(name.Value, stmt.Value, g.declaredVariables[name.Value], false)
+				if err != nil {
+					return err
 				}
+				g.declaredVariables[name.Value] = true
+				g.mainBody.WriteString(code)
+				return nil
 			}
 			val, err := g.generateExpressionWithCast(stmt.Value, false, false)
 			if err != nil {
@@ -82,6 +268,7 @@ func (g *Generator) generateStatement(stmt ast.Statement) error {
 			}
 			if !g.declaredVariables[name.Value] {
 				g.declaredVariables[name.Value] = true
+				g.variableTypes[name.Value] = g.staticType(stmt.Value)
 				g.mainBody.WriteString(fmt.Sprintf("\t%s := %s\n", name.Value, val))
 			} else {
 				g.mainBody.WriteString(fmt.Sprintf("\t%s = %s\n", name.Value, val))
@@ -90,15 +277,13 @@ func (g *Generator) generateStatement(stmt ast.Statement) error {
 		}
 	case *ast.ClassStatement:
 		return g.generateClass(stmt)
+	case *ast.InterfaceStatement:
+		return g.generateInterface(stmt)
+	case *ast.ImportStatement:
+		return g.generateImport(stmt)
+	case *ast.FunctionStatement:
+		return g.generateFunction(stmt.Name.Value, stmt.Function)
 	case *ast.LetStatement:
-		// Если присваиваем класс, создаём объект через &Class{}
-		if call, ok := stmt.Value.(*ast.CallExpression); ok {
-			if ident, ok := call.Function.(*ast.Identifier); ok && len(call.Arguments) == 0 && g.isClass(ident.Value) {
-				g.declaredVariables[stmt.Name.Value] = true
-				g.mainBody.WriteString(fmt.Sprintf("\t%s := &%s{}\n", stmt.Name.Value, ident.Value))
-				return nil
-			}
-		}
 		// Если мы присваиваем функцию, генерируем ее отдельно
 		if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
 			return g.generateFunction(stmt.Name.Value, fn)
@@ -109,27 +294,91 @@ func (g *Generator) generateStatement(stmt ast.Statement) error {
 			return err
 		}
 		g.declaredVariables[stmt.Name.Value] = true
+		g.variableTypes[stmt.Name.Value] = g.staticType(stmt.Value)
 		g.mainBody.WriteString(fmt.Sprintf("\t%s := %s\n", stmt.Name.Value, val))
 
 	case *ast.ForStatement:
-		return g.generateForStatement(stmt)
+		code, err := g.generateFor(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.MatchStatement:
+		code, err := g.generateMatch(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.TryStatement:
+		code, err := g.generateTry(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.RaiseStatement:
+		code, err := g.generateRaise(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.AssertStatement:
+		code, err := g.generateAssert(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.GoStatement:
+		code, err := g.generateGo(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.DeferStatement:
+		code, err := g.generateDefer(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.SendStatement:
+		code, err := g.generateSend(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString("\t" + code + "\n")
+
+	case *ast.SelectStatement:
+		code, err := g.generateSelect(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
+
+	case *ast.WithStatement:
+		code, err := g.generateWith(stmt, false)
+		if err != nil {
+			return err
+		}
+		g.mainBody.WriteString(code)
 
 	case *ast.ExpressionStatement:
-		// d.bark() — CallExpression с DotExpression
+		// d.bark() — CallExpression с DotExpression; методы файлов и встроенных типов генерирует generateCall
 		if call, ok := stmt.Expression.(*ast.CallExpression); ok {
-			if dot, ok := call.Function.(*ast.DotExpression); ok {
+			if dot, ok := call.Function.(*ast.DotExpression); ok && !g.hasBuiltinMethods(dot) {
 				left, err := g.generateExpressionWithCast(dot.Left, false, true)
 				if err != nil {
 					return err
 				}
 				right := dot.Right.Value
-				args := []string{}
-				for _, a := range call.Arguments {
-					astr, err := g.generateExpressionWithCast(a, false, true)
-					if err != nil {
-						return err
-					}
-					args = append(args, astr)
+				args, err := g.generateCallArguments(call, false)
+				if err != nil {
+					return err
 				}
 				g.mainBody.WriteString(fmt.Sprintf("\t%s.%s(%s)\n", left, right, strings.Join(args, ", ")))
 				return nil
@@ -145,7 +394,7 @@ func (g *Generator) generateStatement(stmt ast.Statement) error {
 			g.mainBody.WriteString(fmt.Sprintf("\t%s.%s\n", left, right))
 			return nil
 		}
-		exprStr, err := g.generateExpressionWithCast(stmt.Expression, false, false)
+		exprStr, err := g.generateExpressionStatement(stmt.Expression, false)
 		if err != nil {
 			return err
 		}
@@ -165,24 +414,42 @@ func (g *Generator) generateStatement(stmt ast.Statement) error {
 
 // generateFunction генерирует код для функции верхнего уровня
 func (g *Generator) generateFunction(name string, fn *ast.FunctionLiteral) error {
-	var params []string
-	for _, p := range fn.Parameters {
-		// В Go нам нужны типы. Пока что для простоты используем interface{}
-		params = append(params, fmt.Sprintf("%s interface{}", p.Value))
+	// Сигнатура регистрируется до генерации тела, чтобы работала рекурсия
+	g.functionSignatures[name] = fn
+
+	// Переменные main не видны внутри функции, поэтому типы отслеживаются заново
+	globalTypes, outerTypes, localFunctions := g.variableTypes, g.outerTypes, g.localFunctions
+	g.variableTypes, g.outerTypes, g.localFunctions = make(map[string]string), nil, make(map[string]*ast.FunctionLiteral)
+	defer func() { g.variableTypes, g.outerTypes, g.localFunctions = globalTypes, outerTypes, localFunctions }()
+
+	// Параметры без аннотации типа получают interface{}
+	params, err := g.generateParameters(fn.Parameters, fn.ParameterTypes)
+	if err != nil {
+		return err
+	}
+	g.currentResults = resultTypes(fn.ReturnType, fn.ReturnTypes, fn.Body)
+	defer func() { g.currentResults = nil }()
+	result, err := g.goResult(g.currentResults)
+	if err != nil {
+		return err
 	}
 
 	// Начинаем объявление функции
-	g.functions.WriteString(fmt.Sprintf("func %s(%s) interface{} {\n", name, strings.Join(params, ", ")))
+	g.functions.WriteString(fmt.Sprintf("func %s(%s) %s {\n", name, params, result))
 
-	// Генерируем тело функции
+	restore := g.enterBody(fn.Body)
 	body, err := g.generateBlockStatementWithCast(fn.Body, true)
+	restore()
 	if err != nil {
 		return err
 	}
 	g.functions.WriteString(body)
 
 	// Если в функции нет return, Go требует его для функций, возвращающих значение
-	if !strings.Contains(body, "return") {
+	if needsReturn(fn.Body.Statements) {
+		if fn.ReturnType != nil || len(fn.ReturnTypes) > 0 {
+			return fmt.Errorf("функция %s должна возвращать значение типа %s", name, resultList(g.currentResults))
+		}
 		g.functions.WriteString("\treturn nil\n")
 	}
 
@@ -213,6 +480,9 @@ func (g *Generator) generateExpression(expr ast.Expression) (string, error) {
 func (g *Generator) generateExpressionWithCast(expr ast.Expression, inFunction bool, isFunctionCall bool) (string, error) {
 	switch expr := expr.(type) {
 	case *ast.Identifier:
+		if alias, ok := g.patternAliases[expr.Value]; ok {
+			return alias, nil
+		}
 		return expr.Value, nil
 	case *ast.IntegerLiteral:
 		return fmt.Sprintf("%d", expr.Value), nil
@@ -230,16 +500,25 @@ func (g *Generator) generateExpressionWithCast(expr ast.Expression, inFunction b
 		}
 		return fmt.Sprintf("(%s%s)", expr.Operator, right), nil
 	case *ast.InfixExpression:
-		left, err := g.generateExpressionWithCast(expr.Left, inFunction)
+		left, err := g.generateExpressionWithCast(expr.Left, inFunction, false)
 		if err != nil {
 			return "", err
 		}
-		right, err := g.generateExpressionWithCast(expr.Right, inFunction)
+		right, err := g.generateExpressionWithCast(expr.Right, inFunction, false)
 		if err != nil {
 			return "", err
 		}
-		if inFunction {
+		if code, ok := g.generateListOperator(expr, left, right); ok {
+			return code, nil
+		}
+		if code, ok, err := g.generateSetOperator(expr, left, right); ok || err != nil {
+			return code, err
+		}
+		// Операнды неизвестного типа внутри функций приводим к int64
+		if inFunction && g.staticType(expr.Left) == "" {
 			left = fmt.Sprintf("(%s.(int64))", left)
+		}
+		if inFunction && g.staticType(expr.Right) == "" {
 			right = fmt.Sprintf("(%s.(int64))", right)
 		}
 		// Обработка логических операторов
@@ -250,6 +529,33 @@ func (g *Generator) generateExpressionWithCast(expr ast.Expression, inFunction b
 			return fmt.Sprintf("(%s || %s)", left, right), nil
 		default:
 			return fmt.Sprintf("(%s %s %s)", left, expr.Operator, right), nil
+		}
+	case *ast.TupleLiteral:
+		return g.generateTuple(expr, inFunction)
+	case *ast.ComprehensionExpression:
+		return g.generateComprehension(expr)
+	case *ast.StarredExpression:
+		return "", fmt.Errorf("выражение со звёздочкой допустимо только в присваивании: %s", expr.String())
+	case *ast.FunctionLiteral:
+		return g.generateFunctionLiteral(expr)
+	case *ast.LambdaExpression:
+		return g.generateLambda(expr)
+	case *ast.SetLiteral:
+		return g.generateSet(expr, inFunction)
+	case *ast.HashLiteral:
+		pairs := []string{}
+		for i, key := range expr.Keys {
+			k, err := g.generateExpressionWithCast(key, inFunction, false)
+			if err != nil {
+				return "", err
+			}
+			v, err := g.generateExpressionWithCast(expr.Values[i], inFunction, false)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, fmt.Sprintf("%s: %s", g.boxValue(k, key), g.boxValue(v, expr.Values[i])))
+		}
+		return fmt.Sprintf("map[interface{}]interface{}{%s}", strings.Join(pairs, ", ")), nil
 	case *ast.ArrayLiteral:
 		elements := []string{}
 		for _, el := range expr.Elements {
@@ -257,61 +563,50 @@ func (g *Generator) generateExpressionWithCast(expr ast.Expression, inFunction b
 			if err != nil {
 				return "", err
 			}
-			elements = append(elements, str)
+			elements = append(elements, g.boxValue(str, el))
 		}
-		return fmt.Sprintf("[]interface{}{%s}", strings.Join(elements, ", ")), nil
+		g.useHelper("gopyList")
+		return fmt.Sprintf("gopyNewList(%s)", strings.Join(elements, ", ")), nil
 	case *ast.IndexExpression:
-		left, err := g.generateExpressionWithCast(expr.Left, inFunction, false)
-		if err != nil {
-			return "", err
-		}
-		index, err := g.generateExpressionWithCast(expr.Index, inFunction, false)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("%s[%s]", left, index), nil
-		}
+		return g.generateIndex(expr, inFunction)
+	case *ast.SliceExpression:
+		return g.generateSlice(expr, inFunction)
 	case *ast.CallExpression:
-		var args []string
-		for _, arg := range expr.Arguments {
-			a, err := g.generateExpressionWithCast(arg, inFunction, false)
-			if err != nil {
-				return "", err
-			}
-			args = append(args, a)
-		}
-		// Специальный случай для нашей встроенной функции print
-		if expr.Function.String() == "print" {
-			return fmt.Sprintf("fmt.Println(%s)", strings.Join(args, ", ")), nil
-		}
-		// Обычный вызов функции
-		return fmt.Sprintf("%s(%s)", expr.Function.String(), strings.Join(args, ", ")), nil
-	case *ast.IfExpression:
-		condition, err := g.generateExpressionWithCast(expr.Condition, inFunction)
+		code, err := g.generateCall(expr, inFunction)
 		if err != nil {
 			return "", err
 		}
-		consequence, err := g.generateBlockStatementWithCast(expr.Consequence, inFunction)
-		if err != nil {
-			return "", err
+		// Несколько результатов, использованных как одно значение, собираем в кортеж
+		if n := g.resultCount(expr); n > 1 {
+			return g.packResults(code, n), nil
 		}
-		code := fmt.Sprintf("if %s {\n%s}", condition, consequence)
-		if expr.Alternative != nil {
-			alternative, err := g.generateBlockStatementWithCast(expr.Alternative, inFunction)
+		return code, nil
+	case *ast.IfExpression, *ast.ConditionalExpression:
+		// if, использованный как значение, вычисляется через временную переменную
+		return g.generateConditionalValue(expr, inFunction)
+	case *ast.OrElseExpression:
+		return g.generateOrElse(expr, inFunction)
+	case *ast.ReceiveExpression:
+		return g.generateReceive(expr, inFunction)
+	case *ast.DotExpression:
+		if obj, isGo, err := g.goMember(expr); isGo {
 			if err != nil {
 				return "", err
 			}
-			code += fmt.Sprintf(" else {\n%s}", alternative)
+			return g.generateGoValue(expr, obj, inFunction)
 		}
-		return code, nil
-	case *ast.DotExpression:
-		left, err := g.generateExpressionWithCast(expr.Left, inFunction)
+		left, err := g.generateExpressionWithCast(expr.Left, inFunction, false)
 		if err != nil {
 			return "", err
 		}
 		if expr.Right == nil {
 			return "", fmt.Errorf("DotExpression: отсутствует поле/метод после точки")
 		}
+		// У значения неизвестного типа (interface{}) в Go нет полей и методов
+		if g.staticType(expr.Left) == "" {
+			return "", fmt.Errorf("строка %d: тип значения %s неизвестен, поэтому у него нельзя взять поле или метод %s: укажите тип в аннотации",
+				expr.Token.Line, expr.Left.String(), expr.Right.Value)
+		}
 		// Если DotExpression — часть CallExpression, добавим ()
 		if isFunctionCall {
 			return fmt.Sprintf("%s.%s()", left, expr.Right.Value), nil
@@ -322,6 +617,102 @@ func (g *Generator) generateExpressionWithCast(expr ast.Expression, inFunction b
 	}
 }
 
+// generateIfStatement генерирует if, результат которого не используется
+func (g *Generator) generateIfStatement(expr *ast.IfExpression, inFunction bool) (string, error) {
+	condition, err := g.generateExpressionWithCast(expr.Condition, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	condition = g.truthy(condition, expr.Condition)
+	consequence, err := g.generateBlockStatementWithCast(expr.Consequence, inFunction)
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("if %s {\n%s}", condition, consequence)
+	if expr.Alternative != nil {
+		alternative, err := g.generateBlockStatementWithCast(expr.Alternative, inFunction)
+		if err != nil {
+			return "", err
+		}
+		code += fmt.Sprintf(" else {\n%s}", alternative)
+	}
+	return code, nil
+}
+
+// generateExpressionStatement генерирует выражение, записанное отдельной инструкцией
+func (g *Generator) generateExpressionStatement(expr ast.Expression, inFunction bool) (string, error) {
+	if ifExpr, ok := expr.(*ast.IfExpression); ok {
+		return g.generateIfStatement(ifExpr, inFunction)
+	}
+	// Результаты функции Go, вызванной как инструкция, отбрасываются
+	if call, ok := expr.(*ast.CallExpression); ok {
+		if _, isGo, _ := g.goMember(call.Function); isGo {
+			return g.generateGoCall(call, inFunction, true)
+		}
+	}
+	return g.generateExpressionWithCast(expr, inFunction, false)
+}
+
+// generateCall генерирует вызов функции, метода класса или значения-функции
+func (g *Generator) generateCall(expr *ast.CallExpression, inFunction bool) (string, error) {
+	// sum/any/all от генераторного выражения встраиваются в цикл
+	if name, comp, ok := g.isReduction(expr); ok {
+		return g.generateReduction(name, comp)
+	}
+	// Встроенные функции: print, len, chan, open...
+	if name, b := g.lookupBuiltin(expr.Function); b != nil {
+		return g.generateBuiltin(name, b, expr, inFunction)
+	}
+	// Функции и типы импортированных пакетов Go: strings.Repeat("a", 3)
+	if _, isGo, _ := g.goMember(expr.Function); isGo {
+		return g.generateGoCall(expr, inFunction, false)
+	}
+	// Методы строк и других встроенных типов: s.split(), ", ".join(xs)
+	if name, method, known := g.lookupMethod(expr.Function); known {
+		if method == nil {
+			dot := expr.Function.(*ast.DotExpression)
+			return "", fmt.Errorf("строка %d: у значения типа %s нет метода %s", expr.Token.Line, g.staticType(dot.Left), dot.Right.Value)
+		}
+		return g.generateBuiltin(name, method, expr, inFunction)
+	}
+	// У файла есть методы read, write и close
+	if dot, ok := expr.Function.(*ast.DotExpression); ok && g.staticType(dot.Left) == "file" {
+		return g.generateFileMethod(dot, expr, inFunction)
+	}
+	// ValueError("...") создаёт встроенную ошибку
+	if ident, ok := expr.Function.(*ast.Identifier); ok && errorKinds[ident.Value] != "" {
+		return g.generateErrorValue(errorKinds[ident.Value], expr, inFunction)
+	}
+	// Вызов класса создаёт объект через сгенерированный конструктор
+	if ident, ok := expr.Function.(*ast.Identifier); ok && g.isClass(ident.Value) {
+		return g.generateConstructorCall(g.declaredClasses[ident.Value], expr, inFunction)
+	}
+	args, err := g.generateCallArguments(expr, inFunction)
+	if err != nil {
+		return "", err
+	}
+	// Значение-функцию неизвестного типа вызываем через reflect
+	if g.isDynamicCallee(expr.Function) {
+		fn, err := g.generateExpressionWithCast(expr.Function, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		g.useHelper("gopyCall")
+		return fmt.Sprintf("gopyCall(%s)", strings.Join(append([]string{fn}, args...), ", ")), nil
+	}
+	// Вызов функции-литерала на месте: (lambda x: x)(1)
+	switch expr.Function.(type) {
+	case *ast.FunctionLiteral, *ast.LambdaExpression:
+		fn, err := g.generateExpressionWithCast(expr.Function, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%s)", fn, strings.Join(args, ", ")), nil
+	}
+	// Обычный вызов функции
+	return fmt.Sprintf("%s(%s)", expr.Function.String(), strings.Join(args, ", ")), nil
+}
+
 func (g *Generator) generateBlockStatement(block *ast.BlockStatement) (string, error) {
 	var out bytes.Buffer
 	for _, stmt := range block.Statements {
@@ -350,6 +741,10 @@ func (g *Generator) generateBlockStatement(block *ast.BlockStatement) (string, e
 				if err != nil {
 					return "", err
 				}
+				val, err = g.generateGoFieldValue(name, s.Value, val)
+				if err != nil {
+					return "", err
+				}
 				out.WriteString(fmt.Sprintf("\t%s.%s = %s\n", left, right, val))
 			default:
 				val, err := g.generateExpression(s.Value)
@@ -361,19 +756,15 @@ func (g *Generator) generateBlockStatement(block *ast.BlockStatement) (string, e
 		case *ast.ExpressionStatement:
 			// d.bark() — CallExpression с DotExpression
 			if call, ok := s.Expression.(*ast.CallExpression); ok {
-				if dot, ok := call.Function.(*ast.DotExpression); ok {
+				if dot, ok := call.Function.(*ast.DotExpression); ok && !g.hasBuiltinMethods(dot) {
 					left, err := g.generateExpression(dot.Left)
 					if err != nil {
 						return "", err
 					}
 					right := dot.Right.Value
-					args := []string{}
-					for _, a := range call.Arguments {
-						astr, err := g.generateExpression(a)
-						if err != nil {
-							return "", err
-						}
-						args = append(args, astr)
+					args, err := g.generateCallArguments(call, false)
+					if err != nil {
+						return "", err
 					}
 					out.WriteString(fmt.Sprintf("\t%s.%s(%s)\n", left, right, strings.Join(args, ", ")))
 					continue
@@ -389,7 +780,7 @@ func (g *Generator) generateBlockStatement(block *ast.BlockStatement) (string, e
 				out.WriteString(fmt.Sprintf("\t%s.%s\n", left, right))
 				continue
 			}
-			exprStr, err := g.generateExpressionWithCast(s.Expression, false, false)
+			exprStr, err := g.generateExpressionStatement(s.Expression, false)
 			if err != nil {
 				return "", err
 			}
@@ -411,13 +802,79 @@ func (g *Generator) generateBlockStatementWithCast(block *ast.BlockStatement, in
 			if err != nil {
 				return "", err
 			}
-			out.WriteString("\t" + str + "\n")
+			out.WriteString("\t" + g.returnCode(str) + "\n")
 		case *ast.LetStatement:
-			val, err := g.generateExpressionWithCast(s.Value, inFunction, false)
+			str, err := g.generateLocalAssignment(s.Name.Value, s.Value, inFunction)
 			if err != nil {
 				return "", err
 			}
-			out.WriteString(fmt.Sprintf("\t%s := %s\n", s.Name.Value, val))
+			out.WriteString(str)
+		case *ast.FunctionStatement:
+			str, err := g.generateNestedFunction(s)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.MatchStatement:
+			str, err := g.generateMatch(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.TryStatement:
+			str, err := g.generateTry(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.RaiseStatement:
+			str, err := g.generateRaise(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.AssertStatement:
+			str, err := g.generateAssert(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.ForStatement:
+			str, err := g.generateFor(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.GoStatement:
+			str, err := g.generateGo(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.DeferStatement:
+			str, err := g.generateDefer(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.SendStatement:
+			str, err := g.generateSend(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString("\t" + str + "\n")
+		case *ast.SelectStatement:
+			str, err := g.generateSelect(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
+		case *ast.WithStatement:
+			str, err := g.generateWith(s, inFunction)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(str)
 		case *ast.AssignmentStatement:
 			switch name := s.Name.(type) {
 			case *ast.DotExpression:
@@ -430,7 +887,29 @@ func (g *Generator) generateBlockStatementWithCast(block *ast.BlockStatement, in
 				if err != nil {
 					return "", err
 				}
+				val, err = g.generateGoFieldValue(name, s.Value, val)
+				if err != nil {
+					return "", err
+				}
 				out.WriteString(fmt.Sprintf("\t%s.%s = %s\n", left, right, val))
+			case *ast.Identifier:
+				str, err := g.generateLocalAssignment(name.Value, s.Value, inFunction)
+				if err != nil {
+					return "", err
+				}
+				out.WriteString(str)
+			case *ast.IndexExpression:
+				str, err := g.generateIndexAssignment(name, s.Value, inFunction)
+				if err != nil {
+					return "", err
+				}
+				out.WriteString(str)
+			case *ast.TupleLiteral:
+				str, _, err := g.generateTupleAssignment(name, s.Value, inFunction)
+				if err != nil {
+					return "", err
+				}
+				out.WriteString(str)
 			default:
 				val, err := g.generateExpressionWithCast(s.Value, inFunction, false)
 				if err != nil {
@@ -441,19 +920,15 @@ func (g *Generator) generateBlockStatementWithCast(block *ast.BlockStatement, in
 		case *ast.ExpressionStatement:
 			// self.bark() — CallExpression с DotExpression
 			if call, ok := s.Expression.(*ast.CallExpression); ok {
-				if dot, ok := call.Function.(*ast.DotExpression); ok {
+				if dot, ok := call.Function.(*ast.DotExpression); ok && !g.hasBuiltinMethods(dot) {
 					left, err := g.generateExpressionWithCast(dot.Left, inFunction, true)
 					if err != nil {
 						return "", err
 					}
 					right := dot.Right.Value
-					args := []string{}
-					for _, a := range call.Arguments {
-						astr, err := g.generateExpressionWithCast(a, inFunction, true)
-						if err != nil {
-							return "", err
-						}
-						args = append(args, astr)
+					args, err := g.generateCallArguments(call, inFunction)
+					if err != nil {
+						return "", err
 					}
 					out.WriteString(fmt.Sprintf("\t%s.%s(%s)\n", left, right, strings.Join(args, ", ")))
 					continue
@@ -469,7 +944,7 @@ func (g *Generator) generateBlockStatementWithCast(block *ast.BlockStatement, in
 				out.WriteString(fmt.Sprintf("\t%s.%s\n", left, right))
 				continue
 			}
-			exprStr, err := g.generateExpressionWithCast(s.Expression, inFunction, false)
+			exprStr, err := g.generateExpressionStatement(s.Expression, inFunction)
 			if err != nil {
 				return "", err
 			}
@@ -485,26 +960,80 @@ func (g *Generator) generateBlockStatementWithCast(block *ast.BlockStatement, in
 func (g *Generator) generateSimpleStatementWithCast(stmt ast.Statement, inFunction bool, isFunctionCall bool) (string, error) {
 	switch stmt := stmt.(type) {
 	case *ast.ReturnStatement:
+		if _, ok := stmt.ReturnValue.(*ast.TupleLiteral); ok || len(g.currentResults) > 1 {
+			return g.generateTupleReturn(stmt, inFunction)
+		}
 		val, err := g.generateExpressionWithCast(stmt.ReturnValue, inFunction, isFunctionCall)
 		if err != nil {
 			return "", err
 		}
+		if len(g.currentResults) == 1 {
+			val = g.coerce(val, stmt.ReturnValue, g.currentResults[0])
+		}
 		return fmt.Sprintf("return %s", val), nil
 	default:
 		return "", fmt.Errorf("неподдерживаемый тип инструкции для generateSimpleStatement: %T", stmt)
 	}
 }
 
-// generateClass генерирует Go-структуру и методы для класса
+// generateClass генерирует Go-структуру, конструктор и методы для класса
 func (g *Generator) generateClass(class *ast.ClassStatement) error {
-	// Структура с полями
+	// Класс регистрируется заранее, чтобы поля могли ссылаться на него самого
+	g.declaredClasses[class.Name.Value] = class
+
+	// Структура с полями; класс ошибки встраивает gopyError, которая хранит
+	// вид ошибки, сообщение и место raise
 	fields := []string{}
-	for _, f := range class.Fields {
-		fields = append(fields, fmt.Sprintf("%s interface{}", f.Value))
+	defaults := []string{}
+	if class.Base != nil {
+		if err := g.declareErrorClass(class); err != nil {
+			return err
+		}
+		fields = append(fields, "gopyError")
+		defaults = append(defaults, fmt.Sprintf("gopyError: gopyError{kind: gopyKind%s}", class.Name.Value))
+	}
+	seen := make(map[string]bool)
+	for i, f := range class.Fields {
+		if seen[f.Name.Value] {
+			return fmt.Errorf("поле %s уже объявлено в классе %s", f.Name.Value, class.Name.Value)
+		}
+		seen[f.Name.Value] = true
+		if class.Base != nil && i == 0 {
+			continue // message
+		}
+
+		typ, err := g.goType(g.fieldType(f))
+		if err != nil {
+			return err
+		}
+		field := fmt.Sprintf("%s %s", f.Name.Value, typ)
+		if source, ok := g.goNames[f.Name.Value]; ok {
+			// Вывод объекта называет поле так, как оно записано в Gopy
+			field += fmt.Sprintf(" `gopy:%q`", source)
+		}
+		fields = append(fields, field)
+
+		if f.Default != nil {
+			val, err := g.generateExpression(f.Default)
+			if err != nil {
+				return err
+			}
+			val = g.coerce(val, f.Default, g.fieldType(f))
+			defaults = append(defaults, fmt.Sprintf("%s: %s", f.Name.Value, val))
+		} else if val := g.emptyValue(g.fieldType(f)); val != "" {
+			defaults = append(defaults, fmt.Sprintf("%s: %s", f.Name.Value, val))
+		}
 	}
 	g.functions.WriteString(fmt.Sprintf("type %s struct{%s}\n\n", class.Name.Value, strings.Join(fields, "; ")))
-	g.declaredClasses[class.Name.Value] = true
+
+	// Конструктор заполняет поля значениями по умолчанию
+	g.functions.WriteString(fmt.Sprintf("func %s() *%s {\n", g.constructorName(class.Name.Value), class.Name.Value))
+	g.functions.WriteString(fmt.Sprintf("\treturn &%s{%s}\n", class.Name.Value, strings.Join(defaults, ", ")))
+	g.functions.WriteString("}\n\n")
+
 	// Методы
+	g.currentClass = class.Name.Value
+	defer func() { g.currentClass = "" }()
 	for _, m := range class.Methods {
 		if err := g.generateMethod(class.Name.Value, m); err != nil {
 			return err
@@ -513,54 +1042,388 @@ func (g *Generator) generateClass(class *ast.ClassStatement) error {
 	return nil
 }
 
+// constructorName возвращает имя конструктора класса: NewUser. В библиотеке
+// конструктор класса с неэкспортированным именем тоже не экспортируется, чтобы
+// не попасть в API пакета рядом с экспортированным NewUser
+func (g *Generator) constructorName(class string) string {
+	if r, _ := utf8.DecodeRuneInString(class); g.library != "" && !unicode.IsUpper(r) {
+		return "gopyNew_" + class
+	}
+	return "New" + class
+}
+
+// generateConstructorCall генерирует создание объекта: User(name="Alice", age=25)
+func (g *Generator) generateConstructorCall(class *ast.ClassStatement, call *ast.CallExpression, inFunction bool) (string, error) {
+	className := class.Name.Value
+	if len(call.Arguments) == 0 {
+		return fmt.Sprintf("%s()", g.constructorName(className)), nil
+	}
+
+	assigned := make(map[string]bool)
+	stmts := []string{fmt.Sprintf("gopyObj := %s()", g.constructorName(className))}
+	for i, arg := range call.Arguments {
+		var field *ast.ClassField
+		value := arg
+		if kw, ok := arg.(*ast.KeywordArgument); ok {
+			field = findField(class, kw.Name.Value)
+			if field == nil {
+				return "", fmt.Errorf("у класса %s нет поля %s", className, kw.Name.Value)
+			}
+			value = kw.Value
+		} else {
+			if len(assigned) != i {
+				return "", fmt.Errorf("позиционный аргумент после именованного при создании %s", className)
+			}
+			if i >= len(class.Fields) {
+				return "", fmt.Errorf("класс %s принимает не более %d аргументов, получено %d", className, len(class.Fields), len(call.Arguments))
+			}
+			field = class.Fields[i]
+		}
+		if assigned[field.Name.Value] {
+			return "", fmt.Errorf("поле %s класса %s задано несколько раз", field.Name.Value, className)
+		}
+		assigned[field.Name.Value] = true
+
+		val, err := g.generateExpressionWithCast(value, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		val = g.coerce(val, value, g.fieldType(field))
+		stmts = append(stmts, fmt.Sprintf("gopyObj.%s = %s", field.Name.Value, val))
+	}
+	stmts = append(stmts, "return gopyObj")
+
+	return fmt.Sprintf("func() *%s { %s }()", className, strings.Join(stmts, "; ")), nil
+}
+
 // generateMethod генерирует Go-метод для структуры
 func (g *Generator) generateMethod(className string, m *ast.MethodStatement) error {
-	var params []string
-	for i, p := range m.Parameters {
-		if i == 0 {
-			continue // self
-		}
-		params = append(params, fmt.Sprintf("%s interface{}", p.Value))
+	globalTypes, outerTypes, localFunctions := g.variableTypes, g.outerTypes, g.localFunctions
+	g.variableTypes, g.outerTypes, g.localFunctions = map[string]string{"self": className}, nil, make(map[string]*ast.FunctionLiteral)
+	defer func() { g.variableTypes, g.outerTypes, g.localFunctions = globalTypes, outerTypes, localFunctions }()
+
+	// self всегда первый параметр и становится получателем метода
+	params, err := g.generateParameters(methodParameters(m), m.ParameterTypes)
+	if err != nil {
+		return err
 	}
-	// self всегда первый параметр
-	g.functions.WriteString(fmt.Sprintf("func (self *%s) %s(%s) interface{} {\n", className, m.Name.Value, strings.Join(params, ", ")))
+	g.currentResults = resultTypes(m.ReturnType, m.ReturnTypes, m.Body)
+	defer func() { g.currentResults = nil }()
+	result, err := g.goResult(g.currentResults)
+	if err != nil {
+		return err
+	}
+
+	g.functions.WriteString(fmt.Sprintf("func (self *%s) %s(%s) %s {\n", className, m.Name.Value, params, result))
+	restore := g.enterBody(m.Body)
 	body, err := g.generateBlockStatementWithCast(m.Body, true)
+	restore()
 	if err != nil {
 		return err
 	}
 	g.functions.WriteString(body)
-	if !strings.Contains(body, "return") {
+	if needsReturn(m.Body.Statements) {
+		if m.ReturnType != nil || len(m.ReturnTypes) > 0 {
+			return fmt.Errorf("метод %s.%s должен возвращать значение типа %s", className, m.Name.Value, resultList(g.currentResults))
+		}
 		g.functions.WriteString("\treturn nil\n")
 	}
 	g.functions.WriteString("}\n\n")
 	return nil
 }
 
-// generateForStatement генерирует Go-код для цикла for
-func (g *Generator) generateForStatement(stmt *ast.ForStatement) error {
-	iterable, err := g.generateExpressionWithCast(stmt.Iterable, false, false)
-	if err != nil {
-		return err
-	}
+// generateFor генерирует Go-код для цикла for. Цикл по каналу получает значения,
+// пока канал не закрыт
+func (g *Generator) generateFor(stmt *ast.ForStatement, inFunction bool) (string, error) {
+	var out bytes.Buffer
+	name := stmt.Iterator.Value
+	restore := func() {}
+	if g.staticType(stmt.Iterable) == "chan" {
+		ch, err := g.generateChannel(stmt.Iterable, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(fmt.Sprintf("\tfor %s := range %s {\n", name, ch))
+		restore = g.bindPattern([]patternBinding{{name: name}})
+	} else if typ := g.staticType(stmt.Iterable); typ == "list" || typ == "tuple" || typ == "str" || typ == "dict" || typ == "set" {
+		// Обход элементов списка, кортежа, строки или ключей словаря
+		iterable, err := g.generateExpressionWithCast(stmt.Iterable, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		if typ == "dict" || typ == "set" {
+			out.WriteString(fmt.Sprintf("\tfor %s := range %s {\n", name, iterable))
+		} else {
+			out.WriteString(fmt.Sprintf("\tfor _, %s := range %s {\n", name, g.iterableItems(iterable, stmt.Iterable, stmt.Token.Line)))
+		}
+		restore = g.bindPattern([]patternBinding{{name: name}})
+	} else if call, ok := stmt.Iterable.(*ast.CallExpression); ok && g.isRangeCall(call) {
+		restore = g.bindPattern([]patternBinding{{name: name, typ: "int"}})
+		header, _, err := g.generateRangeHeader(name, call, inFunction)
+		if err != nil {
+			restore()
+			return "", err
+		}
+		out.WriteString(fmt.Sprintf("\t%s {\n", header))
+	} else {
+		iterable, err := g.generateExpressionWithCast(stmt.Iterable, inFunction, false)
+		if err != nil {
+			return "", err
+		}
 
-	// Предполагаем, что Iterable - это IntegerLiteral для range(N)
-	// В будущем здесь будет более сложная логика для итерируемых объектов
-	// Пока что генерируем простой for-цикл Go
-	g.mainBody.WriteString(fmt.Sprintf("\tfor %s := 0; %s < %s; %s++ {\n", stmt.Iterator.Value, stmt.Iterator.Value, iterable, stmt.Iterator.Value))
+		// Предполагаем, что Iterable - это IntegerLiteral для range(N)
+		// В будущем здесь будет более сложная логика для итерируемых объектов
+		// Пока что генерируем простой for-цикл Go
+		start := "0"
+		if inFunction {
+			// Целые внутри функций имеют тип int64
+			start = "int64(0)"
+			if g.staticType(stmt.Iterable) == "" {
+				iterable = g.intValue(iterable, stmt.Iterable)
+			}
+		}
+		out.WriteString(fmt.Sprintf("\tfor %s := %s; %s < %s; %s++ {\n", name, start, name, iterable, name))
+		restore = g.bindPattern([]patternBinding{{name: name, typ: "int"}})
+	}
 
-	body, err := g.generateBlockStatementWithCast(stmt.Body, false)
+	body, err := g.generateBlockStatementWithCast(stmt.Body, inFunction)
+	restore()
 	if err != nil {
-		return err
+		return "", err
 	}
-	g.mainBody.WriteString(body)
-	g.mainBody.WriteString("\t}\n")
+	out.WriteString(body)
+	out.WriteString("\t}\n")
 
-	return nil
+	return out.String(), nil
 }
 
 // isClass проверяет, объявлен ли класс с таким именем
 func (g *Generator) isClass(name string) bool {
-	return g.declaredClasses[name]
+	return g.declaredClasses[name] != nil
+}
+
+// findField ищет поле класса по имени
+func findField(class *ast.ClassStatement, name string) *ast.ClassField {
+	for _, f := range class.Fields {
+		if f.Name.Value == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// fieldType возвращает тип поля: явно указанный или выведенный из значения по умолчанию
+func (g *Generator) fieldType(f *ast.ClassField) string {
+	if f.Type != nil {
+		return f.Type.Value
+	}
+	if f.Default != nil {
+		return g.staticType(f.Default)
+	}
+	return ""
+}
+
+// emptyValue возвращает пустое значение для типа, нулевое значение которого в Go
+// нельзя использовать: список, словарь и множество без элементов вместо nil.
+// Для остальных типов возвращается пустая строка
+func (g *Generator) emptyValue(typ string) string {
+	switch typ {
+	case "list":
+		g.useHelper("gopyList")
+		return "gopyNewList()"
+	case "dict":
+		return "map[interface{}]interface{}{}"
+	case "set":
+		return "map[interface{}]struct{}{}"
+	}
+	return ""
+}
+
+// goTypes сопоставляет встроенные типы Gopy с типами Go
+var goTypes = map[string]string{
+	"int":   "int64",
+	"float": "float64",
+	"str":   "string",
+	"bool":  "bool",
+	"list":  "*gopyList",
+	"dict":  "map[interface{}]interface{}",
+	"tuple": "gopyTuple",
+	"set":   "map[interface{}]struct{}",
+	"chan":  "chan interface{}",
+	"Lock":  "*sync.Mutex",
+	"file":  "*os.File",
+}
+
+// goType возвращает тип Go для типа Gopy; пустая строка означает неизвестный тип
+func (g *Generator) goType(typ string) (string, error) {
+	if typ == "" {
+		return "interface{}", nil
+	}
+	if typ == "tuple" {
+		g.useHelper("gopyTuple")
+	}
+	if typ == "list" {
+		g.useHelper("gopyList")
+	}
+	if typ == "Lock" {
+		g.imports["sync"] = true
+	}
+	if typ == "file" {
+		g.imports["os"] = true
+	}
+	if goTyp, ok := goTypes[typ]; ok {
+		return goTyp, nil
+	}
+	if t, ok := g.goValueTypes[typ]; ok {
+		return g.goTypeString(t), nil
+	}
+	if t := g.goNamedType(typ); t != nil {
+		return g.goTypeString(t), nil
+	}
+	if g.isClass(typ) {
+		return "*" + typ, nil
+	}
+	if g.declaredInterfaces[typ] != nil {
+		return typ, nil
+	}
+	return "", fmt.Errorf("неизвестный тип: %s", typ)
+}
+
+// staticType возвращает тип выражения Gopy, если он известен на этапе трансляции
+func (g *Generator) staticType(expr ast.Expression) string {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return "int"
+	case *ast.StringLiteral:
+		return "str"
+	case *ast.Boolean:
+		return "bool"
+	case *ast.Identifier:
+		typ, _ := g.lookupVariable(expr.Value)
+		return typ
+	case *ast.FunctionLiteral, *ast.LambdaExpression:
+		return "func"
+	case *ast.ArrayLiteral:
+		return "list"
+	case *ast.HashLiteral:
+		return "dict"
+	case *ast.SetLiteral:
+		return "set"
+	case *ast.TupleLiteral:
+		return "tuple"
+	case *ast.PrefixExpression:
+		if expr.Operator == "-" {
+			return g.staticType(expr.Right)
+		}
+		return "bool"
+	case *ast.InfixExpression:
+		switch expr.Operator {
+		case "|", "&", "^":
+			if g.staticType(expr.Left) == "set" || g.staticType(expr.Right) == "set" {
+				return "set"
+			}
+			return "int"
+		case "+", "-", "*", "/":
+			// Список, умноженный на число, остаётся списком: 3 * [0]
+			if expr.Operator == "*" && g.staticType(expr.Right) == "list" {
+				return "list"
+			}
+			// Операнды неизвестного типа приводятся к int64
+			if typ := g.staticType(expr.Left); typ != "" {
+				return typ
+			}
+			if typ := g.staticType(expr.Right); typ != "" {
+				return typ
+			}
+			return "int"
+		default:
+			return "bool"
+		}
+	case *ast.IfExpression, *ast.ConditionalExpression:
+		return g.conditionalType(expr)
+	case *ast.OrElseExpression:
+		return g.orElseType(expr)
+	case *ast.ComprehensionExpression:
+		switch expr.Kind {
+		case ast.DictComprehension:
+			return "dict"
+		case ast.SetComprehension:
+			return "set"
+		}
+		return "list"
+	case *ast.CallExpression:
+		if ident, ok := expr.Function.(*ast.Identifier); ok && g.isClass(ident.Value) {
+			return ident.Value
+		}
+		if dot, ok := expr.Function.(*ast.DotExpression); ok && dot.Right.Value == "read" && g.staticType(dot.Left) == "file" {
+			return "str"
+		}
+		if name, _, ok := g.isReduction(expr); ok {
+			if name == "sum" {
+				return "int"
+			}
+			return "bool"
+		}
+		if _, b, _ := g.lookupMethod(expr.Function); b != nil {
+			return b.result
+		}
+		if obj, _, _ := g.goMember(expr.Function); obj != nil {
+			if typeName, ok := obj.(*types.TypeName); ok {
+				if isGoStruct(expr, typeName) {
+					return g.gopyTypeOf(types.NewPointer(typeName.Type()))
+				}
+				return g.gopyTypeOf(typeName.Type())
+			}
+		}
+		if _, b := g.lookupBuiltin(expr.Function); b != nil {
+			if b.resultOf != nil {
+				return b.resultOf(g, expr)
+			}
+			return b.result
+		}
+		if sig := g.lookupSignature(expr.Function); sig != nil {
+			if len(sig.results) > 1 {
+				return "tuple"
+			}
+			return sig.results[0]
+		}
+	case *ast.IndexExpression:
+		if g.staticType(expr.Left) == "str" {
+			return "str"
+		}
+	case *ast.SliceExpression:
+		if typ := g.staticType(expr.Left); typ == "list" || typ == "str" || typ == "tuple" {
+			return typ
+		}
+	case *ast.DotExpression:
+		if obj, _, _ := g.goMember(expr); obj != nil {
+			return g.gopyTypeOf(obj.Type())
+		}
+		if class := g.declaredClasses[g.staticType(expr.Left)]; class != nil {
+			if field := findField(class, expr.Right.Value); field != nil {
+				return g.fieldType(field)
+			}
+		}
+	}
+	return ""
+}
+
+// coerce приводит сгенерированное значение к типу Gopy typ, если типы могут не совпасть
+func (g *Generator) coerce(code string, value ast.Expression, typ string) string {
+	goTyp, err := g.goType(typ)
+	if err != nil || goTyp == "interface{}" {
+		return code
+	}
+	switch valueType := g.staticType(value); {
+	case valueType == "":
+		return fmt.Sprintf("%s.(%s)", code, goTyp)
+	case valueType == "int" && typ == "int":
+		if _, ok := value.(*ast.Identifier); ok {
+			// main хранит целые литералы в переменных типа int, а не int64
+			return fmt.Sprintf("int64(%s)", code)
+		}
+	}
+	return code
 }
 
 
@@ -1,8 +1,16 @@
 package generator
 
 import (
+	goast "go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
 	"gopy/lexer"
 	"gopy/parser"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -167,23 +175,15 @@ print(result)
 		t.Fatalf("Code generation failed: %s", err)
 	}
 
-	expectedCode := `package main
-
-import (
-	"fmt"
-)
-
-func add(a interface{}, b interface{}) interface{} {
-	return ((a.(int64)) + (b.(int64)))
-}
-
-func main() {
-	result := add(5, 10)
-	fmt.Println(result)
-}
-`
-	if generatedCode != expectedCode {
-		t.Errorf("Generated code is wrong.\nExpected:\n%s\nGot:\n%s", expectedCode, generatedCode)
+	// Результат без аннотации выводится через gopyStr, как в Python
+	expected := []string{
+		"func add(a interface{}, b interface{}) interface{} {\n\treturn ((a.(int64)) + (b.(int64)))\n}\n",
+		"func main() {\n\tresult := add(int64(5), int64(10))\n\tfmt.Println(gopyStr(result))\n}\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("Generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
 	}
 }
 
@@ -214,13 +214,17 @@ import (
 
 type Dog struct{}
 
+func NewDog() *Dog {
+	return &Dog{}
+}
+
 func (self *Dog) bark() interface{} {
 	fmt.Println("гав!")
 	return nil
 }
 
 func main() {
-	d := &Dog{}
+	d := NewDog()
 	d.bark()
 }
 `
@@ -246,6 +250,38 @@ d.bark()
 	if len(p.Errors()) > 0 {
 		t.Fatalf("Parser errors: %v", p.Errors())
 	}
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"type Dog struct{name interface{}; age interface{}}\n",
+		"func NewDog() *Dog {\n\treturn &Dog{}\n}\n",
+		"func (self *Dog) bark() interface{} {\n\tfmt.Println(gopyStr(self.name))\n\tfmt.Println(gopyStr(self.age))\n\treturn nil\n}\n",
+		"func main() {\n\td := NewDog()\n\td.name = \"Шарик\"\n\td.age = 5\n\td.bark()\n}\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("Generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestClassFieldDefaultsGeneration(t *testing.T) {
+	input := `
+class User
+    name = "anon"
+    age: int = 0
+    email
+let u = User()
+let a = User(name="Alice", age=25)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
 	gen := New()
 	generatedCode, err := gen.Generate(program)
 	if err != nil {
@@ -257,19 +293,279 @@ import (
 	"fmt"
 )
 
-type Dog struct{name interface{}; age interface{}}
+type User struct{name string; age int64; email interface{}}
 
-func (self *Dog) bark() interface{} {
-	fmt.Println(self.name)
-	fmt.Println(self.age)
+func NewUser() *User {
+	return &User{name: "anon", age: 0}
+}
+
+func main() {
+	u := NewUser()
+	a := func() *User { gopyObj := NewUser(); gopyObj.name = "Alice"; gopyObj.age = 25; return gopyObj }()
+}
+`
+	if generatedCode != expectedCode {
+		t.Errorf("Generated code is wrong.\nExpected:\n%s\nGot:\n%s", expectedCode, generatedCode)
+	}
+}
+
+func TestClassEmptyContainerFields(t *testing.T) {
+	input := `
+class Bag
+    items: list
+    index: dict
+    tags: set
+    count: int
+b = Bag()
+print(len(b.items))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	// Список, словарь и множество без значения по умолчанию создаются пустыми, а не nil
+	expected := "func NewBag() *Bag {\n\treturn &Bag{items: gopyNewList(), index: map[interface{}]interface{}{}, tags: map[interface{}]struct{}{}}\n}\n"
+	if !strings.Contains(generatedCode, expected) {
+		t.Errorf("Generated code does not contain %q.\nGot:\n%s", expected, generatedCode)
+	}
+}
+
+func TestClassConstructorErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{"class User\n    name\nlet u = User(email=\"a\")\n", "у класса User нет поля email"},
+		{"class User\n    name\nlet u = User(\"a\", \"b\")\n", "класс User принимает не более 1 аргументов, получено 2"},
+		{"class User\n    name\n    name = 1\n", "поле name уже объявлено в классе User"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q for input %q, got none", tt.expectedErr, tt.input)
+			continue
+		}
+		if err.Error() != tt.expectedErr {
+			t.Errorf("wrong error for input %q. want=%q, got=%q", tt.input, tt.expectedErr, err.Error())
+		}
+	}
+}
+
+func TestInterfaceGeneration(t *testing.T) {
+	input := `
+interface Shape
+    def area() -> int
+class Square
+    side: int = 2
+    def area(self) -> int
+        return self.side * self.side
+let show = def(s: Shape)
+    print(s.area())
+show(Square())
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expectedCode := `package main
+
+import (
+	"fmt"
+)
+
+type Shape interface {
+	area() int64
+}
+
+type Square struct{side int64}
+
+func NewSquare() *Square {
+	return &Square{side: 2}
+}
+
+func (self *Square) area() int64 {
+	return (self.side * self.side)
+}
+
+func show(s Shape) interface{} {
+	fmt.Println(s.area())
 	return nil
 }
 
 func main() {
-	d := &Dog{}
-	d.name = "Шарик"
-	d.age = 5
-	d.bark()
+	show(NewSquare())
+}
+`
+	if generatedCode != expectedCode {
+		t.Errorf("Generated code is wrong.\nExpected:\n%s\nGot:\n%s", expectedCode, generatedCode)
+	}
+}
+
+func TestInterfaceSatisfactionErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedErr string
+	}{
+		{
+			"interface Shape\n    def area() -> int\nclass Dog\n    def bark(self)\n        print(1)\nlet show = def(s: Shape)\n    print(1)\nshow(Dog())\n",
+			"класс Dog не реализует интерфейс Shape: нет метода area",
+		},
+		{
+			"interface Shape\n    def area() -> int\nclass Dog\n    def area(self, x)\n        return x\nlet show = def(s: Shape)\n    print(1)\nshow(Dog())\n",
+			"класс Dog не реализует интерфейс Shape: метод area(x), ожидается area() -> int",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expectedErr)
+			continue
+		}
+		if err.Error() != tt.expectedErr {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expectedErr, err.Error())
+		}
+	}
+}
+
+func TestClosureGeneration(t *testing.T) {
+	input := `
+let make_adder = def(n)
+    let add = def(x)
+        return x + n
+    return add
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expectedCode := `package main
+
+import (
+	"fmt"
+)
+
+func make_adder(n interface{}) interface{} {
+	var add func(interface{}) interface{}
+	add = func(x interface{}) interface{} {
+	return ((x.(int64)) + (n.(int64)))
+}
+	return add
+}
+
+func main() {
+}
+`
+	if generatedCode != expectedCode {
+		t.Errorf("Generated code is wrong.\nExpected:\n%s\nGot:\n%s", expectedCode, generatedCode)
+	}
+}
+
+func TestFunctionValueGeneration(t *testing.T) {
+	input := `
+let apply = def(fn, v)
+    return fn(v)
+double = lambda x: x * 2
+print(double(21))
+fs = [double, lambda: 1]
+print(fs[0](2))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+
+	expected := []string{
+		"\t\"reflect\"\n",
+		"func gopyCall(fn interface{}, args ...interface{}) interface{} {\n",
+		"func apply(fn interface{}, v interface{}) interface{} {\n\treturn gopyCall(fn, v)\n}\n",
+		"\tdouble := func(x interface{}) interface{} { return ((x.(int64)) * 2) }\n",
+		"\tfmt.Println(gopyStr(double(int64(21))))\n",
+		"\tfs := gopyNewList(double, func() interface{} { return 1 })\n",
+		"\tfmt.Println(gopyStr(gopyCall(gopyIndex(fs, 0, 7), int64(2))))\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("Generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestFunctionStatementGeneration(t *testing.T) {
+	input := `
+print(twice(4))
+def twice(x: int) -> int
+    return x * 2
+def outer(n: int) -> int
+    def inner(k: int) -> int
+        return k + n
+    return inner(1)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expectedCode := `package main
+
+import (
+	"fmt"
+)
+
+func twice(x int64) int64 {
+	return (x * 2)
+}
+
+func outer(n int64) int64 {
+	var inner func(int64) int64
+	inner = func(k int64) int64 {
+	return (k + n)
+}
+	return inner(1)
+}
+
+func main() {
+	fmt.Println(twice(4))
 }
 `
 	if generatedCode != expectedCode {
@@ -277,6 +573,1672 @@ func main() {
 	}
 }
 
+func TestDuplicateFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"def greet()\n    return 1\ndef greet()\n    return 2\n",
+			"функция greet уже объявлена",
+		},
+		{
+			"let greet = def()\n    return 1\ndef greet()\n    return 2\n",
+			"функция greet уже объявлена",
+		},
+		{
+			"def outer()\n    def inner()\n        return 1\n    def inner()\n        return 2\n    return inner()\n",
+			"функция inner уже объявлена",
+		},
+		{
+			"print(twice(1, 2))\ndef twice(x)\n    return x * 2\n",
+			"twice ожидает 1 аргументов, получено 2",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestTupleUnpackingGeneration(t *testing.T) {
+	input := `
+def divmod(a: int, b: int) -> (int, int)
+    return a / b, a - a / b * b
+def pair(x)
+    return x, x
+q, r = divmod(17, 5)
+a, b = 1, 2
+a, b = b, a
+first, *rest = [1, 2, 3]
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+
+	expected := []string{
+		"func divmod(a int64, b int64) (int64, int64) {\n\treturn (a / b), (a - ((a / b) * b))\n}\n",
+		"func pair(x interface{}) (interface{}, interface{}) {\n\treturn x, x\n}\n",
+		"\tq, r := divmod(17, 5)\n",
+		"\ta, b := 1, 2\n\ta, b = b, a\n",
+		"\tgopyTmp1 := gopyUnpack(gopyNewList(int64(1), int64(2), int64(3)), 2, 1)\n\tfirst, rest := gopyTmp1[0], gopyTmp1[1].(*gopyList)\n",
+		"func gopyUnpack(value interface{}, count int, star int) []interface{} {\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("Generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestTupleUnpackingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a, b = 1, 2, 3\n", "нельзя распаковать 3 значений в 2 переменных"},
+		{"*a, *b = [1, 2]\n", "в присваивании может быть только одна цель со звёздочкой"},
+		{"def f() -> (int, int)\n    return 1\n", "return должен вернуть 2 значений, получено 1"},
+		{"def f()\n    return 1, 2\nx, y, z = f()\n", "нельзя распаковать 2 значений в 3 переменных"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestIndexAndSliceGeneration(t *testing.T) {
+	input := `
+xs = [1, 2, 3]
+print(xs[-1])
+print(xs[1:])
+s = "hello"
+print(s[::-1])
+xs[-1] = 30
+d = {"a": 1}
+d["b"] = 2
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+
+	expected := []string{
+		"\tfmt.Println(gopyStr(gopyIndex(xs, (-1), 3)))\n",
+		"\tfmt.Println(gopyStr(gopySlice(xs, 1, nil, nil, 4).(*gopyList)))\n",
+		"\tfmt.Println(gopySlice(s, nil, nil, (-1), 6).(string))\n",
+		"\tgopySetIndex(xs, (-1), 30, 7)\n",
+		"\td[\"b\"] = 2\n",
+		"panic(gopyErrorf(gopyIndexError, line, \"индекс %s вне диапазона: %d при длине %d\", kind, gopyInt(index, line), length))",
+		"func gopySliceIndices(length int, start, stop, step interface{}, line int) []int {\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("Generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestComprehensionGeneration(t *testing.T) {
+	input := `
+xs = [3, -1]
+ys = [x * 2 for x in xs if x > 0]
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expectedCode := `package main
+
+import (
+	"fmt"
+)
+
+type gopyList struct {
+	items []interface{}
+}
+
+func gopyNewList(items ...interface{}) *gopyList {
+	return &gopyList{items: items}
+}
+
+// gopyListCopy создаёт список с копией элементов items
+func gopyListCopy(items []interface{}) *gopyList {
+	return &gopyList{items: append([]interface{}{}, items...)}
+}
+
+func (l *gopyList) append(item interface{}) {
+	l.items = append(l.items, item)
+}
+
+func main() {
+	xs := gopyNewList(int64(3), int64((-1)))
+	ys := func() *gopyList {
+	gopyResult := gopyNewList()
+	for _, x := range xs.items {
+		if ((x.(int64)) > 0) {
+			gopyResult.append(int64(((x.(int64)) * 2)))
+		}
+	}
+	return gopyResult
+}()
+}
+`
+	if generatedCode != expectedCode {
+		t.Errorf("Generated code is wrong.\nExpected:\n%s\nGot:\n%s", expectedCode, generatedCode)
+	}
+}
+
+func TestGeneratorReductionGeneration(t *testing.T) {
+	input := `
+total = sum(x * x for x in range(5))
+found = any(c for c in "abc")
+squares = {x: x * x for x in range(3)}
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+
+	expected := []string{
+		"\ttotal := func() int64 {\n\tgopyTotal := int64(0)\n\tfor x := int64(0); x < int64(5); x++ {\n\t\tgopyTotal += int64((x * x))\n\t}\n\treturn gopyTotal\n}()\n",
+		"\tfound := func() bool {\n\tfor _, c := range gopyIter(\"abc\", 3) {\n\t\tif gopyTruthy(c) {\n\t\t\treturn true\n\t\t}\n\t}\n\treturn false\n}()\n",
+		"\tfor x := int64(0); x < int64(3); x++ {\n\t\tgopyResult[int64(x)] = int64((x * x))\n\t}\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("Generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestConditionalValueGeneration(t *testing.T) {
+	input := `
+def sign(n: int) -> str
+    return "pos" if n > 0 else "neg" if n < 0 else "zero"
+def pick(flag)
+    x = if flag
+        y = 10
+        y * 2
+    else
+        5
+    return x
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expectedCode := `package main
+
+import (
+	"fmt"
+)
+
+type gopyList struct {
+	items []interface{}
+}
+
+func gopyNewList(items ...interface{}) *gopyList {
+	return &gopyList{items: items}
+}
+
+// gopyListCopy создаёт список с копией элементов items
+func gopyListCopy(items []interface{}) *gopyList {
+	return &gopyList{items: append([]interface{}{}, items...)}
+}
+
+func (l *gopyList) append(item interface{}) {
+	l.items = append(l.items, item)
+}
+
+func gopyTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case *gopyList:
+		return len(v.items) > 0
+	case gopyTuple:
+		return len(v.gopyItems()) > 0
+	case map[interface{}]interface{}:
+		return len(v) > 0
+	case map[interface{}]struct{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+type gopyTuple interface {
+	gopyItems() []interface{}
+}
+
+func sign(n int64) string {
+	return func() string {
+	var gopyTmp1 string
+	if (n > 0) {
+		gopyTmp1 = "pos"
+	} else {
+		if (n < 0) {
+			gopyTmp1 = "neg"
+		} else {
+			gopyTmp1 = "zero"
+		}
+	}
+	return gopyTmp1
+}()
+}
+
+func pick(flag interface{}) interface{} {
+	var x int64
+	if gopyTruthy(flag) {
+		y := int64(10)
+		x = int64((y * 2))
+	} else {
+		x = 5
+	}
+	return x
+}
+
+func main() {
+}
+`
+	if generatedCode != expectedCode {
+		t.Errorf("Generated code is wrong.\nExpected:\n%s\nGot:\n%s", expectedCode, generatedCode)
+	}
+}
+
+func TestConditionalValueErrors(t *testing.T) {
+	input := `
+x = if ready
+    y = 1
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	_, err := New().Generate(program)
+	if err == nil || !strings.Contains(err.Error(), "должна заканчиваться выражением") {
+		t.Errorf("expected error about if branch value, got %v", err)
+	}
+}
+
+func TestMatchGeneration(t *testing.T) {
+	input := `
+class Point
+    x: int = 0
+    y: int = 0
+def command(cmd: str) -> str
+    match cmd
+        case "start" | "run"
+            return "go"
+        case other
+            return other
+def where(p)
+    match p
+        case Point(0, 0)
+            return "origin"
+        case Point(x=px) if px > 0
+            return "right"
+        case _
+            return "elsewhere"
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\tswitch cmd {\n\tcase \"start\", \"run\":\n\t\treturn \"go\"\n\tdefault:\n\t\tother := cmd\n\t\treturn other\n\t}\n",
+		"\tcase func() bool { _, ok := p.(*Point); return ok }() && p.(*Point).x == 0 && p.(*Point).y == 0:\n\t\treturn \"origin\"\n",
+		"\tcase func() bool { _, ok := p.(*Point); return ok }() && (p.(*Point).x > 0):\n\t\treturn \"right\"\n",
+		"\tdefault:\n\t\treturn \"elsewhere\"\n\t}\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestMatchTypeSwitchGeneration(t *testing.T) {
+	input := `
+interface Shape
+    def area() -> int
+class Square
+    side: int = 2
+    def area(self) -> int
+        return self.side * self.side
+class Circle
+    r: int = 1
+    def area(self) -> int
+        return 3 * self.r * self.r
+def describe(s: Shape) -> int
+    match s
+        case Square(side=n)
+            return n
+    return 0
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := "\tswitch gopyTmp1 := s.(type) {\n\tcase *Square:\n\t\tn := gopyTmp1.side\n\t\treturn int64(n)\n\t}\n"
+	if !strings.Contains(generatedCode, expected) {
+		t.Errorf("generated code does not contain %q.\nGot:\n%s", expected, generatedCode)
+	}
+	warnings := gen.Warnings()
+	if len(warnings) != 1 || warnings[0] != "строка 13: match по интерфейсу Shape не обрабатывает классы: Circle" {
+		t.Errorf("wrong exhaustiveness warnings: %q", warnings)
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1\nmatch x\n    case _\n        print(1)\n    case 2\n        print(2)\n", "строка 3: образец _ делает следующие ветки case недостижимыми"},
+		{"x = 1\nmatch x\n    case \"one\"\n        print(1)\n", "образец one никогда не совпадёт со значением типа int"},
+		{"x = 1\nmatch x\n    case 1 | 1\n        print(1)\n", "строка 3: значение 1 уже проверяется в другой ветке case"},
+		{"def f(v)\n    match v\n        case Ghost()\n            return 1\n    return 0\n", "неизвестный класс в образце: Ghost"},
+		{"def f(v)\n    match v\n        case [a, a]\n            return a\n    return 0\n", "имя a связано в образце несколько раз"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestTryGeneration(t *testing.T) {
+	input := `
+def first(xs: list) -> int
+    try
+        return xs[0]
+    except IndexError as e
+        print(e)
+    finally
+        print("done")
+    return -1
+d = {"a": 1}
+try
+    v = d["b"]
+except KeyError, ValueError
+    v = 0
+n = d["c"] or else 0
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\t\"errors\"\n",
+		// Неудачное утверждение типа перехватывается как TypeError
+		"\tif err, ok := recovered.(*runtime.TypeAssertionError); ok {\n\t\treturn &gopyError{kind: gopyTypeError, message: err.Error(), cause: err}\n\t}\n",
+		"\tvar gopyResult1 int64\n\tgopyReturned1 := false\n\tfunc() {\n\t\tdefer func() {\n\t\t\tfmt.Println(\"done\")\n\t\t}()\n",
+		"\t\t\t\tgopyErr := gopyCatch(gopyRecovered)\n\t\t\t\tswitch {\n\t\t\t\tcase errors.Is(gopyErr, gopyIndexError):\n\t\t\t\t\te := gopyErr\n\t\t\t\t\tfmt.Println(gopyStr(e))\n\t\t\t\tdefault:\n\t\t\t\t\tpanic(gopyRecovered)\n\t\t\t\t}\n",
+		"\t\tgopyResult1 = gopyIndex(xs, 0, 4).(int64)\n\t\tgopyReturned1 = true\n\t\treturn\n\t}()\n\tif gopyReturned1 {\n\t\treturn gopyResult1\n\t}\n\treturn (-1)\n",
+		"\tvar v interface{}\n\tfunc() {\n",
+		"\t\t\t\tcase errors.Is(gopyErr, gopyKeyError) || errors.Is(gopyErr, gopyValueError):\n\t\t\t\t\tv = int64(0)\n",
+		"\t\tv = gopyKey(d, \"b\", 12)\n\t}()\n",
+		"\tn := func() (gopyValue interface{}) {\n\tdefer func() {\n\t\tif recover() != nil {\n\t\t\tgopyValue = int64(0)\n\t\t}\n\t}()\n\treturn gopyKey(d, \"c\", 15)\n}()\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestTryErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try\n    f()\nexcept\n    g()\nexcept ValueError\n    h()\n", "строка 3: except без вида ошибки должен быть последним"},
+		{"try\n    f()\nexcept Oops\n    g()\n", "неизвестный вид ошибки в except: Oops"},
+		{"try\n    return 1\nfinally\n    g()\n", "строка 1: return вне функции"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestRaiseGeneration(t *testing.T) {
+	input := `
+class ParseError(ValueError)
+    pos: int = 0
+def parse(s: str) -> int
+    if s == ""
+        raise ParseError("empty", pos=1)
+    raise ValueError
+def safe(s: str) -> int
+    try
+        return parse(s)
+    except ParseError as e
+        return e.pos
+    except Error
+        raise
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	gen.SetSourceFile("parse.gopy")
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"const gopySourceFile = \"parse.gopy\"\n",
+		"var gopyKindParseError = &gopyErrorKind{name: \"ParseError\", parent: gopyValueError}\n",
+		"type ParseError struct{gopyError; pos int64}\n",
+		"\treturn &ParseError{gopyError: gopyError{kind: gopyKindParseError}, pos: 0}\n",
+		"\tpanic(gopyRaise(func() *ParseError { gopyObj := NewParseError(); gopyObj.message = \"empty\"; gopyObj.pos = 1; return gopyObj }(), 6))\n",
+		"\tpanic(gopyRaise(gopyErrorf(gopyValueError, 0, \"\"), 7))\n}\n",
+		"\t\t\t\tcase errors.Is(gopyErr, gopyKindParseError):\n\t\t\t\t\tvar e *ParseError\n\t\t\t\t\terrors.As(gopyErr, &e)\n",
+		"\t\t\t\tcase errors.Is(gopyErr, gopyBaseError):\n\t\t\t\t\tpanic(gopyRecovered)\n",
+		"func main() {\n\tdefer gopyReport()\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestRaiseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"raise\n", "строка 1: raise без ошибки допустим только внутри except"},
+		{"raise \"oops\"\n", "строка 1: raise ожидает ошибку, получено str"},
+		{"class Point\n    x: int = 0\nraise Point()\n", "строка 3: raise ожидает ошибку, получено Point"},
+		{"class Oops(Point)\n    x: int = 0\n", "класс Oops может наследовать только встроенный вид ошибки (AssertionError, Error, IndexError, KeyError, OSError, TypeError, ValueError), а не Point"},
+		{"x = ValueError(1, 2)\n", "ValueError принимает не более одного аргумента, получено 2"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestGoroutineGeneration(t *testing.T) {
+	input := `
+def worker(jobs: chan, results: chan)
+    for j in jobs
+        results <- j * 2
+    close(results)
+jobs = chan(3)
+results = chan()
+n = 2
+go worker(jobs, results)
+jobs <- n
+for v in results
+    print(v)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\t\"sync\"\n",
+		"var gopyTasks sync.WaitGroup\n",
+		"func worker(jobs chan interface{}, results chan interface{}) interface{} {\n\tfor j := range jobs {\n\tresults <- int64(((j.(int64)) * 2))\n\t}\n\tclose(results)\n",
+		"\tjobs := make(chan interface{}, int64(3))\n\tresults := make(chan interface{})\n",
+		"\tgopyTasks.Add(1)\n\tgopyTmp1 := jobs\n\tgopyTmp2 := results\n\tgo func() {\n\t\tdefer gopyTasks.Done()\n\t\tdefer gopyReport()\n\t\tworker(gopyTmp1, gopyTmp2)\n\t}()\n",
+		"\tjobs <- int64(n)\n",
+		"\tfor v := range results {\n",
+		"\tgopyTasks.Wait()\n}\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestSelectGeneration(t *testing.T) {
+	input := `
+def poll(inbox: chan, done: chan) -> str
+    last = ""
+    select
+        case msg = <-inbox
+            return msg
+        case last = <-done
+            return last
+        case <-done
+            return "done"
+        default
+            return "idle"
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := "\tselect {\n" +
+		"\tcase msg := <-inbox:\n\t\treturn msg.(string)\n" +
+		"\tcase gopyTmp1 := <-done:\n\t\tlast = gopyTmp1.(string)\n\t\treturn last\n" +
+		"\tcase <-done:\n\t\treturn \"done\"\n" +
+		"\tdefault:\n\t\treturn \"idle\"\n" +
+		"\t}\n"
+	if !strings.Contains(generatedCode, expected) {
+		t.Errorf("generated code does not contain %q.\nGot:\n%s", expected, generatedCode)
+	}
+}
+
+func TestConcurrencyErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1\nx <- 2\n", "x не является каналом: тип int"},
+		{"ch = chan(\"big\")\n", "chan: аргумент 1 должен иметь тип int, получено str"},
+		{"ch = chan()\nselect\n    default\n        print(1)\n    default\n        print(2)\n", "строка 5: в select может быть только одна ветка default"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestParallelGeneration(t *testing.T) {
+	input := `
+def square(x: int) -> int
+    return x * x
+def bump(lock: Lock, n: int) -> int
+    with lock
+        return n + 1
+lock = Lock()
+squares = parallel_map(square, [1, 2, 3], workers=4)
+doubled = parallel_map(lambda x: x * 2, squares)
+with lock
+    print(doubled)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\t\"sync\"\n",
+		"func gopyParallelMap(fn interface{}, items []interface{}, workers int64, line int) *gopyList {\n",
+		"func bump(lock *sync.Mutex, n int64) int64 {\n\tvar gopyResult1 int64\n\tfunc() {\n\t\tlock.Lock()\n\t\tdefer lock.Unlock()\n\t\tgopyResult1 = (n + 1)\n\t\treturn\n\t}()\n\treturn gopyResult1\n}\n",
+		"\tlock := &sync.Mutex{}\n",
+		"\tsquares := gopyParallelMap(square, gopyNewList(int64(1), int64(2), int64(3)).items, int64(4), 8)\n",
+		"gopyParallelMap(func(x interface{}) interface{} { return ((x.(int64)) * 2) }, squares.items, 8, 9)",
+		"\tfunc() {\n\t\tlock.Lock()\n\t\tdefer lock.Unlock()\n\t\tfmt.Println(gopyStr(doubled))\n\t}()\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestParallelErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1\nwith x\n    print(x)\n", "строка 2: with ожидает Lock, файл или объект с методами enter и exit, получено int"},
+		{"def f(x: int) -> int\n    return x\nys = parallel_map(f, \"abc\")\n", "parallel_map: аргумент 2 должен иметь тип list, получено str"},
+		{"def f(x: int) -> int\n    return x\nys = parallel_map(f, [1], threads=2)\n", "parallel_map: неизвестный аргумент threads"},
+		{"lock = Lock(1)\n", "Lock ожидает 0 аргументов, получено 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestWithGeneration(t *testing.T) {
+	input := `
+class Timer
+    name: str
+    def enter(self) -> str
+        return self.name
+    def exit(self)
+        print("stop")
+def load(path: str) -> str
+    with open(path) as f
+        return f.read()
+with Timer("job") as label
+    defer print("done")
+    print(label)
+with open("out.txt", "w") as out
+    out.write(load("in.txt"))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"func gopyOpen(path string, mode string, line int) *os.File {\n",
+		"func load(path string) string {\n\tvar gopyResult1 string\n\tfunc() {\n\t\tf := gopyOpen(path, \"r\", 9)\n\t\tdefer f.Close()\n\t\tgopyResult1 = gopyRead(f, 10)\n\t\treturn\n\t}()\n\treturn gopyResult1\n}\n",
+		"\tfunc() {\n\t\tgopyTmp2 := func() *Timer { gopyObj := NewTimer(); gopyObj.name = \"job\"; return gopyObj }()\n",
+		"\t\tlabel := gopyTmp2.enter()\n\t\tdefer gopyTmp2.exit()\n\t\tdefer fmt.Println(\"done\")\n\t\tfmt.Println(label)\n\t}()\n",
+		"\t\tout := gopyOpen(\"out.txt\", \"w\", 14)\n\t\tdefer out.Close()\n\t\tgopyWrite(out, load(\"in.txt\"), 15)\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestWithErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"class Box\n    size: int\nwith Box()\n    print(1)\n", "класс Box нельзя использовать в with: нет метода enter"},
+		{"class Box\n    size: int\n    def enter(self, n)\n        print(n)\n    def exit(self)\n        print(0)\nwith Box()\n    print(1)\n", "метод Box.enter не должен принимать аргументов"},
+		{"f = open(\"a.txt\", \"rw\")\n", "неизвестный режим открытия файла \"rw\""},
+		{"with open(\"a.txt\") as f\n    f.seek(0)\n", "у файла нет метода seek"},
+		{"with open(\"a.txt\") as f\n    f.write(1)\n", "метод файла write принимает строку, получено int"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestAssertGeneration(t *testing.T) {
+	input := `
+def first(xs: list, limit: int) -> int
+    assert (limit > 0) and (xs[0] < limit), "too big"
+    return xs[0]
+print(first([1], 5))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"func gopyAssert(line int, source string, message interface{}, operands ...interface{}) error {\n",
+		"const gopyAssertions = true\n",
+		"\tif gopyAssertions {\n\t\tgopyTmp1 := limit\n\t\tif !((gopyTmp1 > 0)) {\n\t\t\tpanic(gopyAssert(3, \"limit > 0\", \"too big\", \"limit\", gopyTmp1))\n\t\t}\n",
+		"\t\tgopyTmp2 := gopyIndex(xs, 0, 3)\n\t\tgopyTmp3 := limit\n\t\tif !(((gopyTmp2.(int64)) < gopyTmp3)) {\n\t\t\tpanic(gopyAssert(3, \"xs[0] < limit\", \"too big\", \"xs[0]\", gopyTmp2, \"limit\", gopyTmp3))\n\t\t}\n\t}\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+
+	gen := New()
+	gen.SetRelease(true)
+	generatedCode, err = gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	if !strings.Contains(generatedCode, "const gopyAssertions = false\n") {
+		t.Errorf("release build does not disable assertions.\nGot:\n%s", generatedCode)
+	}
+}
+
+func TestBuiltinGeneration(t *testing.T) {
+	input := `
+def longest(words: list) -> int
+    return max(len(w) for w in words) if len(words) > 0 else 0
+xs = [3, 1, 2]
+name = input("name: ")
+print(len(xs), len(name), str(len(xs)) + "!", int("7"), abs(-2))
+print(min(xs), max(1, 5), sum(xs), sorted(xs, reverse=true))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"var gopyStdin = bufio.NewReader(os.Stdin)\n",
+		"\tname := gopyInput(\"name: \", 5)\n",
+		"fmt.Println(int64(len(xs.items)), int64(len([]rune(name))), (fmt.Sprint(int64(len(xs.items))) + \"!\"), gopyToInt(\"7\", 6), gopyAbs(int64((-2))))",
+		"fmt.Println(gopyStr(gopyExtreme(\"min\", xs.items, false, 7)), func(values ...int64) int64 { m := values[0]; for _, v := range values[1:] { if v > m { m = v } }; return m }(int64(1), int64(5)), gopySum(xs.items, 7), gopyStr(gopySorted(xs.items, nil, true, 7)))",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"print(len())\n", "len ожидает 1 аргументов, получено 0"},
+		{"print(len(1))\n", "len: аргумент 1 должен иметь тип str или list или tuple или dict или set или chan, получено int"},
+		{"print(int(\"1\", \"2\"))\n", "int ожидает от 0 до 1 аргументов, получено 2"},
+		{"print(max())\n", "max ожидает не менее 1 аргументов, получено 0"},
+		{"print(sorted([1], reverse=1))\n", "sorted: аргумент reverse должен иметь тип bool, получено int"},
+		{"print(sorted([1], cmp=1))\n", "sorted: неизвестный аргумент cmp"},
+		{"print(1, sep=1)\n", "print: аргумент sep должен иметь тип str, получено int"},
+		{"def len(x: int) -> int\n    return x\nprint(len(1, 2))\n", "len ожидает 1 аргументов, получено 2"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestPrintGeneration(t *testing.T) {
+	input := `
+xs = [1, "a", true]
+n = 2
+print xs, n, "!"
+print("a", n, sep=", ", end="")
+print
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"func gopyStr(value interface{}) string {\n",
+		"\tfmt.Println(gopyStr(xs), n, \"!\")\n",
+		"\tfmt.Print(strings.Join([]string{\"a\", gopyStr(n)}, \", \") + \"\")\n",
+		"\tfmt.Println()\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestStringMethodGeneration(t *testing.T) {
+	input := `
+def shout(s: str) -> str
+    return s.strip().upper() + "!"
+line = "a b  c"
+words = line.split()
+print(", ".join(words), line.replace(" ", "_", 1), line.find("b"), line.startswith("a"))
+print("{} = {n:>4}".format("x", n=42), [w.lower() for w in words])
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\treturn (strings.ToUpper(strings.TrimSpace(s)) + \"!\")\n",
+		"\twords := gopySplit(line, \"\", true, -1, 5)\n",
+		"fmt.Println(gopyJoin(\", \", words.items, 6), strings.Replace(line, \" \", \"_\", int(1)), gopyFind(line, \"b\"), gopyStr(strings.HasPrefix(line, \"a\")))",
+		"gopyFormat(\"{} = {n:>4}\", []interface{}{\"x\"}, map[string]interface{}{\"n\": int64(42)}, 7)",
+		"strings.ToLower(w.(string))",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestStringMethodErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"s = \"a\"\nprint(s.reverse())\n", "строка 2: у значения типа str нет метода reverse"},
+		{"print(\"a\".split(\",\", 1, 2))\n", "str.split ожидает от 0 до 2 аргументов, получено 3"},
+		{"print(\"a\".join(1))\n", "str.join: аргумент 1 должен иметь тип list или tuple, получено int"},
+		{"print(\"a\".replace(\"a\", 1))\n", "str.replace: аргумент 2 должен иметь тип str, получено int"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestListGeneration(t *testing.T) {
+	input := `
+def add_item(items: list, x)
+    items.append(x)
+xs = [3, 1]
+add_item(xs, 2)
+xs.insert(0, 5)
+last = xs.pop()
+xs.sort(reverse=true)
+print(2 in xs, xs + [0], xs * 2, xs == [5, 3])
+for x in xs
+    print(x)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"type gopyList struct {\n\titems []interface{}\n}\n",
+		"func add_item(items *gopyList, x interface{}) interface{} {\n\titems.append(x)\n",
+		"\txs := gopyNewList(int64(3), int64(1))\n",
+		"\txs.insert(int64(0), int64(5))\n",
+		"\tlast := xs.pop(-1, 7)\n",
+		"\txs.sort(nil, true, 8)\n",
+		"fmt.Println(gopyStr(gopyContains(xs, int64(2), 9)), gopyStr(gopyConcat(xs, gopyNewList(int64(0)), 9)), gopyStr(gopyRepeat(xs, int64(2), 9)), gopyStr(gopyEqual(xs, gopyNewList(int64(5), int64(3)))))",
+		"\tfor _, x := range xs.items {\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestListMethodErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs = [1]\nxs.push(2)\n", "строка 2: у значения типа list нет метода push"},
+		{"xs = [1]\nxs.append()\n", "list.append ожидает 1 аргументов, получено 0"},
+		{"xs = [1]\nxs.insert(\"a\", 2)\n", "list.insert: аргумент 1 должен иметь тип int, получено str"},
+		{"xs = [1]\nxs.sort(cmp=1)\n", "list.sort: неизвестный аргумент cmp"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestSetAndTupleGeneration(t *testing.T) {
+	input := `
+a = {1, 2}
+b = {2, 3}
+c = a | b
+d = a & b - {9}
+ok = a == b
+flags = 6 ^ 3
+p = (1, "x")
+grid = {(0, 0): "start"}
+n = len(p)
+print(p[1:])
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\ta := gopyNewSet(2, int64(1), int64(2))\n",
+		"\tc := gopyUnion(a, b)\n",
+		"\td := gopyIntersection(a, gopyDifference(b, gopyNewSet(5, int64(9))))\n",
+		"\tok := gopyEqual(a, b)\n",
+		"\tflags := (6 ^ 3)\n",
+		"\tp := gopyTuple(gopyTuple2{int64(1), \"x\"})\n",
+		"\tgrid := map[interface{}]interface{}{gopyTuple(gopyTuple2{int64(0), int64(0)}): \"start\"}\n",
+		"\tn := int64(len(p.gopyItems()))\n",
+		"type gopyTuple2 struct {\n\tv0, v1 interface{}\n}\n\nfunc (t gopyTuple2) gopyItems() []interface{} {\n\treturn []interface{}{t.v0, t.v1}\n}\n",
+		"\tcase 1:\n\t\treturn gopyTuple1{items[0]}\n",
+		// Ошибка называет тип Gopy, а не тип Go
+		"\tcase *gopyList:\n\t\tpanic(gopyErrorf(gopyTypeError, line, \"значение типа list нельзя хешировать\"))\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestSetOperatorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"s = {1}\nx = s | 2\n", "строка 2: оператор | не применим к типам set и int"},
+		{"s = {1}\nx = \"a\" - s\n", "строка 2: оператор - не применим к типам str и set"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestGoImportGeneration(t *testing.T) {
+	input := `
+import strings
+import strconv
+import os
+import math
+import fmt
+parts = strings.Split("a,b", ",")
+s = strings.Join(parts, "-")
+n = strconv.Atoi("42")
+before, after, found = strings.Cut("k=v", "=")
+os.WriteFile("out.txt", s, 420)
+print(strings.Repeat(s, 2), math.MaxInt32)
+fmt.Println(parts, {1}, s)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\t\"strconv\"\n\t\"strings\"\n",
+		"\tparts := gopyFromGo(strings.Split(\"a,b\", \",\")).(*gopyList)\n",
+		"\ts := strings.Join(gopyToGo(parts, reflect.TypeOf([]string(nil)), 8).([]string), \"-\")\n",
+		"\tn := func() int64 { r0, err := strconv.Atoi(\"42\"); gopyCheck(err, 9); return int64(r0) }()\n",
+		"\tbefore, after, found := strings.Cut(\"k=v\", \"=\")\n",
+		"\tgopyCheck(os.WriteFile(\"out.txt\", []byte(s), os.FileMode(int64(420))), 11)\n",
+		"fmt.Println(strings.Repeat(s, int(int64(2))), int64(math.MaxInt32))",
+		// Контейнеры Gopy передаются в параметры interface{} как значения Go
+		"fmt.Println(gopyExport(parts), gopyExport(gopyNewSet(13, int64(1))), s)",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestGoImportErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import nosuchpkg\n", "строка 1: пакет Go nosuchpkg не найден"},
+		{"import strings\nx = strings.Title2(\"a\")\n", "строка 2: в пакете strings нет Title2"},
+		{"import strings\nx = strings.Repeat(\"a\")\n", "строка 2: strings.Repeat ожидает 2 аргументов, получено 1"},
+		{"import time\nd = time.Duration(1, 2)\n", "строка 2: time.Duration ожидает 1 аргументов, получено 2"},
+		{"import strings\nx = strings.Repeat(1, 2)\n", "строка 2: strings.Repeat: аргумент 1: ожидается str, получено int"},
+		{"import fmt\nfmt.Println(sep=\"\")\n", "строка 2: функции Go fmt.Println нельзя передать именованный аргумент"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestGoMethodAndFieldGeneration(t *testing.T) {
+	input := `
+import strings
+import net/url
+import time
+r = strings.NewReader("hello")
+n = r.Len()
+u = url.URL(Scheme="https", Host="example.com")
+u.Path = "/docs"
+print(u.String(), u.Host, n)
+time.Now().UnmarshalText("2024-01-02T03:04:05Z")
+def host(v: url.URL) -> str
+    return v.Host
+print(host(u))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	generatedCode, err := New().Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"\tn := int64(r.Len())\n",
+		"\tu := &url.URL{Scheme: \"https\", Host: \"example.com\"}\n",
+		"\tu.Path = \"/docs\"\n",
+		"fmt.Println(u.String(), u.Host, n)",
+		"gopyCheck(func() *time.Time { v := time.Now(); return &v }().UnmarshalText([]byte(\"2024-01-02T03:04:05Z\")), 10)",
+		// Тип Go в аннотации: указатель *url.URL передаётся как значение
+		"func host(v url.URL) string {\n\treturn v.Host\n}",
+		"fmt.Println(host(*u))",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestGoMethodAndFieldErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import strings\nr = strings.NewReader(\"a\")\nprint(r.s)\n", "строка 3: у значения типа *strings.Reader нет поля или метода s"},
+		{"import net/url\nu = url.URL(Nope=\"a\")\n", "строка 2: у структуры url.URL нет поля Nope"},
+		{"import net/url\nu = url.URL()\nu.Host = 5\n", "строка 3: поле u.Host: ожидается str, получено int"},
+		{"import strings\nr = strings.NewReader(\"a\")\nn = r.Reset(\"b\")\n", "строка 3: r.Reset не возвращает значения"},
+		{"import net/url\ndef host(u: url.URL) -> str\n    return u.Host\nprint(host(\"a\"))\n", "строка 4: host: аргумент 1: ожидается url.URL, получено str"},
+		{"import net/url\ndef host(u)\n    return u.Host\n", "строка 3: тип значения u неизвестен, поэтому у него нельзя взять поле или метод Host: укажите тип в аннотации"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		_, err := New().Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestModuleImportGeneration(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"utils.gopy":      "def greet(name: str) -> str\n    return _prefix() + name\n\ndef _prefix() -> str\n    return \"hi \"\n",
+		"geo/shapes.gopy": "class Point\n    x: int\n    y: int\n\ndef origin() -> Point\n    return Point(x=0, y=0)\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input := `
+import utils
+import geo/shapes
+
+def norm(p: shapes.Point) -> int
+    return p.x + p.y
+
+print(utils.greet("bob"), norm(shapes.origin()))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	g := New()
+	g.SetModuleDir(dir)
+	generatedCode, err := g.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"func utils_greet(name string) string {\n\treturn (utils__prefix() + name)\n}\n",
+		"type geo_shapes_Point struct{x int64; y int64}\n",
+		"func geo_shapes_origin() *geo_shapes_Point {\n",
+		"func norm(p *geo_shapes_Point) int64 {\n",
+		"fmt.Println(utils_greet(\"bob\"), norm(geo_shapes_origin()))",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestModuleImportErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.gopy":      "import b\ndef f() -> int\n    return b.g()\n",
+		"b.gopy":      "import a\ndef g() -> int\n    return 1\n",
+		"script.gopy": "def f() -> int\n    return 1\nprint(f())\n",
+		"util.gopy":   "def _hidden() -> int\n    return 1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import a\nprint(a.f())\n", "a.gopy: b.gopy: строка 1: циклический импорт модулей: a -> b -> a"},
+		{"import script\n", "script.gopy: строка 3: модуль может содержать только объявления def, class, interface и import"},
+		{"import util\nprint(util._hidden())\n", "строка 2: _hidden — внутреннее имя модуля util"},
+		{"import util\nprint(util.nope())\n", "строка 2: в модуле util нет nope"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		g := New()
+		g.SetModuleDir(dir)
+		_, err := g.Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestLibraryGeneration(t *testing.T) {
+	input := `
+class Order
+    customer: str
+    items: list = []
+    def total(self) -> int
+        return len(self.items)
+
+def price_with_tax(price: int, rate: int) -> int
+    return price + price * rate / 100
+
+def normalize(names: list) -> list
+    return names
+
+def log(msg: str)
+    print(msg)
+
+class point
+    x: int
+
+def _internal() -> int
+    return 1
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	g := New()
+	g.SetLibrary("rules")
+	generatedCode, err := g.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"package rules\n",
+		"\tValueError error = gopyValueError\n",
+		"func (self *Order) Customer() string {\n\treturn self.customer\n}\n",
+		"func (self *Order) SetItems(value []interface{}) {\n\tself.items = gopyFromGo(value).(*gopyList)\n}\n",
+		"func (self *Order) Total() (_ int64, err error) {\n\tdefer gopyRecover(&err)\n\treturn self.total(), nil\n}\n",
+		"func PriceWithTax(price int64, rate int64) (_ int64, err error) {\n",
+		"\treturn gopyExport(normalize(gopyFromGo(names).(*gopyList))).([]interface{}), nil\n",
+		"func Log(msg string) (err error) {\n\tdefer gopyRecover(&err)\n\tlog(msg)\n\treturn nil\n}\n",
+		// Конструктор неэкспортированного класса не попадает в API пакета
+		"func gopyNew_point() *point {\n",
+		"func NewPoint() *Point {\n\treturn gopyNew_point()\n}\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+	for _, fragment := range []string{"func main()", "func Internal(", "func Newpoint("} {
+		if strings.Contains(generatedCode, fragment) {
+			t.Errorf("generated code should not contain %q", fragment)
+		}
+	}
+}
+
+func TestLibraryErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"def f() -> int\n    return 1\nprint(f())\n", "строка 3: библиотека может содержать только объявления def, class, interface и import"},
+		{"def Greet() -> int\n    return 1\n", "функция Greet: в библиотеке имена функций пишутся с маленькой буквы"},
+		{"def parse_line() -> int\n    return 1\ndef parseLine() -> int\n    return 2\n", "имена parse_line и parseLine дают в библиотеке одно имя Go ParseLine"},
+		{"class point\n    x: int\ndef new_point() -> int\n    return 1\n", "имена point и new_point дают в библиотеке одно имя Go NewPoint"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		g := New()
+		g.SetLibrary("rules")
+		_, err := g.Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestNameManglingGeneration(t *testing.T) {
+	input := `
+class Item
+    type: str
+    len: int = 0
+    def range(self) -> int
+        return self.len
+
+def func(type: str, chan: int) -> str
+    return type + str(chan)
+
+def len(xs: list) -> int
+    return 42
+
+def check(type: int)
+    assert type > 5
+
+map = {"a": 1}
+fmt = [1, 2, 3]
+type = "kind"
+type_ = "taken"
+gopyList = 7
+print(func(type, len(fmt)), type_, gopyList, map)
+item = Item(type="x")
+print(item.range(), item)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	g := New()
+	generatedCode, err := g.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+	expected := []string{
+		"type Item struct{type__ string `gopy:\"type\"`; len int64}\n",
+		"func (self *Item) range_() int64 {\n",
+		"func func_(type__ string, chan_ int64) string {\n",
+		"func len_(xs *gopyList) int64 {\n",
+		"\tmap_ := map[interface{}]interface{}{\"a\": int64(1)}\n",
+		"\tfmt_ := gopyNewList(int64(1), int64(2), int64(3))\n",
+		"\ttype__ := \"kind\"\n",
+		"\ttype_ := \"taken\"\n",
+		"\tgopyList_ := 7\n",
+		"\tfmt.Println(func_(type__, len_(fmt_)), type_, gopyList_, gopyStr(map_))\n",
+		"gopyObj.type__ = \"x\"",
+		"\tfmt.Println(item.range_(), gopyStr(item))\n",
+		// Сообщение assert называет имена так, как они записаны в Gopy
+		"panic(gopyAssert(15, \"type > 5\", nil, \"type\", gopyTmp1))",
+	}
+	for _, want := range expected {
+		if !strings.Contains(generatedCode, want) {
+			t.Errorf("generated code does not contain %q\ngot:\n%s", want, generatedCode)
+		}
+	}
+}
+
+func TestNameManglingErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"def func(a: int) -> int\n    return a\nprint(func(1, 2))\n", "func ожидает 1 аргументов, получено 2"},
+		{"class type\n    x: int\nt = type(y=1)\n", "у класса type нет поля y"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		g := New()
+		_, err := g.Generate(program)
+		if err == nil {
+			t.Errorf("expected error %q, got none", tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestForRangeGeneration(t *testing.T) {
+	input := `
+xs = [1, 2, 3]
+for i in range(3)
+    print(i)
+for i in range(1, len(xs))
+    print(i)
+for i in range(10, 0, -4)
+    print(i)
+def count(n: int)
+    for j in range(n)
+        print(j)
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+
+	// В main счётчик имеет тип int, внутри функций — int64
+	expected := []string{
+		"\tfor i := 0; i < 3; i++ {\n",
+		"\tfor i := 1; i < int(int64(len(xs.items))); i++ {\n",
+		"\tfor i := 10; ((-4) > 0 && i < 0) || ((-4) < 0 && i > 0); i += (-4) {\n",
+		"\tfor j := int64(0); j < int64(n); j++ {\n",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(generatedCode, fragment) {
+			t.Errorf("Generated code does not contain %q.\nGot:\n%s", fragment, generatedCode)
+		}
+	}
+}
+
+func TestFunctionIntLocalsGeneration(t *testing.T) {
+	input := `
+def add(n: int) -> int
+    total = 0
+    total = total + n
+    for i in range(n)
+        total = total + i
+    xs = [1, 2, 3]
+    for x in xs
+        total = total + x
+    return total
+
+print(add(4))
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	gen := New()
+	generatedCode, err := gen.Generate(program)
+	if err != nil {
+		t.Fatalf("Code generation failed: %s", err)
+	}
+
+	// Целая переменная функции имеет тип int64, как параметры и счётчики range
+	if !strings.Contains(generatedCode, "\ttotal := int64(0)\n") {
+		t.Errorf("Generated code does not declare total as int64.\nGot:\n%s", generatedCode)
+	}
+	checkGoCode(t, generatedCode)
+}
+
+// checkGoCode проверяет типы в сгенерированном коде Go
+func checkGoCode(t *testing.T, code string) {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %s\n%s", err, code)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("main", fset, []*goast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not compile: %s\n%s", err, code)
+	}
+}
+
 func checkParserErrors(t *testing.T, p *parser.Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
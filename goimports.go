@@ -0,0 +1,533 @@
+package generator
+
+import (
+	"fmt"
+	"go/importer"
+	"go/types"
+	"gopy/ast"
+	"strings"
+)
+
+// errorType — встроенный интерфейс error языка Go
+var errorType = types.Universe.Lookup("error").Type()
+
+// generateImport загружает типы пакета Go из GOROOT. Пакет попадает в импорты
+// сгенерированной программы, только когда код его использует
+func (g *Generator) generateImport(stmt *ast.ImportStatement) error {
+	// Модули Gopy загружает loadModules
+	if g.modules[stmt.Path] != nil {
+		return nil
+	}
+	if g.goImporter == nil {
+		g.goImporter = importer.Default()
+	}
+	pkg, err := g.goImporter.Import(stmt.Path)
+	if err != nil {
+		return fmt.Errorf("строка %d: пакет Go %s не найден", stmt.Token.Line, stmt.Path)
+	}
+	g.goPackages[pkg.Name()] = pkg
+	return nil
+}
+
+// goMember возвращает объект импортированного пакета Go, к которому обращается
+// pkg.Name, или поле и метод значения типа Go: reader.Len. ok == false —
+// выражение не относится к Go
+func (g *Generator) goMember(expr ast.Expression) (types.Object, bool, error) {
+	dot, isDot := expr.(*ast.DotExpression)
+	if !isDot {
+		return nil, false, nil
+	}
+	if recv, ok := g.goValueTypes[g.staticType(dot.Left)]; ok {
+		return g.goSelector(dot, recv)
+	}
+	ident, isIdent := dot.Left.(*ast.Identifier)
+	if !isIdent {
+		return nil, false, nil
+	}
+	// Переменная с именем пакета скрывает пакет
+	if _, isVariable := g.lookupVariable(ident.Value); isVariable {
+		return nil, false, nil
+	}
+	pkg := g.goPackages[ident.Value]
+	if pkg == nil {
+		return nil, false, nil
+	}
+	obj := pkg.Scope().Lookup(dot.Right.Value)
+	if obj == nil || !obj.Exported() {
+		return nil, true, fmt.Errorf("строка %d: в пакете %s нет %s", dot.Token.Line, pkg.Path(), dot.Right.Value)
+	}
+	g.imports[pkg.Path()] = true
+	return obj, true, nil
+}
+
+// goNamedType возвращает тип пакета Go, записанный в аннотации: url.URL.
+// Значения этого типа, как и результаты функций Go, получают его имя типом Gopy
+func (g *Generator) goNamedType(typ string) types.Type {
+	pkgName, name, ok := strings.Cut(typ, ".")
+	if !ok || g.goPackages[pkgName] == nil {
+		return nil
+	}
+	obj, isType := g.goPackages[pkgName].Scope().Lookup(name).(*types.TypeName)
+	if !isType || !obj.Exported() {
+		return nil
+	}
+	g.goValueTypes[typ] = obj.Type()
+	return obj.Type()
+}
+
+// goSelector возвращает поле или метод значения типа Go recv. Методы с
+// получателем-указателем доступны и у значений: их адрес берёт goReceiver
+func (g *Generator) goSelector(dot *ast.DotExpression, recv types.Type) (types.Object, bool, error) {
+	obj, _, _ := types.LookupFieldOrMethod(recv, true, nil, dot.Right.Value)
+	if obj == nil || !obj.Exported() {
+		return nil, true, fmt.Errorf("строка %d: у значения типа %s нет поля или метода %s", dot.Token.Line, goTypeName(recv), dot.Right.Value)
+	}
+	return obj, true, nil
+}
+
+// goReceiver генерирует значение типа Go слева от точки. Метод с получателем-указателем
+// у значения, которое нельзя адресовать (результата вызова), вызывается через копию
+func (g *Generator) goReceiver(dot *ast.DotExpression, obj types.Object, inFunction bool) (string, error) {
+	code, err := g.generateExpressionWithCast(dot.Left, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	fn, isMethod := obj.(*types.Func)
+	if !isMethod {
+		return code, nil
+	}
+	recv := g.goValueTypes[g.staticType(dot.Left)]
+	_, pointerMethod := fn.Type().(*types.Signature).Recv().Type().(*types.Pointer)
+	_, pointerValue := recv.(*types.Pointer)
+	if _, addressable := dot.Left.(*ast.Identifier); pointerMethod && !pointerValue && !addressable {
+		return g.addressOf(code, recv), nil
+	}
+	return code, nil
+}
+
+// addressOf возвращает указатель на копию значения, которое нельзя адресовать
+func (g *Generator) addressOf(code string, t types.Type) string {
+	return fmt.Sprintf("func() *%s { v := %s; return &v }()", g.goTypeString(t), code)
+}
+
+// isGoStruct сообщает, что вызов типа-структуры пакета Go создаёт структуру,
+// а не преобразует значение: url.URL(Scheme="https")
+func isGoStruct(call *ast.CallExpression, typeName *types.TypeName) bool {
+	if _, ok := typeName.Type().Underlying().(*types.Struct); !ok {
+		return false
+	}
+	return len(positionalArguments(call)) == 0
+}
+
+// goFunction возвращает сигнатуру функции пакета Go или метода значения типа Go,
+// которую вызывает call
+func (g *Generator) goFunction(fn ast.Expression) *types.Signature {
+	obj, _, _ := g.goMember(fn)
+	if f, ok := obj.(*types.Func); ok {
+		return f.Type().(*types.Signature)
+	}
+	return nil
+}
+
+// goResults возвращает результаты функции Go без последней ошибки и сообщает,
+// возвращает ли функция ошибку
+func goResults(sig *types.Signature) ([]types.Type, bool) {
+	results := []types.Type{}
+	for i := 0; i < sig.Results().Len(); i++ {
+		results = append(results, sig.Results().At(i).Type())
+	}
+	if n := len(results); n > 0 && types.Identical(results[n-1], errorType) {
+		return results[:n-1], true
+	}
+	return results, false
+}
+
+// goSignature описывает функцию Go как сигнатуру Gopy: нужны только типы
+// результатов, аргументы проверяет generateGoCall
+func (g *Generator) goSignature(sig *types.Signature) *signature {
+	results, _ := goResults(sig)
+	s := &signature{results: []string{}}
+	for _, t := range results {
+		s.results = append(s.results, g.gopyTypeOf(t))
+	}
+	if len(s.results) == 0 {
+		s.results = []string{""}
+	}
+	return s
+}
+
+// goTypeString записывает тип Go так, как он выглядит в сгенерированном коде,
+// и добавляет в импорты пакеты, которые в нём упоминаются
+func (g *Generator) goTypeString(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		g.imports[pkg.Path()] = true
+		return pkg.Name()
+	})
+}
+
+// goTypeName записывает тип Go для сообщений и типов Gopy, не добавляя пакеты
+// в импорты: тип попадает в код только через goTypeString
+func goTypeName(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		return pkg.Name()
+	})
+}
+
+// gopyTypeOf возвращает тип Gopy для значения типа Go. Числа, строки и логические
+// значения становятся int, float, str и bool, срезы и map — списками и словарями.
+// Остальные значения сохраняют тип Go, и он служит их типом Gopy
+func (g *Generator) gopyTypeOf(t types.Type) string {
+	if _, named := t.(*types.Named); !named {
+		switch u := t.(type) {
+		case *types.Basic:
+			if typ := basicType(u); typ != "" {
+				return typ
+			}
+		case *types.Slice:
+			if isBytes(u) {
+				return "str"
+			}
+			return "list"
+		case *types.Map:
+			return "dict"
+		}
+	}
+	name := goTypeName(t)
+	g.goValueTypes[name] = t
+	return name
+}
+
+// basicType возвращает тип Gopy для числового, строкового или логического типа Go
+func basicType(t *types.Basic) string {
+	switch info := t.Info(); {
+	case info&types.IsInteger != 0:
+		return "int"
+	case info&types.IsFloat != 0:
+		return "float"
+	case info&types.IsString != 0:
+		return "str"
+	case info&types.IsBoolean != 0:
+		return "bool"
+	}
+	return ""
+}
+
+func isBytes(t *types.Slice) bool {
+	elem, ok := t.Elem().(*types.Basic)
+	return ok && elem.Kind() == types.Byte
+}
+
+// fromGo приводит значение типа Go к представлению Gopy: целые — к int64,
+// []byte — к строке, срезы и map — к спискам и словарям
+func (g *Generator) fromGo(code string, t types.Type) string {
+	if _, named := t.(*types.Named); named {
+		return code
+	}
+	switch u := t.(type) {
+	case *types.Basic:
+		switch typ := basicType(u); {
+		case typ == "int" && u.Kind() != types.Int64:
+			return fmt.Sprintf("int64(%s)", code)
+		case typ == "float" && u.Kind() != types.Float64:
+			return fmt.Sprintf("float64(%s)", code)
+		}
+	case *types.Slice:
+		if isBytes(u) {
+			return fmt.Sprintf("string(%s)", code)
+		}
+		g.useHelper("gopyFromGo")
+		return fmt.Sprintf("gopyFromGo(%s).(*gopyList)", code)
+	case *types.Map:
+		g.useHelper("gopyFromGo")
+		return fmt.Sprintf("gopyFromGo(%s).(map[interface{}]interface{})", code)
+	}
+	return code
+}
+
+// toGo приводит аргумент Gopy к типу параметра функции Go. Несовместимый тип,
+// известный при трансляции, — ошибка
+func (g *Generator) toGo(code string, arg ast.Expression, t types.Type, line int) (string, error) {
+	argType := g.staticType(arg)
+	goTyp := goTypeName(t)
+	if goValue, ok := g.goValueTypes[argType]; ok {
+		pointer, isPointer := goValue.(*types.Pointer)
+		switch {
+		case types.AssignableTo(goValue, t):
+			return code, nil
+		case types.AssignableTo(types.NewPointer(goValue), t):
+			// Значение передаётся в параметр-указатель: &v
+			if _, addressable := arg.(*ast.Identifier); addressable {
+				return "&" + code, nil
+			}
+			return g.addressOf(code, goValue), nil
+		case isPointer && types.AssignableTo(pointer.Elem(), t):
+			return "*" + code, nil
+		}
+		return "", fmt.Errorf("нельзя передать значение типа %s как %s", argType, goTyp)
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		want := basicType(u)
+		if want == "" {
+			break
+		}
+		if argType != "" && argType != want && !(want == "float" && argType == "int") {
+			return "", fmt.Errorf("ожидается %s, получено %s", want, argType)
+		}
+		from := goTypes[want]
+		switch {
+		case want == "int" || argType == "int":
+			// Целые main имеют тип int, поэтому сначала приводим их к int64
+			code, from = g.intValue(code, arg), "int64"
+		case argType == "":
+			code = g.coerce(code, arg, want)
+		}
+		if goTyp == from {
+			return code, nil
+		}
+		return fmt.Sprintf("%s(%s)", g.goTypeString(t), code), nil
+	case *types.Interface:
+		if u.Empty() {
+			switch argType {
+			case "", "list", "tuple", "dict", "set":
+				// fmt.Println([1, 2]) выводит [1 2], а не внутреннее устройство списка
+				g.useHelper("gopyExport")
+				return fmt.Sprintf("gopyExport(%s)", g.boxValue(code, arg)), nil
+			}
+			return g.boxValue(code, arg), nil
+		}
+		if argType == "" {
+			return fmt.Sprintf("%s.(%s)", code, g.goTypeString(t)), nil
+		}
+	case *types.Slice:
+		if isBytes(u) && argType == "str" {
+			return fmt.Sprintf("[]byte(%s)", code), nil
+		}
+		if argType == "" || argType == "list" || argType == "tuple" {
+			g.useHelper("gopyToGo")
+			goTyp = g.goTypeString(t)
+			return fmt.Sprintf("gopyToGo(%s, reflect.TypeOf(%s(nil)), %d).(%s)", code, goTyp, line, goTyp), nil
+		}
+	case *types.Map:
+		if argType == "" || argType == "dict" {
+			g.useHelper("gopyToGo")
+			goTyp = g.goTypeString(t)
+			return fmt.Sprintf("gopyToGo(%s, reflect.TypeOf(%s(nil)), %d).(%s)", code, goTyp, line, goTyp), nil
+		}
+	default:
+		if argType == "" {
+			return fmt.Sprintf("%s.(%s)", code, g.goTypeString(t)), nil
+		}
+	}
+	return "", fmt.Errorf("ожидается %s, получено %s", goTyp, argType)
+}
+
+// generateGoCall генерирует вызов функции пакета Go или преобразование к типу
+// пакета: проверяет число аргументов, приводит их к типам параметров и
+// результаты — к значениям Gopy. Последний результат error превращается в OSError.
+// statement — результат вызова не используется
+func (g *Generator) generateGoCall(call *ast.CallExpression, inFunction bool, statement bool) (string, error) {
+	obj, _, err := g.goMember(call.Function)
+	if err != nil {
+		return "", err
+	}
+	name := call.Function.String()
+	line := call.Token.Line
+	if typeName, ok := obj.(*types.TypeName); ok && isGoStruct(call, typeName) {
+		return g.generateGoStruct(call, typeName, inFunction)
+	}
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.KeywordArgument); ok {
+			return "", fmt.Errorf("строка %d: функции Go %s нельзя передать именованный аргумент", line, name)
+		}
+	}
+
+	// time.Duration(n) — преобразование к типу пакета
+	if typeName, ok := obj.(*types.TypeName); ok {
+		if len(call.Arguments) != 1 {
+			return "", fmt.Errorf("строка %d: %s ожидает 1 аргументов, получено %d", line, name, len(call.Arguments))
+		}
+		code, err := g.generateExpressionWithCast(call.Arguments[0], inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		code, err = g.toGo(code, call.Arguments[0], typeName.Type(), line)
+		if err != nil {
+			return "", fmt.Errorf("строка %d: %s: %s", line, name, err)
+		}
+		if goTyp := g.goTypeString(typeName.Type()); !strings.HasPrefix(code, goTyp+"(") {
+			code = fmt.Sprintf("%s(%s)", goTyp, code)
+		}
+		return code, nil
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return "", fmt.Errorf("строка %d: %s не является функцией", line, name)
+	}
+	sig := fn.Type().(*types.Signature)
+	callee := name
+	if sig.Recv() != nil {
+		dot := call.Function.(*ast.DotExpression)
+		recv, err := g.goReceiver(dot, fn, inFunction)
+		if err != nil {
+			return "", err
+		}
+		callee = recv + "." + dot.Right.Value
+	}
+
+	params := sig.Params()
+	min, max := params.Len(), params.Len()
+	if sig.Variadic() {
+		min, max = params.Len()-1, -1
+	}
+	if len(call.Arguments) < min || (max >= 0 && len(call.Arguments) > max) {
+		return "", fmt.Errorf("строка %d: %s ожидает %s аргументов, получено %d", line, name, arityText(min, max), len(call.Arguments))
+	}
+	args := []string{}
+	for i, arg := range call.Arguments {
+		code, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		var param types.Type
+		if sig.Variadic() && i >= params.Len()-1 {
+			param = params.At(params.Len() - 1).Type().(*types.Slice).Elem()
+		} else {
+			param = params.At(i).Type()
+		}
+		code, err = g.toGo(code, arg, param, line)
+		if err != nil {
+			return "", fmt.Errorf("строка %d: %s: аргумент %d: %s", line, name, i+1, err)
+		}
+		args = append(args, code)
+	}
+	code := fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", "))
+
+	results, hasError := goResults(sig)
+	if !statement && len(results) == 0 {
+		return "", fmt.Errorf("строка %d: %s не возвращает значения", line, name)
+	}
+	if hasError {
+		g.useHelper("gopyCheck")
+	}
+	switch {
+	case hasError && len(results) == 0:
+		return fmt.Sprintf("gopyCheck(%s, %d)", code, line), nil
+	case statement && hasError:
+		// Результаты не нужны, но ошибку нужно проверить
+		blanks := strings.Repeat("_, ", len(results))
+		return fmt.Sprintf("func() { %serr := %s; gopyCheck(err, %d) }()", blanks, code, line), nil
+	case statement:
+		return code, nil
+	case len(results) == 1 && !hasError:
+		return g.fromGo(code, results[0]), nil
+	}
+
+	// Несколько результатов или результат с ошибкой разбираются в замыкании
+	names, values, resultTypes := []string{}, []string{}, []string{}
+	converted := false
+	for i, t := range results {
+		name := fmt.Sprintf("r%d", i)
+		names = append(names, name)
+		value := g.fromGo(name, t)
+		converted = converted || value != name
+		values = append(values, value)
+		goTyp, err := g.goType(g.gopyTypeOf(t))
+		if err != nil {
+			return "", err
+		}
+		resultTypes = append(resultTypes, goTyp)
+	}
+	if !hasError && !converted {
+		return code, nil
+	}
+	check := ""
+	if hasError {
+		names = append(names, "err")
+		check = fmt.Sprintf("gopyCheck(err, %d); ", line)
+	}
+	resultList := strings.Join(resultTypes, ", ")
+	if len(resultTypes) > 1 {
+		resultList = "(" + resultList + ")"
+	}
+	return fmt.Sprintf("func() %s { %s := %s; %sreturn %s }()",
+		resultList, strings.Join(names, ", "), code, check, strings.Join(values, ", ")), nil
+}
+
+// generateGoStruct создаёт структуру пакета Go из именованных аргументов:
+// url.URL(Scheme="https") — &url.URL{Scheme: "https"}. Как и объект класса,
+// структура хранится по указателю
+func (g *Generator) generateGoStruct(call *ast.CallExpression, typeName *types.TypeName, inFunction bool) (string, error) {
+	name := call.Function.String()
+	line := call.Token.Line
+	fields := []string{}
+	for _, arg := range call.Arguments {
+		kw := arg.(*ast.KeywordArgument)
+		obj, index, _ := types.LookupFieldOrMethod(typeName.Type(), false, nil, kw.Name.Value)
+		field, isField := obj.(*types.Var)
+		// Поля встроенных структур в литерале Go указать нельзя
+		if !isField || !field.Exported() || len(index) != 1 {
+			return "", fmt.Errorf("строка %d: у структуры %s нет поля %s", line, name, kw.Name.Value)
+		}
+		code, err := g.generateExpressionWithCast(kw.Value, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		code, err = g.toGo(code, kw.Value, field.Type(), line)
+		if err != nil {
+			return "", fmt.Errorf("строка %d: %s: поле %s: %s", line, name, kw.Name.Value, err)
+		}
+		fields = append(fields, fmt.Sprintf("%s: %s", kw.Name.Value, code))
+	}
+	return fmt.Sprintf("&%s{%s}", g.goTypeString(typeName.Type()), strings.Join(fields, ", ")), nil
+}
+
+// generateGoFieldValue приводит значение, присваиваемое полю структуры Go,
+// к типу поля; поля классов приводятся через coerce
+func (g *Generator) generateGoFieldValue(dot *ast.DotExpression, value ast.Expression, code string) (string, error) {
+	obj, isGo, err := g.goMember(dot)
+	if !isGo {
+		return g.coerce(code, value, g.staticType(dot)), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	field, isField := obj.(*types.Var)
+	if !isField || !field.IsField() {
+		return "", fmt.Errorf("строка %d: %s нельзя присвоить значение", dot.Token.Line, dot.String())
+	}
+	code, err = g.toGo(code, value, field.Type(), dot.Token.Line)
+	if err != nil {
+		return "", fmt.Errorf("строка %d: поле %s: %s", dot.Token.Line, dot.String(), err)
+	}
+	return code, nil
+}
+
+// generateGoValue генерирует обращение к константе или переменной пакета Go: math.Pi,
+// а также к полю или методу значения типа Go: resp.StatusCode
+func (g *Generator) generateGoValue(dot *ast.DotExpression, obj types.Object, inFunction bool) (string, error) {
+	code := dot.String()
+	if _, isGo := g.goValueTypes[g.staticType(dot.Left)]; isGo {
+		recv, err := g.goReceiver(dot, obj, inFunction)
+		if err != nil {
+			return "", err
+		}
+		code = recv + "." + dot.Right.Value
+	}
+	switch obj := obj.(type) {
+	case *types.Const:
+		// Нетипизированная константа получает тип Gopy: int64(math.MaxInt32)
+		if basic, ok := obj.Type().(*types.Basic); ok && basic.Info()&types.IsUntyped != 0 {
+			if goTyp := goTypes[basicType(basic)]; goTyp != "" && goTyp != "string" && goTyp != "bool" {
+				return fmt.Sprintf("%s(%s)", goTyp, code), nil
+			}
+			return code, nil
+		}
+		return g.fromGo(code, obj.Type()), nil
+	case *types.Var:
+		return g.fromGo(code, obj.Type()), nil
+	case *types.Func:
+		return code, nil
+	}
+	return "", fmt.Errorf("строка %d: %s нельзя использовать как значение", dot.Token.Line, code)
+}
@@ -0,0 +1,249 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// signature описывает параметры и результат функции, метода класса или метода интерфейса
+type signature struct {
+	parameters []*ast.Identifier
+	types      map[string]*ast.Identifier
+	results    []string // типы Gopy результатов; пустая строка — неизвестный тип
+}
+
+// paramType возвращает тип Gopy i-го параметра или пустую строку, если он не указан
+func (s *signature) paramType(i int) string {
+	return typeName(s.types[s.parameters[i].Value])
+}
+
+// String форматирует сигнатуру для сообщений об ошибках: (a: int, b) -> str
+func (s *signature) String() string {
+	params := []string{}
+	for i, p := range s.parameters {
+		if typ := s.paramType(i); typ != "" {
+			params = append(params, p.Value+": "+typ)
+		} else {
+			params = append(params, p.Value)
+		}
+	}
+	out := "(" + strings.Join(params, ", ") + ")"
+	switch {
+	case len(s.results) > 1:
+		out += " -> (" + strings.Join(s.results, ", ") + ")"
+	case len(s.results) == 1 && s.results[0] != "":
+		out += " -> " + s.results[0]
+	}
+	return out
+}
+
+// matches сравнивает сигнатуры по числу параметров и их типам; имена не важны
+func (s *signature) matches(other *signature) bool {
+	if len(s.parameters) != len(other.parameters) {
+		return false
+	}
+	for i := range s.parameters {
+		if s.paramType(i) != other.paramType(i) {
+			return false
+		}
+	}
+	if len(s.results) != len(other.results) {
+		return false
+	}
+	for i := range s.results {
+		if s.results[i] != other.results[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// generateInterface генерирует Go-интерфейс из объявления interface
+func (g *Generator) generateInterface(iface *ast.InterfaceStatement) error {
+	name := iface.Name.Value
+	if g.isClass(name) || g.declaredInterfaces[name] != nil {
+		return fmt.Errorf("тип %s уже объявлен", name)
+	}
+	g.declaredInterfaces[name] = iface
+
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("type %s interface {\n", name))
+	seen := make(map[string]bool)
+	for _, m := range iface.Methods {
+		if seen[m.Name.Value] {
+			return fmt.Errorf("метод %s уже объявлен в интерфейсе %s", m.Name.Value, name)
+		}
+		seen[m.Name.Value] = true
+
+		sig := interfaceMethodSignature(m)
+		params := []string{}
+		for i, p := range sig.parameters {
+			typ, err := g.goType(sig.paramType(i))
+			if err != nil {
+				return err
+			}
+			params = append(params, fmt.Sprintf("%s %s", p.Value, typ))
+		}
+		result, err := g.goResult(sig.results)
+		if err != nil {
+			return err
+		}
+		out.WriteString(fmt.Sprintf("\t%s(%s) %s\n", m.Name.Value, strings.Join(params, ", "), result))
+	}
+	out.WriteString("}\n\n")
+
+	g.functions.WriteString(out.String())
+	return nil
+}
+
+// checkImplements проверяет, что класс структурно удовлетворяет интерфейсу:
+// у него есть все методы интерфейса с тем же числом и типами параметров
+func (g *Generator) checkImplements(class *ast.ClassStatement, iface *ast.InterfaceStatement) error {
+	for _, want := range iface.Methods {
+		method := findMethod(class, want.Name.Value)
+		if method == nil {
+			return fmt.Errorf("класс %s не реализует интерфейс %s: нет метода %s",
+				class.Name.Value, iface.Name.Value, want.Name.Value)
+		}
+		have, expected := classMethodSignature(method), interfaceMethodSignature(want)
+		if !have.matches(expected) {
+			return fmt.Errorf("класс %s не реализует интерфейс %s: метод %s%s, ожидается %s%s",
+				class.Name.Value, iface.Name.Value, want.Name.Value, have, want.Name.Value, expected)
+		}
+	}
+	return nil
+}
+
+// generateCallArguments генерирует аргументы вызова, сверяя их с сигнатурой вызываемой функции,
+// если она известна: проверяет число аргументов, соответствие интерфейсам и приводит типы
+func (g *Generator) generateCallArguments(call *ast.CallExpression, inFunction bool) ([]string, error) {
+	sig := g.lookupSignature(call.Function)
+	if sig != nil && len(call.Arguments) != len(sig.parameters) {
+		return nil, fmt.Errorf("%s ожидает %d аргументов, получено %d",
+			call.Function.String(), len(sig.parameters), len(call.Arguments))
+	}
+
+	args := []string{}
+	for i, arg := range call.Arguments {
+		a, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return nil, err
+		}
+		if sig != nil {
+			typ := sig.paramType(i)
+			if iface := g.declaredInterfaces[typ]; iface != nil {
+				if class := g.declaredClasses[g.staticType(arg)]; class != nil {
+					if err := g.checkImplements(class, iface); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if t, isGo := g.goValueTypes[typ]; isGo {
+				// Параметр с типом Go: url.URL принимает и *url.URL
+				if a, err = g.toGo(a, arg, t, call.Token.Line); err != nil {
+					return nil, fmt.Errorf("строка %d: %s: аргумент %d: %s", call.Token.Line, call.Function.String(), i+1, err)
+				}
+			} else {
+				a = g.coerce(a, arg, typ)
+			}
+			if typ == "" {
+				a = g.boxValue(a, arg)
+			}
+		} else {
+			// Функция неизвестного типа принимает interface{}, а целые в ней — int64
+			a = g.boxValue(a, arg)
+		}
+		args = append(args, a)
+	}
+	return args, nil
+}
+
+// lookupSignature ищет сигнатуру вызываемой функции: функции верхнего уровня,
+// метода объекта известного класса или метода значения с типом-интерфейсом
+func (g *Generator) lookupSignature(fn ast.Expression) *signature {
+	switch fn := fn.(type) {
+	case *ast.Identifier:
+		if typ, ok := g.lookupVariable(fn.Value); ok {
+			if lit := g.localFunctions[fn.Value]; typ == "func" && lit != nil {
+				return functionSignature(lit)
+			}
+			return nil
+		}
+		if lit := g.functionSignatures[fn.Value]; lit != nil {
+			return functionSignature(lit)
+		}
+	case *ast.DotExpression:
+		if sig := g.goFunction(fn); sig != nil {
+			return g.goSignature(sig)
+		}
+		owner := g.staticType(fn.Left)
+		if class := g.declaredClasses[owner]; class != nil {
+			if method := findMethod(class, fn.Right.Value); method != nil {
+				return classMethodSignature(method)
+			}
+		}
+		if iface := g.declaredInterfaces[owner]; iface != nil {
+			for _, m := range iface.Methods {
+				if m.Name.Value == fn.Right.Value {
+					return interfaceMethodSignature(m)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findMethod ищет метод класса по имени
+func findMethod(class *ast.ClassStatement, name string) *ast.MethodStatement {
+	for _, m := range class.Methods {
+		if m.Name.Value == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// methodParameters возвращает параметры метода без self
+func methodParameters(m *ast.MethodStatement) []*ast.Identifier {
+	if len(m.Parameters) == 0 {
+		return m.Parameters
+	}
+	return m.Parameters[1:]
+}
+
+func functionSignature(fn *ast.FunctionLiteral) *signature {
+	return &signature{parameters: fn.Parameters, types: fn.ParameterTypes, results: resultTypes(fn.ReturnType, fn.ReturnTypes, fn.Body)}
+}
+
+func classMethodSignature(m *ast.MethodStatement) *signature {
+	return &signature{parameters: methodParameters(m), types: m.ParameterTypes, results: resultTypes(m.ReturnType, m.ReturnTypes, m.Body)}
+}
+
+func interfaceMethodSignature(m *ast.MethodSignature) *signature {
+	return &signature{parameters: m.Parameters, types: m.ParameterTypes, results: resultTypes(m.ReturnType, nil, nil)}
+}
+
+// typeName возвращает имя типа из аннотации или пустую строку, если аннотации нет
+func typeName(typ *ast.Identifier) string {
+	if typ == nil {
+		return ""
+	}
+	return typ.Value
+}
+
+// generateParameters генерирует список параметров Go и регистрирует их типы
+// в текущей области видимости; параметры без аннотации получают interface{}
+func (g *Generator) generateParameters(params []*ast.Identifier, types map[string]*ast.Identifier) (string, error) {
+	out := []string{}
+	for _, p := range params {
+		typ := typeName(types[p.Value])
+		goTyp, err := g.goType(typ)
+		if err != nil {
+			return "", err
+		}
+		g.variableTypes[p.Value] = typ
+		out = append(out, fmt.Sprintf("%s %s", p.Value, goTyp))
+	}
+	return strings.Join(out, ", "), nil
+}
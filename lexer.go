@@ -10,6 +10,7 @@ type Lexer struct {
 	position     int  // текущая позиция в input (указывает на текущий символ)
 	readPosition int  // следующая позиция для чтения (после текущего символа)
 	ch           byte // текущий символ
+	line         int  // номер текущей строки (с 1)
 
 	// Для обработки отступов
 	indentStack []int // Стек для отслеживания уровней отступов
@@ -18,7 +19,7 @@ type Lexer struct {
 
 // New создает новый экземпляр Lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input, indentStack: []int{0}}
+	l := &Lexer{input: input, indentStack: []int{0}, line: 1}
 	l.readChar()
 	return l
 }
@@ -33,8 +34,18 @@ func (l *Lexer) readChar() {
 	l.readPosition++
 }
 
-// NextToken возвращает следующий токен
+// NextToken возвращает следующий токен вместе с номером строки, на которой он стоит
 func (l *Lexer) NextToken() token.Token {
+	line := l.line
+	tok := l.readToken()
+	if tok.Line == 0 {
+		tok.Line = line
+	}
+	return tok
+}
+
+// readToken читает следующий токен
+func (l *Lexer) readToken() token.Token {
 	// Если есть ожидающие токены (INDENT/DEDENT), выдаем их первыми
 	if len(l.pendingTokens) > 0 {
 		tok := l.pendingTokens[0]
@@ -60,7 +71,14 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.ARROW, Literal: literal}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -75,11 +93,38 @@ func (l *Lexer) NextToken() token.Token {
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '-' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.LARROW, Literal: literal}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.LT_EQ, Literal: literal}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.GT_EQ, Literal: literal}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '|':
+		tok = newToken(token.PIPE, l.ch)
+	case '&':
+		tok = newToken(token.AMPERSAND, l.ch)
+	case '^':
+		tok = newToken(token.CARET, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -88,9 +133,14 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
+	case '{':
+		tok = newToken(token.LBRACE, l.ch)
+	case '}':
+		tok = newToken(token.RBRACE, l.ch)
 	case '\n':
 		tok = newToken(token.NEWLINE, l.ch)
 		l.readChar() // Consume the newline character
+		l.line++
 		// Now, measure the indent of the next line and queue INDENT/DEDENT tokens
 		currentLineStart := l.position
 		currentIndent := l.measureIndent(currentLineStart)
@@ -99,11 +149,11 @@ func (l *Lexer) NextToken() token.Token {
 
 		if currentIndent > lastIndent {
 			l.indentStack = append(l.indentStack, currentIndent)
-			l.pendingTokens = append(l.pendingTokens, newToken(token.INDENT, 0))
+			l.pendingTokens = append(l.pendingTokens, token.Token{Type: token.INDENT, Line: l.line})
 		} else if currentIndent < lastIndent {
 			for currentIndent < l.indentStack[len(l.indentStack)-1] {
 				l.indentStack = l.indentStack[:len(l.indentStack)-1]
-				l.pendingTokens = append(l.pendingTokens, newToken(token.DEDENT, 0))
+				l.pendingTokens = append(l.pendingTokens, token.Token{Type: token.DEDENT, Line: l.line})
 			}
 		}
 		
@@ -133,6 +183,10 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			// "or else" читается одним токеном: значение по умолчанию при ошибке
+			if tok.Type == token.OR && l.skipWord("else") {
+				tok = token.Token{Type: token.ORELSE, Literal: "or else"}
+			}
 			
 			return tok
 		} else if isDigit(l.ch) {
@@ -146,7 +200,7 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	}
 
-	
+	l.readChar()
 	return tok
 }
 
@@ -164,12 +218,29 @@ func (l *Lexer) skipWhitespaceAndComments() {
 
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	// Цифры допустимы после первого символа: MaxInt32, utf8
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
+// skipWord пропускает следующее на этой строке слово, если оно равно word
+func (l *Lexer) skipWord(word string) bool {
+	i := l.position
+	for i < len(l.input) && (l.input[i] == ' ' || l.input[i] == '\t') {
+		i++
+	}
+	end := i + len(word)
+	if end > len(l.input) || l.input[i:end] != word || (end < len(l.input) && isLetter(l.input[end])) {
+		return false
+	}
+	for l.position < end {
+		l.readChar()
+	}
+	return true
+}
+
 func (l *Lexer) readNumber() string {
 	position := l.position
 	for isDigit(l.ch) {
@@ -64,6 +64,255 @@ let result = add(five, ten)
 
 	l := New(input)
 
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong tokentype. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTypeAnnotationTokens(t *testing.T) {
+	input := `def area(self, k: int) -> int`
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.DEF, "def"},
+		{token.IDENT, "area"},
+		{token.LPAREN, "("},
+		{token.IDENT, "self"},
+		{token.COMMA, ","},
+		{token.IDENT, "k"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong tokentype. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenLines(t *testing.T) {
+	input := "x = 1\nif x\n    y = 2\n"
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedLine int
+	}{
+		{token.IDENT, 1},
+		{token.ASSIGN, 1},
+		{token.INT, 1},
+		{token.NEWLINE, 1},
+		{token.IF, 2},
+		{token.IDENT, 2},
+		{token.NEWLINE, 2},
+		{token.INDENT, 3},
+		{token.IDENT, 3},
+		{token.ASSIGN, 3},
+		{token.INT, 3},
+		{token.NEWLINE, 3},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong tokentype. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Line != tt.expectedLine {
+			t.Fatalf("tests[%d] - wrong line. expected=%d, got=%d",
+				i, tt.expectedLine, tok.Line)
+		}
+	}
+}
+
+func TestOrElseToken(t *testing.T) {
+	input := "a or b\nx or else y\n"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.OR, "or"},
+		{token.IDENT, "b"},
+		{token.NEWLINE, "\n"},
+		{token.IDENT, "x"},
+		{token.ORELSE, "or else"},
+		{token.IDENT, "y"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong tokentype. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestChannelTokens(t *testing.T) {
+	input := "go f(ch)\nch <- x\nv = <-ch\nselect\n"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.GO, "go"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "ch"},
+		{token.RPAREN, ")"},
+		{token.NEWLINE, "\n"},
+		{token.IDENT, "ch"},
+		{token.LARROW, "<-"},
+		{token.IDENT, "x"},
+		{token.NEWLINE, "\n"},
+		{token.IDENT, "v"},
+		{token.ASSIGN, "="},
+		{token.LARROW, "<-"},
+		{token.IDENT, "ch"},
+		{token.NEWLINE, "\n"},
+		{token.SELECT, "select"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong tokentype. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSetOperatorTokens(t *testing.T) {
+	input := "a | b & c ^ d"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.PIPE, "|"},
+		{token.IDENT, "b"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "c"},
+		{token.CARET, "^"},
+		{token.IDENT, "d"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong tokentype. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIdentifierWithDigits(t *testing.T) {
+	input := "x = math.MaxInt32 + utf8"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "math"},
+		{token.DOT, "."},
+		{token.IDENT, "MaxInt32"},
+		{token.PLUS, "+"},
+		{token.IDENT, "utf8"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong tokentype. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong literal. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComparisonTokens(t *testing.T) {
+	input := "a <= b >= c <- ch"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.LARROW, "<-"},
+		{token.IDENT, "ch"},
+	}
+
+	l := New(input)
+
 	for i, tt := range tests {
 		tok := l.NextToken()
 
@@ -0,0 +1,263 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"gopy/ast"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SetLibrary включает режим библиотеки: вместо программы генерируется пакет Go
+// name, который можно импортировать из кода на Go
+func (g *Generator) SetLibrary(name string) {
+	g.library = name
+}
+
+// exportName возвращает экспортированное имя Go для имени Gopy: parse_line — ParseLine
+func exportName(name string) string {
+	var out strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(part)
+		out.WriteRune(unicode.ToUpper(r))
+		out.WriteString(part[size:])
+	}
+	return out.String()
+}
+
+// libraryExports следит, чтобы разные имена Gopy не дали одно имя Go
+type libraryExports map[string]string
+
+func (e libraryExports) add(goName, name string) error {
+	if other, ok := e[goName]; ok {
+		return fmt.Errorf("имена %s и %s дают в библиотеке одно имя Go %s", other, name, goName)
+	}
+	e[goName] = name
+	return nil
+}
+
+// generateLibraryAPI генерирует экспортированный интерфейс библиотеки. Функции и
+// методы Gopy вызываются через обёртки с именами Go: ошибка Gopy возвращается
+// последним результатом error, а списки и словари передаются как срезы и map Go.
+// Имена, начинающиеся с _, не экспортируются
+func (g *Generator) generateLibraryAPI(program *ast.Program) (string, error) {
+	var out bytes.Buffer
+	exports := make(libraryExports)
+
+	// Виды ошибок можно проверить через errors.Is(err, pkg.ValueError)
+	g.useHelper("gopyRecover")
+	kinds := []string{}
+	for kind := range errorKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	out.WriteString("var (\n")
+	for _, kind := range kinds {
+		exports.add(kind, kind)
+		out.WriteString(fmt.Sprintf("\t%s error = %s\n", kind, errorKinds[kind]))
+	}
+	out.WriteString(")\n\n")
+
+	for _, stmt := range program.Statements {
+		var err error
+		switch stmt := stmt.(type) {
+		case *ast.FunctionStatement:
+			err = g.exportFunction(exports, stmt.Name.Value, stmt.Function, &out)
+		case *ast.LetStatement:
+			if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+				err = g.exportFunction(exports, stmt.Name.Value, fn, &out)
+			}
+		case *ast.ClassStatement:
+			err = g.exportClass(exports, stmt, &out)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+// exportFunction генерирует обёртку функции: greet(name: str) -> str становится
+// func Greet(name string) (string, error)
+func (g *Generator) exportFunction(exports libraryExports, name string, fn *ast.FunctionLiteral, out *bytes.Buffer) error {
+	if strings.HasPrefix(name, "_") {
+		return nil
+	}
+	goName := exportName(name)
+	if goName == name {
+		return fmt.Errorf("функция %s: в библиотеке имена функций пишутся с маленькой буквы", name)
+	}
+	if err := exports.add(goName, name); err != nil {
+		return err
+	}
+	procedure := fn.ReturnType == nil && len(fn.ReturnTypes) == 0 && !containsReturn(fn.Body.Statements)
+	return g.exportCall(out, "func "+goName, name, functionSignature(fn), procedure)
+}
+
+// exportClass экспортирует класс: тип, конструктор NewT, методы чтения и записи
+// полей (x — X и SetX) и обёртки методов
+func (g *Generator) exportClass(exports libraryExports, class *ast.ClassStatement, out *bytes.Buffer) error {
+	name := class.Name.Value
+	if strings.HasPrefix(name, "_") {
+		return nil
+	}
+	goName := exportName(name)
+	if err := exports.add(goName, name); err != nil {
+		return err
+	}
+	if err := exports.add("New"+goName, name); err != nil {
+		return err
+	}
+	if goName != name {
+		out.WriteString(fmt.Sprintf("type %s = %s\n\n", goName, name))
+		out.WriteString(fmt.Sprintf("func New%s() *%s {\n\treturn %s()\n}\n\n", goName, goName, g.constructorName(name)))
+	}
+
+	members := make(libraryExports)
+	for i, f := range class.Fields {
+		// message класса ошибки хранится во встроенной gopyError и доступен через Error()
+		if (class.Base != nil && i == 0) || strings.HasPrefix(f.Name.Value, "_") {
+			continue
+		}
+		field := exportName(f.Name.Value)
+		if field == f.Name.Value {
+			return fmt.Errorf("поле %s класса %s: в библиотеке имена полей пишутся с маленькой буквы", f.Name.Value, name)
+		}
+		if err := members.add(field, f.Name.Value); err != nil {
+			return fmt.Errorf("класс %s: %s", name, err)
+		}
+		if err := members.add("Set"+field, f.Name.Value); err != nil {
+			return fmt.Errorf("класс %s: %s", name, err)
+		}
+		typ := g.fieldType(f)
+		apiTyp, err := g.apiType(typ)
+		if err != nil {
+			return err
+		}
+		out.WriteString(fmt.Sprintf("func (self *%s) %s() %s {\n\treturn %s\n}\n\n",
+			goName, field, apiTyp, g.apiResult("self."+f.Name.Value, typ)))
+		out.WriteString(fmt.Sprintf("func (self *%s) Set%s(value %s) {\n\tself.%s = %s\n}\n\n",
+			goName, field, apiTyp, f.Name.Value, g.apiArgument("value", typ)))
+	}
+	for _, m := range class.Methods {
+		if strings.HasPrefix(m.Name.Value, "_") {
+			continue
+		}
+		method := exportName(m.Name.Value)
+		if method == m.Name.Value {
+			return fmt.Errorf("метод %s.%s: в библиотеке имена методов пишутся с маленькой буквы", name, m.Name.Value)
+		}
+		if err := members.add(method, m.Name.Value); err != nil {
+			return fmt.Errorf("класс %s: %s", name, err)
+		}
+		procedure := m.ReturnType == nil && len(m.ReturnTypes) == 0 && !containsReturn(m.Body.Statements)
+		decl := fmt.Sprintf("func (self *%s) %s", goName, method)
+		if err := g.exportCall(out, decl, "self."+m.Name.Value, classMethodSignature(m), procedure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportCall генерирует обёртку decl, которая вызывает функцию Gopy callee:
+// приводит аргументы и результаты к типам API и возвращает ошибку Gopy как error.
+// procedure — функция не возвращает значения, и обёртка возвращает только error
+func (g *Generator) exportCall(out *bytes.Buffer, decl, callee string, sig *signature, procedure bool) error {
+	params, args := []string{}, []string{}
+	errName := "err"
+	for i, p := range sig.parameters {
+		typ := sig.paramType(i)
+		apiTyp, err := g.apiType(typ)
+		if err != nil {
+			return err
+		}
+		params = append(params, fmt.Sprintf("%s %s", p.Value, apiTyp))
+		args = append(args, g.apiArgument(p.Value, typ))
+		if p.Value == errName {
+			errName = "gopyErr"
+		}
+	}
+	call := fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", "))
+
+	if procedure {
+		out.WriteString(fmt.Sprintf("%s(%s) (%s error) {\n\tdefer gopyRecover(&%s)\n\t%s\n\treturn nil\n}\n\n",
+			decl, strings.Join(params, ", "), errName, errName, call))
+		return nil
+	}
+	results, values, names := []string{}, []string{}, []string{}
+	for i, typ := range sig.results {
+		apiTyp, err := g.apiType(typ)
+		if err != nil {
+			return err
+		}
+		results = append(results, "_ "+apiTyp)
+		names = append(names, fmt.Sprintf("r%d", i))
+		values = append(values, g.apiResult(names[i], typ))
+	}
+	results = append(results, errName+" error")
+	body := fmt.Sprintf("\treturn %s, nil\n", g.apiResult(call, sig.results[0]))
+	if len(sig.results) > 1 {
+		body = fmt.Sprintf("\t%s := %s\n\treturn %s, nil\n", strings.Join(names, ", "), call, strings.Join(values, ", "))
+	}
+	out.WriteString(fmt.Sprintf("%s(%s) (%s) {\n\tdefer gopyRecover(&%s)\n%s}\n\n",
+		decl, strings.Join(params, ", "), strings.Join(results, ", "), errName, body))
+	return nil
+}
+
+// apiType возвращает тип Go, которым значение типа Gopy typ представлено в API
+// библиотеки. Список и кортеж — []interface{}, объект класса — указатель на его
+// экспортированный тип
+func (g *Generator) apiType(typ string) (string, error) {
+	_, fromModule := g.moduleName(typ)
+	switch {
+	case typ == "list" || typ == "tuple":
+		return "[]interface{}", nil
+	case typ == "dict":
+		return "map[interface{}]interface{}", nil
+	case g.isClass(typ) && !fromModule:
+		// Классы модулей Gopy остаются внутренними типами библиотеки
+		return "*" + exportName(typ), nil
+	}
+	return g.goType(typ)
+}
+
+// apiArgument приводит аргумент, переданный из Go, к значению Gopy типа typ
+func (g *Generator) apiArgument(code, typ string) string {
+	switch typ {
+	case "list":
+		g.useHelper("gopyFromGo")
+		return fmt.Sprintf("gopyFromGo(%s).(*gopyList)", code)
+	case "tuple":
+		g.useHelper("gopyFromGo")
+		g.useHelper("gopyNewTuple")
+		return fmt.Sprintf("gopyNewTuple(gopyFromGo(%s).(*gopyList).items)", code)
+	case "dict":
+		g.useHelper("gopyFromGo")
+		return fmt.Sprintf("gopyFromGo(%s).(map[interface{}]interface{})", code)
+	case "":
+		g.useHelper("gopyFromGo")
+		return fmt.Sprintf("gopyFromGo(%s)", code)
+	}
+	return code
+}
+
+// apiResult приводит значение Gopy типа typ к значению, которое получает код на Go
+func (g *Generator) apiResult(code, typ string) string {
+	switch typ {
+	case "list", "tuple":
+		g.useHelper("gopyExport")
+		return fmt.Sprintf("gopyExport(%s).([]interface{})", code)
+	case "dict":
+		g.useHelper("gopyExport")
+		return fmt.Sprintf("gopyExport(%s).(map[interface{}]interface{})", code)
+	case "":
+		g.useHelper("gopyExport")
+		return fmt.Sprintf("gopyExport(%s)", code)
+	}
+	return code
+}
@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+func init() {
+	methods["list"] = map[string]*builtin{
+		"append":  {minArgs: 1, maxArgs: 1, generate: (*Generator).generateListMethod},
+		"extend":  {minArgs: 1, maxArgs: 1, params: []string{"list|tuple|str|dict|set"}, generate: (*Generator).generateListMethod},
+		"insert":  {minArgs: 2, maxArgs: 2, params: []string{"int", ""}, generate: (*Generator).generateListMethod},
+		"pop":     {maxArgs: 1, params: []string{"int"}, generate: (*Generator).generateListMethod},
+		"remove":  {minArgs: 1, maxArgs: 1, generate: (*Generator).generateListMethod},
+		"index":   {minArgs: 1, maxArgs: 1, result: "int", generate: (*Generator).generateListMethod},
+		"count":   {minArgs: 1, maxArgs: 1, result: "int", generate: (*Generator).generateListMethod},
+		"reverse": {generate: (*Generator).generateListMethod},
+		"clear":   {generate: (*Generator).generateListMethod},
+		"copy":    {result: "list", generate: (*Generator).generateListMethod},
+		"sort": {keywords: map[string]string{"key": "func", "reverse": "bool"},
+			generate: (*Generator).generateListSort},
+	}
+}
+
+// generateListMethod генерирует вызов метода списка. Методы объявлены у типа
+// gopyList, поэтому вызов выглядит так же, как в Gopy: xs.append(x)
+func (g *Generator) generateListMethod(call *ast.CallExpression, inFunction bool) (string, error) {
+	xs, args, err := g.methodArguments(call, inFunction, "list")
+	if err != nil {
+		return "", err
+	}
+	method := call.Function.(*ast.DotExpression).Right.Value
+	for i, arg := range call.Arguments {
+		args[i] = g.boxValue(args[i], arg)
+	}
+	g.useHelper("gopyList")
+	line := call.Token.Line
+	switch method {
+	case "extend":
+		g.useHelper("gopyListMethods")
+		return fmt.Sprintf("%s.extend(%s)", xs, g.iterableItems(args[0], call.Arguments[0], line)), nil
+	case "insert":
+		g.useHelper("gopyListMethods")
+		return fmt.Sprintf("%s.insert(%s, %s)", xs, g.intValue(args[0], call.Arguments[0]), args[1]), nil
+	case "pop":
+		g.useHelper("gopyListPop")
+		index := "-1"
+		if len(args) == 1 {
+			index = g.intValue(args[0], call.Arguments[0])
+		}
+		return fmt.Sprintf("%s.pop(%s, %d)", xs, index, line), nil
+	case "index", "remove":
+		g.useHelper("gopyListSearch")
+		return fmt.Sprintf("%s.%s(%s, %d)", xs, method, args[0], line), nil
+	case "count":
+		g.useHelper("gopyListSearch")
+	case "reverse", "clear":
+		g.useHelper("gopyListMethods")
+	case "copy":
+		return fmt.Sprintf("gopyListCopy(%s.items)", xs), nil
+	}
+	return fmt.Sprintf("%s.%s(%s)", xs, method, strings.Join(args, ", ")), nil
+}
+
+// generateListSort генерирует xs.sort(key=None, reverse=false): список
+// сортируется на месте по тем же правилам, что и sorted
+func (g *Generator) generateListSort(call *ast.CallExpression, inFunction bool) (string, error) {
+	xs, err := g.methodReceiver(call, inFunction, "list")
+	if err != nil {
+		return "", err
+	}
+	key, reverse := "nil", "false"
+	if expr := keywordArgument(call, "key"); expr != nil {
+		if key, err = g.generateExpressionWithCast(expr, inFunction, false); err != nil {
+			return "", err
+		}
+	}
+	if expr := keywordArgument(call, "reverse"); expr != nil {
+		if reverse, err = g.generateExpressionWithCast(expr, inFunction, false); err != nil {
+			return "", err
+		}
+		reverse = g.coerce(reverse, expr, "bool")
+	}
+	g.useHelper("gopyListSort")
+	return fmt.Sprintf("%s.sort(%s, %s, %d)", xs, key, reverse, call.Token.Line), nil
+}
+
+// iterableItems возвращает элементы итерируемого значения как []interface{}
+func (g *Generator) iterableItems(code string, expr ast.Expression, line int) string {
+	switch g.staticType(expr) {
+	case "list":
+		return code + ".items"
+	case "tuple":
+		return code + ".gopyItems()"
+	}
+	g.useHelper("gopyIter")
+	return fmt.Sprintf("gopyIter(%s, %d)", code, line)
+}
+
+// comparedByItems сообщает, что значения типа сравниваются через gopyEqual:
+// оператор == Go не применим к ним или сравнивает ссылки
+func comparedByItems(typ string) bool {
+	return typ == "list" || typ == "tuple" || typ == "dict" || typ == "set"
+}
+
+// generateListOperator генерирует операторы, которые работают со списками:
+// xs + ys, xs * n, x in xs и сравнение списков. ok == false — оператор
+// относится к другим типам и генерируется как обычно
+func (g *Generator) generateListOperator(expr *ast.InfixExpression, left, right string) (string, bool) {
+	leftType, rightType := g.staticType(expr.Left), g.staticType(expr.Right)
+	line := expr.Token.Line
+	switch expr.Operator {
+	case "in", "not in":
+		code := ""
+		if leftType == "str" && rightType == "str" {
+			g.imports["strings"] = true
+			code = fmt.Sprintf("strings.Contains(%s, %s)", right, left)
+		} else {
+			g.useHelper("gopyContains")
+			code = fmt.Sprintf("gopyContains(%s, %s, %d)", right, g.boxValue(left, expr.Left), line)
+		}
+		if expr.Operator == "not in" {
+			return "(!" + code + ")", true
+		}
+		return code, true
+	case "+":
+		if leftType == "list" || rightType == "list" {
+			g.useHelper("gopyConcat")
+			return fmt.Sprintf("gopyConcat(%s, %s, %d)", left, right, line), true
+		}
+	case "*":
+		if leftType == "list" {
+			g.useHelper("gopyRepeat")
+			return fmt.Sprintf("gopyRepeat(%s, %s, %d)", left, g.intValue(right, expr.Right), line), true
+		}
+		if rightType == "list" {
+			g.useHelper("gopyRepeat")
+			return fmt.Sprintf("gopyRepeat(%s, %s, %d)", right, g.intValue(left, expr.Left), line), true
+		}
+	case "==", "!=":
+		// Контейнеры сравниваются по элементам, а не по ссылке
+		if comparedByItems(leftType) || comparedByItems(rightType) {
+			g.useHelper("gopyEqual")
+			code := fmt.Sprintf("gopyEqual(%s, %s)", left, right)
+			if expr.Operator == "!=" {
+				return "(!" + code + ")", true
+			}
+			return code, true
+		}
+	}
+	return "", false
+}
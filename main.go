@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"gopy/generator"
 	"gopy/lexer"
@@ -13,12 +14,15 @@ import (
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Использование: gopy <файл.gopy>")
+	release := flag.Bool("release", false, "собрать программу без проверок assert")
+	library := flag.String("lib", "", "собрать библиотеку: записать пакет Go с этим именем в одноимённый каталог")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("Использование: gopy [-release] [-lib пакет] <файл.gopy>")
 		os.Exit(1)
 	}
 
-	inputFile := os.Args[1]
+	inputFile := flag.Arg(0)
 	content, err := ioutil.ReadFile(inputFile)
 	if err != nil {
 		fmt.Printf("Ошибка чтения файла %s: %s\n", inputFile, err)
@@ -38,11 +42,41 @@ func main() {
 	}
 
 	gen := generator.New()
+	gen.SetSourceFile(filepath.Base(inputFile))
+	gen.SetModuleDir(filepath.Dir(inputFile))
+	gen.SetRelease(*release)
+	gen.SetLibrary(*library)
 	generatedCode, err := gen.Generate(program)
 	if err != nil {
 		fmt.Printf("Ошибка генерации кода: %s\n", err)
 		os.Exit(1)
 	}
+	for _, warning := range gen.Warnings() {
+		fmt.Println("Предупреждение: " + warning)
+	}
+
+	// Библиотека записывается рядом с исходным файлом и только проверяется компилятором
+	if *library != "" {
+		dir := filepath.Join(filepath.Dir(inputFile), *library)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Printf("Ошибка создания каталога %s: %s\n", dir, err)
+			os.Exit(1)
+		}
+		goPath := filepath.Join(dir, *library+".go")
+		if err := ioutil.WriteFile(goPath, []byte(generatedCode), 0644); err != nil {
+			fmt.Printf("Ошибка записи файла %s: %s\n", goPath, err)
+			os.Exit(1)
+		}
+		cmd := exec.Command("go", "build", goPath)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Printf("Ошибка компиляции Go-кода: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("--- Gopy: пакет %s записан в %s ---\n", *library, goPath)
+		return
+	}
 
 	// Сохраняем сгенерированный Go-код во временный файл
 	goFile, err := ioutil.TempFile("", "gopy-*.go")
@@ -72,6 +106,7 @@ func main() {
 
 	// Запускаем скомпилированный exe
 	cmdRun := exec.Command("./" + outputExe)
+	cmdRun.Stdin = os.Stdin // для input()
 	cmdRun.Stdout = os.Stdout
 	cmdRun.Stderr = os.Stderr
 	if err := cmdRun.Run(); err != nil {
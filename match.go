@@ -0,0 +1,593 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// patternBinding — имя, которое образец связывает с частью сопоставляемого значения
+type patternBinding struct {
+	name string
+	code string // выражение Go, из которого берётся значение
+	typ  string
+}
+
+// patternMatch — разобранный образец: условия совпадения и связанные имена
+type patternMatch struct {
+	conditions []string
+	bindings   []patternBinding
+}
+
+func (m *patternMatch) condition() string {
+	if len(m.conditions) == 0 {
+		return "true"
+	}
+	return strings.Join(m.conditions, " && ")
+}
+
+// generateMatch генерирует match. Ветки только с литералами становятся switch по
+// значению, ветки только с образцами классов — switch по типу, остальные —
+// switch без выражения, где каждая ветка проверяет свои условия по порядку
+func (g *Generator) generateMatch(stmt *ast.MatchStatement, inFunction bool) (string, error) {
+	if len(stmt.Cases) == 0 {
+		return "", fmt.Errorf("строка %d: match без веток case", stmt.Token.Line)
+	}
+	for i, c := range stmt.Cases[:len(stmt.Cases)-1] {
+		if c.Guard == nil && irrefutable(c.Pattern) {
+			return "", fmt.Errorf("строка %d: образец %s делает следующие ветки case недостижимыми",
+				stmt.Cases[i].Token.Line, c.Pattern.String())
+		}
+	}
+
+	subject, err := g.generateExpressionWithCast(stmt.Subject, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	typ := g.staticType(stmt.Subject)
+	// Значение вычисляется один раз в инициализации switch
+	init := ""
+	if _, ok := stmt.Subject.(*ast.Identifier); !ok {
+		g.tempCounter++
+		tmp := fmt.Sprintf("gopyTmp%d", g.tempCounter)
+		init = fmt.Sprintf("%s := %s; ", tmp, subject)
+		subject = tmp
+	}
+	g.checkExhaustive(stmt, typ)
+
+	switch {
+	case g.isValueSwitch(stmt, typ):
+		return g.generateValueSwitch(stmt, init, subject, typ, inFunction)
+	case g.isTypeSwitch(stmt, typ):
+		return g.generateTypeSwitch(stmt, init, subject, typ, inFunction)
+	}
+	return g.generateCaseSwitch(stmt, init, subject, typ, inFunction)
+}
+
+// irrefutable сообщает, что образец совпадает с любым значением
+func irrefutable(pattern ast.Pattern) bool {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern, *ast.CapturePattern:
+		return true
+	case *ast.OrPattern:
+		for _, alternative := range pattern.Alternatives {
+			if irrefutable(alternative) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDefault сообщает, что ветка — последняя и совпадает с любым значением
+func isDefault(stmt *ast.MatchStatement, i int) bool {
+	c := stmt.Cases[i]
+	return i == len(stmt.Cases)-1 && c.Guard == nil && irrefutable(c.Pattern)
+}
+
+// isValueSwitch: все ветки, кроме последней ветки по умолчанию, — литералы без условий,
+// а тип значения известен
+func (g *Generator) isValueSwitch(stmt *ast.MatchStatement, typ string) bool {
+	if typ != "int" && typ != "str" && typ != "bool" {
+		return false
+	}
+	for i, c := range stmt.Cases {
+		if isDefault(stmt, i) {
+			continue
+		}
+		if c.Guard != nil || len(literalValues(c.Pattern)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// literalValues возвращает литералы образца 1 | 2 | 3 или nil, если образец не литеральный
+func literalValues(pattern ast.Pattern) []*ast.LiteralPattern {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return []*ast.LiteralPattern{pattern}
+	case *ast.OrPattern:
+		values := []*ast.LiteralPattern{}
+		for _, alternative := range pattern.Alternatives {
+			literal, ok := alternative.(*ast.LiteralPattern)
+			if !ok {
+				return nil
+			}
+			values = append(values, literal)
+		}
+		return values
+	}
+	return nil
+}
+
+// isTypeSwitch: значение неизвестного типа или интерфейс, а все ветки, кроме
+// последней ветки по умолчанию, — образцы разных классов, поля которых только связываются
+func (g *Generator) isTypeSwitch(stmt *ast.MatchStatement, typ string) bool {
+	if typ != "" && g.declaredInterfaces[typ] == nil {
+		return false
+	}
+	seen := make(map[string]bool)
+	for i, c := range stmt.Cases {
+		if isDefault(stmt, i) {
+			continue
+		}
+		class, ok := c.Pattern.(*ast.ClassPattern)
+		if !ok || c.Guard != nil || seen[class.Class.Value] || !classPatternIrrefutable(class) {
+			return false
+		}
+		seen[class.Class.Value] = true
+	}
+	return true
+}
+
+// classPatternIrrefutable сообщает, что образец класса проверяет только класс
+func classPatternIrrefutable(class *ast.ClassPattern) bool {
+	for _, argument := range class.Arguments {
+		if !irrefutable(argument) {
+			return false
+		}
+	}
+	for _, keyword := range class.Keywords {
+		if !irrefutable(keyword.Pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Generator) generateValueSwitch(stmt *ast.MatchStatement, init, subject, typ string, inFunction bool) (string, error) {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("\tswitch %s%s {\n", init, subject))
+	seen := make(map[string]bool)
+	for i, c := range stmt.Cases {
+		m := &patternMatch{}
+		if isDefault(stmt, i) {
+			if err := g.compilePattern(c.Pattern, subject, typ, m); err != nil {
+				return "", err
+			}
+			out.WriteString("\tdefault:\n")
+		} else {
+			values := []string{}
+			for _, literal := range literalValues(c.Pattern) {
+				if err := g.checkLiteral(literal, typ); err != nil {
+					return "", err
+				}
+				value, err := g.generateExpressionWithCast(literal.Value, inFunction, false)
+				if err != nil {
+					return "", err
+				}
+				// Go не допускает повторяющихся значений в switch
+				if seen[value] {
+					return "", fmt.Errorf("строка %d: значение %s уже проверяется в другой ветке case", c.Token.Line, literal.String())
+				}
+				seen[value] = true
+				values = append(values, value)
+			}
+			out.WriteString(fmt.Sprintf("\tcase %s:\n", strings.Join(values, ", ")))
+		}
+		body, err := g.generateCaseBody(c, m.bindings, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(body)
+	}
+	out.WriteString("\t}\n")
+	return out.String(), nil
+}
+
+func (g *Generator) generateTypeSwitch(stmt *ast.MatchStatement, init, subject, typ string, inFunction bool) (string, error) {
+	g.tempCounter++
+	value := fmt.Sprintf("gopyTmp%d", g.tempCounter)
+
+	var cases strings.Builder
+	bound := false
+	for i, c := range stmt.Cases {
+		m := &patternMatch{}
+		if isDefault(stmt, i) {
+			if err := g.compilePattern(c.Pattern, subject, typ, m); err != nil {
+				return "", err
+			}
+			cases.WriteString("\tdefault:\n")
+		} else {
+			class := c.Pattern.(*ast.ClassPattern)
+			if err := g.checkClassPattern(class, typ); err != nil {
+				return "", err
+			}
+			if err := g.compileClassPattern(class, value, class.Class.Value, m); err != nil {
+				return "", err
+			}
+			cases.WriteString(fmt.Sprintf("\tcase *%s:\n", class.Class.Value))
+			for _, b := range m.bindings {
+				if usesName(c.Body, b.name) {
+					bound = true
+				}
+			}
+		}
+		body, err := g.generateCaseBody(c, m.bindings, inFunction)
+		if err != nil {
+			return "", err
+		}
+		cases.WriteString(body)
+	}
+
+	// Переменная switch по типу должна использоваться хотя бы в одной ветке
+	guard := subject + ".(type)"
+	if bound {
+		guard = value + " := " + guard
+	}
+	return fmt.Sprintf("\tswitch %s%s {\n%s\t}\n", init, guard, cases.String()), nil
+}
+
+func (g *Generator) generateCaseSwitch(stmt *ast.MatchStatement, init, subject, typ string, inFunction bool) (string, error) {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("\tswitch %s{\n", init))
+	for i, c := range stmt.Cases {
+		m := &patternMatch{}
+		if err := g.compilePattern(c.Pattern, subject, typ, m); err != nil {
+			return "", err
+		}
+		if isDefault(stmt, i) {
+			out.WriteString("\tdefault:\n")
+		} else {
+			condition := m.condition()
+			if c.Guard != nil {
+				guard, err := g.generateGuard(c.Guard, m.bindings, inFunction)
+				if err != nil {
+					return "", err
+				}
+				if len(m.conditions) == 0 {
+					condition = guard
+				} else {
+					condition += " && " + guard
+				}
+			}
+			out.WriteString(fmt.Sprintf("\tcase %s:\n", condition))
+		}
+		body, err := g.generateCaseBody(c, m.bindings, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(body)
+	}
+	out.WriteString("\t}\n")
+	return out.String(), nil
+}
+
+// generateGuard генерирует условие if ветки case. Связанные образцом имена ещё не
+// объявлены, поэтому в условии они заменяются выражениями, из которых берутся значения
+func (g *Generator) generateGuard(guard ast.Expression, bindings []patternBinding, inFunction bool) (string, error) {
+	restore := g.bindPattern(bindings)
+	for _, b := range bindings {
+		g.patternAliases[b.name] = b.code
+	}
+	code, err := g.generateExpressionWithCast(guard, inFunction, false)
+	truthy := g.truthy(code, guard)
+	for _, b := range bindings {
+		delete(g.patternAliases, b.name)
+	}
+	restore()
+	if err != nil {
+		return "", err
+	}
+	return truthy, nil
+}
+
+// generateCaseBody объявляет связанные образцом имена, которые использует тело ветки, и генерирует тело
+func (g *Generator) generateCaseBody(c *ast.MatchCase, bindings []patternBinding, inFunction bool) (string, error) {
+	var out strings.Builder
+	used := []patternBinding{}
+	for _, b := range bindings {
+		if usesName(c.Body, b.name) {
+			used = append(used, b)
+			out.WriteString(fmt.Sprintf("\t\t%s := %s\n", b.name, b.code))
+		}
+	}
+	restore := g.bindPattern(used)
+	defer restore()
+	body, err := g.generateBlockStatementWithCast(c.Body, inFunction)
+	if err != nil {
+		return "", err
+	}
+	out.WriteString(indentCode(body))
+	return out.String(), nil
+}
+
+// bindPattern объявляет типы связанных имён на время ветки и возвращает функцию,
+// восстанавливающую прежние типы переменных
+func (g *Generator) bindPattern(bindings []patternBinding) func() {
+	saved := make(map[string]string)
+	existed := make(map[string]bool)
+	for _, b := range bindings {
+		saved[b.name], existed[b.name] = g.variableTypes[b.name]
+		g.variableTypes[b.name] = b.typ
+	}
+	return func() {
+		for _, b := range bindings {
+			if existed[b.name] {
+				g.variableTypes[b.name] = saved[b.name]
+			} else {
+				delete(g.variableTypes, b.name)
+			}
+		}
+	}
+}
+
+// compilePattern переводит образец в условия Go над выражением code типа typ
+func (g *Generator) compilePattern(pattern ast.Pattern, code, typ string, m *patternMatch) error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return nil
+	case *ast.CapturePattern:
+		return m.bind(pattern.Name.Value, code, typ)
+	case *ast.LiteralPattern:
+		if err := g.checkLiteral(pattern, typ); err != nil {
+			return err
+		}
+		value, err := g.generateExpressionWithCast(pattern.Value, false, false)
+		if err != nil {
+			return err
+		}
+		if typ == "" {
+			g.useHelper("gopyEqual")
+			m.conditions = append(m.conditions, fmt.Sprintf("gopyEqual(%s, %s)", code, g.boxValue(value, pattern.Value)))
+		} else {
+			m.conditions = append(m.conditions, fmt.Sprintf("%s == %s", code, value))
+		}
+		return nil
+	case *ast.OrPattern:
+		alternatives := []string{}
+		for _, alternative := range pattern.Alternatives {
+			sub := &patternMatch{}
+			if err := g.compilePattern(alternative, code, typ, sub); err != nil {
+				return err
+			}
+			if len(sub.bindings) > 0 {
+				return fmt.Errorf("альтернативы образца %s не могут связывать имена", pattern.String())
+			}
+			if len(sub.conditions) == 0 {
+				return nil
+			}
+			alternatives = append(alternatives, sub.condition())
+		}
+		m.conditions = append(m.conditions, "("+strings.Join(alternatives, " || ")+")")
+		return nil
+	case *ast.ClassPattern:
+		if err := g.checkClassPattern(pattern, typ); err != nil {
+			return err
+		}
+		value := code
+		if typ != pattern.Class.Value {
+			m.conditions = append(m.conditions, typeTest(code, "*"+pattern.Class.Value))
+			value = fmt.Sprintf("%s.(*%s)", code, pattern.Class.Value)
+		}
+		return g.compileClassPattern(pattern, value, pattern.Class.Value, m)
+	case *ast.ListPattern:
+		return g.compileListPattern(pattern, code, typ, m)
+	}
+	return fmt.Errorf("неподдерживаемый образец: %s", pattern.String())
+}
+
+// compileClassPattern сопоставляет поля объекта value, класс которого уже проверен
+func (g *Generator) compileClassPattern(pattern *ast.ClassPattern, value, className string, m *patternMatch) error {
+	class := g.declaredClasses[className]
+	if len(pattern.Arguments) > len(class.Fields) {
+		return fmt.Errorf("образец класса %s принимает не более %d позиционных образцов, получено %d",
+			className, len(class.Fields), len(pattern.Arguments))
+	}
+	for i, argument := range pattern.Arguments {
+		field := class.Fields[i]
+		if err := g.compilePattern(argument, value+"."+field.Name.Value, g.fieldType(field), m); err != nil {
+			return err
+		}
+	}
+	for _, keyword := range pattern.Keywords {
+		field := findField(class, keyword.Name.Value)
+		if field == nil {
+			return fmt.Errorf("у класса %s нет поля %s", className, keyword.Name.Value)
+		}
+		if err := g.compilePattern(keyword.Pattern, value+"."+field.Name.Value, g.fieldType(field), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileListPattern сопоставляет длину списка и его элементы; *rest связывает
+// новый список с остальными элементами
+func (g *Generator) compileListPattern(pattern *ast.ListPattern, code, typ string, m *patternMatch) error {
+	g.useHelper("gopyList")
+	value := code + ".items"
+	switch typ {
+	case "list":
+	case "":
+		m.conditions = append(m.conditions, typeTest(code, "*gopyList"))
+		value = code + ".(*gopyList).items"
+	default:
+		return fmt.Errorf("образец %s никогда не совпадёт со значением типа %s", pattern.String(), typ)
+	}
+
+	n := len(pattern.Elements)
+	star := -1
+	for i, el := range pattern.Elements {
+		if _, ok := el.(*ast.StarPattern); ok {
+			star = i
+		}
+	}
+	if star < 0 {
+		m.conditions = append(m.conditions, fmt.Sprintf("len(%s) == %d", value, n))
+	} else if n > 1 {
+		m.conditions = append(m.conditions, fmt.Sprintf("len(%s) >= %d", value, n-1))
+	}
+
+	for i, el := range pattern.Elements {
+		if i == star {
+			name := el.(*ast.StarPattern).Name.Value
+			if name == "_" {
+				continue
+			}
+			rest := fmt.Sprintf("%s[%d:]", value, star)
+			if after := n - star - 1; after > 0 {
+				rest = fmt.Sprintf("%s[%d:len(%s)-%d]", value, star, value, after)
+			}
+			if err := m.bind(name, fmt.Sprintf("gopyListCopy(%s)", rest), "list"); err != nil {
+				return err
+			}
+			continue
+		}
+		index := fmt.Sprintf("%s[%d]", value, i)
+		if star >= 0 && i > star {
+			index = fmt.Sprintf("%s[len(%s)-%d]", value, value, n-i)
+		}
+		if err := g.compilePattern(el, index, "", m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *patternMatch) bind(name, code, typ string) error {
+	for _, b := range m.bindings {
+		if b.name == name {
+			return fmt.Errorf("имя %s связано в образце несколько раз", name)
+		}
+	}
+	m.bindings = append(m.bindings, patternBinding{name: name, code: code, typ: typ})
+	return nil
+}
+
+// checkLiteral проверяет, что литерал может совпасть со значением типа typ
+func (g *Generator) checkLiteral(literal *ast.LiteralPattern, typ string) error {
+	if typ != "" && g.staticType(literal.Value) != typ {
+		return fmt.Errorf("образец %s никогда не совпадёт со значением типа %s", literal.String(), typ)
+	}
+	return nil
+}
+
+// checkClassPattern проверяет, что класс объявлен и может совпасть со значением типа typ
+func (g *Generator) checkClassPattern(pattern *ast.ClassPattern, typ string) error {
+	class := g.declaredClasses[pattern.Class.Value]
+	if class == nil {
+		return fmt.Errorf("неизвестный класс в образце: %s", pattern.Class.Value)
+	}
+	if typ == "" || typ == pattern.Class.Value {
+		return nil
+	}
+	if iface := g.declaredInterfaces[typ]; iface != nil && g.checkImplements(class, iface) == nil {
+		return nil
+	}
+	return fmt.Errorf("образец %s никогда не совпадёт со значением типа %s", pattern.String(), typ)
+}
+
+// typeTest возвращает условие «значение имеет тип goTyp»
+func typeTest(code, goTyp string) string {
+	return fmt.Sprintf("func() bool { _, ok := %s.(%s); return ok }()", code, goTyp)
+}
+
+// checkExhaustive предупреждает, если match по интерфейсу без ветки по умолчанию
+// не обрабатывает какие-то из классов, реализующих интерфейс
+func (g *Generator) checkExhaustive(stmt *ast.MatchStatement, typ string) {
+	iface := g.declaredInterfaces[typ]
+	if iface == nil {
+		return
+	}
+	covered := make(map[string]bool)
+	for _, c := range stmt.Cases {
+		if c.Guard != nil {
+			continue
+		}
+		if irrefutable(c.Pattern) {
+			return
+		}
+		alternatives := []ast.Pattern{c.Pattern}
+		if or, ok := c.Pattern.(*ast.OrPattern); ok {
+			alternatives = or.Alternatives
+		}
+		for _, alternative := range alternatives {
+			if class, ok := alternative.(*ast.ClassPattern); ok && classPatternIrrefutable(class) {
+				covered[class.Class.Value] = true
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range g.declaredClasses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	missing := []string{}
+	for _, name := range names {
+		if !covered[name] && g.checkImplements(g.declaredClasses[name], iface) == nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		g.warnings = append(g.warnings, fmt.Sprintf("строка %d: match по интерфейсу %s не обрабатывает классы: %s",
+			stmt.Token.Line, typ, strings.Join(missing, ", ")))
+	}
+}
+
+// usesName сообщает, встречается ли переменная name внутри узла AST.
+// Имена полей после точки и именованных аргументов переменными не считаются
+func usesName(node ast.Node, name string) bool {
+	return walkIdentifiers(reflect.ValueOf(node), name)
+}
+
+func walkIdentifiers(v reflect.Value, name string) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return false
+		}
+		switch node := v.Interface().(type) {
+		case *ast.Identifier:
+			return node.Value == name
+		case *ast.DotExpression:
+			return walkIdentifiers(reflect.ValueOf(node.Left), name)
+		case *ast.KeywordArgument:
+			return walkIdentifiers(reflect.ValueOf(node.Value), name)
+		}
+		return walkIdentifiers(v.Elem(), name)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if walkIdentifiers(v.Field(i), name) {
+				return true
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if walkIdentifiers(v.Index(i), name) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if walkIdentifiers(key, name) || walkIdentifiers(v.MapIndex(key), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"gopy/lexer"
+	"gopy/parser"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// module — импортированный модуль Gopy. Его объявления попадают в ту же программу
+// Go с префиксом: функция greet модуля utils становится utils_greet
+type module struct {
+	path    string
+	file    string
+	prefix  string
+	exports map[string]bool
+}
+
+// SetModuleDir задаёт каталог проекта, относительно которого import ищет модули Gopy
+func (g *Generator) SetModuleDir(dir string) {
+	g.moduleDir = dir
+}
+
+// moduleFile возвращает файл модуля Gopy для import path. Если файла нет,
+// import загружает пакет Go
+func (g *Generator) moduleFile(path string) (string, bool) {
+	file := filepath.Join(g.moduleDir, filepath.FromSlash(path)+".gopy")
+	info, err := os.Stat(file)
+	return file, err == nil && !info.IsDir()
+}
+
+// loadModules генерирует объявления модулей Gopy, которые импортирует программа,
+// и заменяет в ней обращения module.name на имена объявлений. loading — цепочка
+// импортов от главного файла, по ней находятся циклы
+func (g *Generator) loadModules(program *ast.Program, loading []string) error {
+	bindings := make(map[string]*module)
+	for _, stmt := range program.Statements {
+		imp, ok := stmt.(*ast.ImportStatement)
+		if !ok {
+			continue
+		}
+		file, ok := g.moduleFile(imp.Path)
+		if !ok {
+			continue
+		}
+		for i, path := range loading {
+			if path == imp.Path {
+				chain := append(append([]string{}, loading[i:]...), imp.Path)
+				return fmt.Errorf("строка %d: циклический импорт модулей: %s", imp.Token.Line, strings.Join(chain, " -> "))
+			}
+		}
+		m := g.modules[imp.Path]
+		if m == nil {
+			var err error
+			if m, err = g.loadModule(imp.Path, file, loading); err != nil {
+				return err
+			}
+		}
+		bindings[m.name()] = m
+	}
+	return renameModuleNames(reflect.ValueOf(program), nil, bindings)
+}
+
+// loadModule разбирает файл модуля и генерирует его объявления. Модуль состоит
+// только из объявлений: его код выполняется при вызове, а не при импорте
+func (g *Generator) loadModule(path, file string, loading []string) (*module, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения модуля %s: %s", file, err)
+	}
+	p := parser.New(lexer.New(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: ошибки парсинга:\n\t%s", filepath.Base(file), strings.Join(p.Errors(), "\n\t"))
+	}
+
+	m := &module{path: path, file: filepath.Base(file), prefix: strings.ReplaceAll(path, "/", "_") + "_", exports: make(map[string]bool)}
+	names := make(map[string]string)
+	for _, stmt := range program.Statements {
+		name, ok := declarationName(stmt)
+		if !ok {
+			return nil, fmt.Errorf("%s: строка %d: модуль может содержать только объявления def, class, interface и import",
+				m.file, lineOf(stmt))
+		}
+		if name == nil {
+			continue
+		}
+		names[name.Value] = m.prefix + name.Value
+		// Имена, начинающиеся с _, видны только внутри модуля
+		m.exports[name.Value] = !strings.HasPrefix(name.Value, "_")
+	}
+
+	if err := g.loadModules(program, append(loading, path)); err != nil {
+		return nil, fmt.Errorf("%s: %s", m.file, err)
+	}
+	if err := renameModuleNames(reflect.ValueOf(program), names, nil); err != nil {
+		return nil, fmt.Errorf("%s: %s", m.file, err)
+	}
+	g.mangleNames(program)
+	g.modules[path] = m
+	if err := g.declareFunctions(program.Statements); err != nil {
+		return nil, fmt.Errorf("%s: %s", m.file, err)
+	}
+	for _, stmt := range program.Statements {
+		if err := g.generateStatement(stmt); err != nil {
+			return nil, fmt.Errorf("%s: %s", m.file, err)
+		}
+	}
+	return m, nil
+}
+
+// declarationName возвращает имя объявления верхнего уровня. ok == false — инструкция
+// не является объявлением; у import имени нет
+func declarationName(stmt ast.Statement) (*ast.Identifier, bool) {
+	switch stmt := stmt.(type) {
+	case *ast.FunctionStatement:
+		return stmt.Name, true
+	case *ast.ClassStatement:
+		return stmt.Name, true
+	case *ast.InterfaceStatement:
+		return stmt.Name, true
+	case *ast.LetStatement:
+		if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+			return stmt.Name, true
+		}
+	case *ast.ImportStatement:
+		return nil, true
+	}
+	return nil, false
+}
+
+// lineOf возвращает строку, с которой начинается инструкция
+func lineOf(stmt ast.Statement) int {
+	token := reflect.ValueOf(stmt).Elem().FieldByName("Token")
+	if !token.IsValid() {
+		return 0
+	}
+	return token.FieldByName("Line").Interface().(int)
+}
+
+// renameModuleNames переименовывает в узле AST собственные объявления модуля (names)
+// и заменяет обращения module.name к импортированным модулям (bindings) именами
+// их объявлений. Имена полей после точки и именованных аргументов не меняются
+func renameModuleNames(v reflect.Value, names map[string]string, bindings map[string]*module) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		switch node := v.Interface().(type) {
+		case *ast.Identifier:
+			return renameIdentifier(node, names, bindings)
+		case *ast.DotExpression:
+			if ident, ok := node.Left.(*ast.Identifier); ok && bindings[ident.Value] != nil {
+				name, err := bindings[ident.Value].member(ident.Value, node.Right)
+				if err != nil {
+					return err
+				}
+				v.Set(reflect.ValueOf(name))
+				return nil
+			}
+			return renameModuleNames(reflect.ValueOf(&node.Left).Elem(), names, bindings)
+		case *ast.KeywordArgument:
+			return renameModuleNames(reflect.ValueOf(&node.Value).Elem(), names, bindings)
+		case *ast.ClassField:
+			if err := renameModuleNames(reflect.ValueOf(node.Type), names, bindings); err != nil {
+				return err
+			}
+			return renameModuleNames(reflect.ValueOf(&node.Default).Elem(), names, bindings)
+		case *ast.MethodStatement, *ast.MethodSignature, *ast.KeywordPattern:
+			// Имена методов и полей не переименовываются
+			elem := v.Elem()
+			for i := 0; i < elem.NumField(); i++ {
+				if elem.Type().Field(i).Name == "Name" {
+					continue
+				}
+				if err := renameModuleNames(elem.Field(i), names, bindings); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return renameModuleNames(v.Elem(), names, bindings)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if err := renameModuleNames(v.Field(i), names, bindings); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := renameModuleNames(v.Index(i), names, bindings); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Ключи ParameterTypes — имена параметров, они переименовываются вместе с параметрами
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key)
+			if err := renameModuleNames(value, names, bindings); err != nil {
+				return err
+			}
+			if name, ok := names[key.String()]; ok && key.Kind() == reflect.String {
+				v.SetMapIndex(key, reflect.Value{})
+				v.SetMapIndex(reflect.ValueOf(name), value)
+			}
+		}
+	}
+	return nil
+}
+
+// renameIdentifier переименовывает собственное объявление модуля или тип из
+// аннотации, записанный через точку: utils.Point
+func renameIdentifier(ident *ast.Identifier, names map[string]string, bindings map[string]*module) error {
+	if name, ok := names[ident.Value]; ok {
+		ident.Value = name
+		return nil
+	}
+	dot := strings.Index(ident.Value, ".")
+	if dot < 0 || bindings[ident.Value[:dot]] == nil {
+		return nil
+	}
+	right := &ast.Identifier{Token: ident.Token, Value: ident.Value[dot+1:]}
+	name, err := bindings[ident.Value[:dot]].member(ident.Value[:dot], right)
+	if err != nil {
+		return err
+	}
+	ident.Value = name.Value
+	return nil
+}
+
+// name возвращает имя, под которым модуль доступен после import: geo/shapes — shapes
+func (m *module) name() string {
+	return m.path[strings.LastIndex(m.path, "/")+1:]
+}
+
+// member возвращает имя объявления модуля, к которому обращается binding.name
+func (m *module) member(binding string, name *ast.Identifier) (*ast.Identifier, error) {
+	exported, ok := m.exports[name.Value]
+	if !ok {
+		return nil, fmt.Errorf("строка %d: в модуле %s нет %s", name.Token.Line, binding, name.Value)
+	}
+	if !exported {
+		return nil, fmt.Errorf("строка %d: %s — внутреннее имя модуля %s", name.Token.Line, name.Value, binding)
+	}
+	return &ast.Identifier{Token: name.Token, Value: m.prefix + name.Value}, nil
+}
+
+// sourceName возвращает имя так, как оно записано в Gopy: utils_BadValue —
+// utils.BadValue, type_ — type
+func (g *Generator) sourceName(name string) string {
+	if source, ok := g.goNames[name]; ok {
+		return source
+	}
+	if source, ok := g.moduleName(name); ok {
+		return source
+	}
+	return name
+}
+
+// moduleName возвращает запись module.name для имени объявления модуля Gopy
+func (g *Generator) moduleName(name string) (string, bool) {
+	for _, m := range g.modules {
+		if _, ok := m.exports[strings.TrimPrefix(name, m.prefix)]; ok && strings.HasPrefix(name, m.prefix) {
+			return m.name() + "." + strings.TrimPrefix(name, m.prefix), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,340 @@
+package generator
+
+import (
+	"go/token"
+	"go/types"
+	"gopy/ast"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Имена Gopy попадают в код Go как есть, но часть из них в Go занята: ключевые
+// слова (type, range, func), встроенные имена (len, string, nil), пакеты, которые
+// импортирует сгенерированный код (fmt, strings), и имена самого генератора
+// (gopyList, NewPoint, main). Такие имена заменяются на имя с _ в конце: type — type_.
+// Замены запоминаются, и в сообщениях об ошибках снова стоят имена Gopy
+
+// generatedPackages — пакеты, которые может импортировать сгенерированный код
+var generatedPackages = func() map[string]bool {
+	packages := map[string]bool{"fmt": true, "errors": true, "os": true, "strings": true, "sync": true, "unicode": true}
+	for _, helper := range runtimeHelpers {
+		for _, imp := range helper.imports {
+			packages[path.Base(imp)] = true
+		}
+	}
+	return packages
+}()
+
+// programNames — имена, которые программа объявляет, и все имена, которые в ней встречаются
+type programNames struct {
+	variables map[string]bool // переменные, параметры, функции, классы и интерфейсы
+	functions map[string]bool // функции и классы: они перекрывают встроенные функции Gopy
+	types     map[string]bool // классы и интерфейсы: их имена пишутся в аннотациях
+	members   map[string]bool // поля и методы классов
+	packages  map[string]bool // пакеты Go, импортированные программой
+	all       map[string]bool
+}
+
+// mangleNames заменяет в программе объявленные ею имена, которые заняты в Go.
+// Замена идёт прямо в AST программы, как и в renameModuleNames: генератор дальше
+// работает только с именами Go, а имена Gopy восстанавливает sourceName. AST
+// обходится через reflect, чтобы не перечислять все виды узлов, в которых
+// встречаются имена
+func (g *Generator) mangleNames(program *ast.Program) {
+	names := &programNames{
+		variables: make(map[string]bool),
+		functions: make(map[string]bool),
+		types:     make(map[string]bool),
+		members:   make(map[string]bool),
+		packages:  make(map[string]bool),
+		all:       make(map[string]bool),
+	}
+	collectNames(reflect.ValueOf(program), names)
+
+	r := &nameRenames{
+		goNames:   make(map[string]string),
+		variables: make(map[string]bool),
+		members:   make(map[string]bool),
+		types:     names.types,
+		functions: names.functions,
+	}
+	for name := range names.variables {
+		if g.isReservedName(name, names) {
+			r.variables[name] = true
+		}
+	}
+	for name := range names.members {
+		if isReservedMember(name) {
+			r.members[name] = true
+		}
+	}
+	for _, set := range []map[string]bool{r.variables, r.members} {
+		for _, name := range sortedKeys(set) {
+			if _, ok := r.goNames[name]; !ok {
+				r.goNames[name] = g.mangledName(name, names)
+				g.goNames[r.goNames[name]] = name
+			}
+		}
+	}
+	mangleNode(reflect.ValueOf(program), nameVariable, r)
+}
+
+// isReservedName сообщает, что имя переменной, функции или класса нельзя
+// использовать в Go как есть
+func (g *Generator) isReservedName(name string, names *programNames) bool {
+	switch {
+	case token.IsKeyword(name) || types.Universe.Lookup(name) != nil:
+		return true
+	case name == "main" || name == "init":
+		return true
+	case generatedPackages[name] && !names.packages[name]:
+		return true
+	case strings.HasPrefix(name, "New") && (names.types[name[3:]] || g.isClass(name[3:])):
+		// Конструктор класса
+		return true
+	}
+	if _, ok := g.moduleName(name); ok {
+		return true
+	}
+	return isGeneratedName(name)
+}
+
+// isReservedMember сообщает, что имя поля или метода нельзя использовать в Go
+// как есть. Встроенные имена Go (len, string) полям и методам не мешают
+func isReservedMember(name string) bool {
+	return token.IsKeyword(name) || isGeneratedName(name)
+}
+
+// isGeneratedName сообщает, что имя совпадает с именами, которые создаёт генератор:
+// gopyList, gopyTmp1. Замены с _ в конце генератор не создаёт
+func isGeneratedName(name string) bool {
+	return strings.HasPrefix(name, "gopy") && !strings.HasSuffix(name, "_")
+}
+
+// mangledName возвращает свободное имя Go для name: type_, а если оно занято — type__
+func (g *Generator) mangledName(name string, names *programNames) string {
+	goName := name + "_"
+	for names.all[goName] || (g.goNames[goName] != "" && g.goNames[goName] != name) || g.isReservedName(goName, names) {
+		goName += "_"
+	}
+	names.all[goName] = true
+	return goName
+}
+
+// collectNames находит имена, которые объявляет узел AST
+func collectNames(v reflect.Value, names *programNames) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		switch node := v.Interface().(type) {
+		case *ast.Identifier:
+			names.all[node.Value] = true
+			return
+		case *ast.ImportStatement:
+			names.packages[path.Base(node.Path)] = true
+		case *ast.AssignmentStatement:
+			collectTargets(node.Name, names)
+		case *ast.LetStatement:
+			names.variables[node.Name.Value] = true
+			if _, ok := node.Value.(*ast.FunctionLiteral); ok {
+				names.functions[node.Name.Value] = true
+			}
+		case *ast.FunctionStatement:
+			names.variables[node.Name.Value] = true
+			names.functions[node.Name.Value] = true
+		case *ast.ClassStatement:
+			names.variables[node.Name.Value] = true
+			names.functions[node.Name.Value] = true
+			names.types[node.Name.Value] = true
+		case *ast.InterfaceStatement:
+			names.variables[node.Name.Value] = true
+			names.types[node.Name.Value] = true
+		case *ast.ClassField:
+			names.members[node.Name.Value] = true
+		case *ast.MethodStatement:
+			names.members[node.Name.Value] = true
+			addNames(names.variables, node.Parameters)
+		case *ast.MethodSignature:
+			names.members[node.Name.Value] = true
+			addNames(names.variables, node.Parameters)
+		case *ast.FunctionLiteral:
+			addNames(names.variables, node.Parameters)
+		case *ast.LambdaExpression:
+			addNames(names.variables, node.Parameters)
+		case *ast.ComprehensionClause:
+			addNames(names.variables, node.Targets)
+		case *ast.ForStatement:
+			names.variables[node.Iterator.Value] = true
+		case *ast.CapturePattern:
+			names.variables[node.Name.Value] = true
+		case *ast.StarPattern:
+			if node.Name != nil {
+				names.variables[node.Name.Value] = true
+			}
+		case *ast.ExceptClause:
+			if node.Name != nil {
+				names.variables[node.Name.Value] = true
+			}
+		case *ast.WithStatement:
+			if node.Name != nil {
+				names.variables[node.Name.Value] = true
+			}
+		}
+		collectNames(v.Elem(), names)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			collectNames(v.Field(i), names)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			collectNames(v.Index(i), names)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			collectNames(v.MapIndex(key), names)
+		}
+	}
+}
+
+// collectTargets находит переменные, которым присваивает значение левая часть
+// присваивания: x, a, b и *rest
+func collectTargets(target ast.Expression, names *programNames) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		names.variables[target.Value] = true
+	case *ast.TupleLiteral:
+		for _, element := range target.Elements {
+			collectTargets(element, names)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range target.Elements {
+			collectTargets(element, names)
+		}
+	case *ast.StarredExpression:
+		collectTargets(target.Value, names)
+	}
+}
+
+func addNames(set map[string]bool, idents []*ast.Identifier) {
+	for _, ident := range idents {
+		set[ident.Value] = true
+	}
+}
+
+// nameKind — что обозначает имя в данном месте AST
+type nameKind int
+
+const (
+	nameVariable nameKind = iota // переменная, функция или класс
+	nameType                     // тип в аннотации
+	nameMember                   // поле или метод: после точки, в именованном аргументе, в объявлении
+)
+
+// annotationFields — поля узлов AST, в которых записаны типы
+var annotationFields = map[string]bool{"ParameterTypes": true, "ReturnType": true, "ReturnTypes": true, "Type": true, "Base": true}
+
+// nameRenames — замены имён программы. Имя переменной и поле с тем же именем
+// заменяются независимо: поле len остаётся len, даже если переменная len стала len_
+type nameRenames struct {
+	goNames   map[string]string
+	variables map[string]bool
+	members   map[string]bool
+	types     map[string]bool
+	functions map[string]bool
+}
+
+// rename заменяет имя, если в месте kind оно занято в Go
+func (r *nameRenames) rename(ident *ast.Identifier, kind nameKind) {
+	switch {
+	case kind == nameVariable && r.variables[ident.Value],
+		kind == nameType && r.variables[ident.Value] && r.types[ident.Value],
+		kind == nameMember && r.members[ident.Value]:
+		ident.Value = r.goNames[ident.Value]
+	}
+}
+
+// mangleNode заменяет имена в узле AST. Имя в аннотации заменяется, только если
+// это класс или интерфейс программы. Вызов встроенной функции Gopy остаётся
+// вызовом встроенной функции, если программа не объявляет функцию с тем же именем
+func mangleNode(v reflect.Value, kind nameKind, r *nameRenames) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		switch node := v.Interface().(type) {
+		case *ast.Identifier:
+			r.rename(node, kind)
+			return
+		case *ast.DotExpression:
+			mangleNode(reflect.ValueOf(&node.Left).Elem(), nameVariable, r)
+			if node.Right != nil {
+				r.rename(node.Right, nameMember)
+			}
+			return
+		case *ast.CallExpression:
+			if ident, ok := node.Function.(*ast.Identifier); !ok || !isBuiltinFunction(ident.Value) || r.functions[ident.Value] {
+				mangleNode(reflect.ValueOf(&node.Function).Elem(), nameVariable, r)
+			}
+			mangleNode(reflect.ValueOf(&node.Arguments).Elem(), nameVariable, r)
+			return
+		case *ast.ClassField, *ast.MethodStatement, *ast.MethodSignature, *ast.KeywordArgument, *ast.KeywordPattern:
+			// Name этих узлов — имя поля или метода
+			elem := reflect.ValueOf(node).Elem()
+			for i := 0; i < elem.NumField(); i++ {
+				if elem.Type().Field(i).Name == "Name" {
+					r.rename(elem.Field(i).Interface().(*ast.Identifier), nameMember)
+					continue
+				}
+				mangleField(elem, i, r)
+			}
+			return
+		}
+		mangleNode(v.Elem(), kind, r)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			mangleField(v, i, r)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			mangleNode(v.Index(i), kind, r)
+		}
+	case reflect.Map:
+		// Ключи ParameterTypes — имена параметров, они заменяются вместе с параметрами
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key)
+			mangleNode(value, kind, r)
+			if key.Kind() == reflect.String && r.variables[key.String()] {
+				v.SetMapIndex(key, reflect.Value{})
+				v.SetMapIndex(reflect.ValueOf(r.goNames[key.String()]), value)
+			}
+		}
+	}
+}
+
+// isBuiltinFunction сообщает, что name — встроенная функция Gopy. range
+// генерируется отдельно от остальных встроенных функций
+func isBuiltinFunction(name string) bool {
+	return builtins[name] != nil || name == "range"
+}
+
+// mangleField заменяет имена в i-м поле узла AST
+func mangleField(node reflect.Value, i int, r *nameRenames) {
+	kind := nameVariable
+	if annotationFields[node.Type().Field(i).Name] {
+		kind = nameType
+	}
+	mangleNode(node.Field(i), kind, r)
+}
+
+// identifierPattern находит имена в тексте сообщения
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// sourceMessage заменяет в сообщении имена Go на имена, как они записаны в Gopy
+func (g *Generator) sourceMessage(message string) string {
+	return identifierPattern.ReplaceAllStringFunc(message, g.sourceName)
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"gopy/ast"
 	"gopy/lexer"
 	"gopy/token"
@@ -10,14 +11,18 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	CONDITIONAL // a if c else b
+	ANDOR       // and or
 	EQUALS      // ==
 	LESSGREATER // > or <
+	BITOR       // |
+	BITXOR      // ^
+	BITAND      // &
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
 	INDEX       // array[index]
-	ANDOR       // and or
 )
 
 var precedences = map[token.TokenType]int{
@@ -25,14 +30,24 @@ var precedences = map[token.TokenType]int{
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
+	token.IN:       LESSGREATER,
+	token.NOT:      LESSGREATER,
+	token.PIPE:     BITOR,
+	token.CARET:    BITXOR,
+	token.AMPERSAND: BITAND,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 	token.AND:      ANDOR,
 	token.OR:       ANDOR,
+	token.IF:       CONDITIONAL,
+	token.ORELSE:   CONDITIONAL,
 }
 
 
@@ -71,7 +86,11 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.DEF, p.parseFunctionLiteral)
 	p.registerPrefix(token.NOT, p.parsePrefixExpression)
+	p.registerPrefix(token.LARROW, p.parseReceiveExpression)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.LAMBDA, p.parseLambdaExpression)
+	p.registerPrefix(token.ASTERISK, p.parseStarredExpression)
 	// Удаляю регистрацию prefixParseFn для CLASS
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -83,11 +102,20 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.NOT, p.parseNotInExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.DOT, p.parseDotExpression)
 	p.registerInfix(token.AND, p.parseInfixExpression)
 	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.IF, p.parseConditionalExpression)
+	p.registerInfix(token.ORELSE, p.parseOrElseExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -147,7 +175,15 @@ func (p *Parser) ParseProgram() *ast.Program {
 }
 
 func (p *Parser) parseStatement() ast.Statement {
-	
+	// def <name>(...) — объявление функции, def(...) — функция-литерал
+	if p.curTokenIs(token.DEF) && p.peekTokenIs(token.IDENT) {
+		return p.parseFunctionStatement()
+	}
+	// print x, y — форма инструкции, то же, что print(x, y)
+	if p.curTokenIs(token.PRINT) && !p.peekTokenIs(token.LPAREN) {
+		return p.parsePrintStatement()
+	}
+
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
@@ -155,10 +191,30 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseReturnStatement()
 	case token.CLASS:
 		return p.parseClassStatement()
+	case token.INTERFACE:
+		return p.parseInterfaceStatement()
 	case token.FOR:
 		return p.parseForStatement()
+	case token.MATCH:
+		return p.parseMatchStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.RAISE:
+		return p.parseRaiseStatement()
+	case token.ASSERT:
+		return p.parseAssertStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.GO:
+		return p.parseGoStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
+	case token.SELECT:
+		return p.parseSelectStatement()
+	case token.WITH:
+		return p.parseWithStatement()
 	default:
-		left := p.parseExpression(LOWEST)
+		left := p.parseExpressionTuple(p.parseExpression(LOWEST))
 		// Если после выражения идёт =, это присваивание (в том числе для DotExpression)
 		if p.curTokenIs(token.ASSIGN) || p.peekTokenIs(token.ASSIGN) {
 			if p.curTokenIs(token.ASSIGN) {
@@ -168,18 +224,42 @@ func (p *Parser) parseStatement() ast.Statement {
 				p.nextToken() // =
 			}
 			p.nextToken() // value
-			value := p.parseExpression(LOWEST)
+			value := p.parseExpressionTuple(p.parseExpression(LOWEST))
 			return &ast.AssignmentStatement{
 				Token: p.curToken,
 				Name: left,
 				Value: value,
 			}
 		}
+		// ch <- v — отправка в канал
+		if p.peekTokenIs(token.LARROW) {
+			p.nextToken()
+			stmt := &ast.SendStatement{Token: p.curToken, Channel: left}
+			p.nextToken()
+			stmt.Value = p.parseExpression(LOWEST)
+			return stmt
+		}
 		es := &ast.ExpressionStatement{Token: p.curToken, Expression: left}
 		return es
 	}
 }
 
+// parsePrintStatement разбирает print x, y, sep=", " как вызов print
+func (p *Parser) parsePrintStatement() ast.Statement {
+	function := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	call := &ast.CallExpression{Token: p.curToken, Function: function, Arguments: []ast.Expression{}}
+	if !p.peekTokenIs(token.NEWLINE) && !p.peekTokenIs(token.EOF) && !p.peekTokenIs(token.DEDENT) {
+		p.nextToken()
+		call.Arguments = append(call.Arguments, p.parseCallArgument())
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			call.Arguments = append(call.Arguments, p.parseCallArgument())
+		}
+	}
+	return &ast.ExpressionStatement{Token: function.Token, Expression: call}
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
@@ -205,12 +285,75 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	p.nextToken()
 
-	stmt.ReturnValue = p.parseExpression(LOWEST)
+	stmt.ReturnValue = p.parseExpressionTuple(p.parseExpression(LOWEST))
+
+	return stmt
+}
+
+
+// parseRaiseStatement разбирает raise <выражение> и raise без значения
+func (p *Parser) parseRaiseStatement() *ast.RaiseStatement {
+	stmt := &ast.RaiseStatement{Token: p.curToken}
+	if p.peekTokenIs(token.NEWLINE) || p.peekTokenIs(token.EOF) || p.peekTokenIs(token.DEDENT) {
+		return stmt
+	}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	return stmt
+}
+
+// parseAssertStatement разбирает assert <условие> [, <сообщение>]
+func (p *Parser) parseAssertStatement() *ast.AssertStatement {
+	stmt := &ast.AssertStatement{Token: p.curToken}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Message = p.parseExpression(LOWEST)
+	}
+	return stmt
+}
 
+// parseImportStatement разбирает import <имя>[/<имя>...]
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Path = p.curToken.Literal
+	for p.peekTokenIs(token.SLASH) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Path += "/" + p.curToken.Literal
+	}
 	return stmt
 }
 
+// parseExpressionTuple собирает выражения через запятую в кортеж: a, b = b, a.
+// Одиночное выражение возвращается как есть
+func (p *Parser) parseExpressionTuple(first ast.Expression) ast.Expression {
+	if !p.peekTokenIs(token.COMMA) {
+		return first
+	}
+	tuple := &ast.TupleLiteral{Token: p.curToken, Elements: []ast.Expression{first}}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+	}
+	return tuple
+}
 
+// parseStarredExpression разбирает цель распаковки *rest
+func (p *Parser) parseStarredExpression() ast.Expression {
+	expr := &ast.StarredExpression{Token: p.curToken}
+	p.nextToken()
+	expr.Value = p.parseExpression(PREFIX)
+	return expr
+}
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
@@ -239,7 +382,9 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		return nil
 	}
 
-	for !p.peekTokenIs(token.NEWLINE) && precedence < p.peekPrecedence() {
+	// Выражение с блоком (if) заканчивается вместе с блоком на DEDENT:
+	// следующий if — уже новая инструкция, а не a if cond else b
+	for !p.peekTokenIs(token.NEWLINE) && !p.curTokenIs(token.DEDENT) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -284,11 +429,152 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
-	array := &ast.ArrayLiteral{Token: p.curToken}
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array := &ast.ArrayLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+	if p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		return array
+	}
+
+	p.nextToken()
+	first := p.parseExpression(LOWEST)
+	// [x * 2 for x in xs] — включение списка
+	if p.peekTokenIs(token.FOR) {
+		return p.parseComprehension(array.Token, ast.ListComprehension, nil, first, token.RBRACKET)
+	}
+	array.Elements = append(array.Elements, first)
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		array.Elements = append(array.Elements, p.parseExpression(LOWEST))
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
 	return array
 }
 
+// parseComprehension разбирает части for ... in ... if ... включения после его элемента
+// и закрывающую скобку end. Генераторное выражение в аргументе вызова не имеет
+// собственных скобок, тогда end пустой
+func (p *Parser) parseComprehension(tok token.Token, kind string, key ast.Expression, element ast.Expression, end token.TokenType) ast.Expression {
+	comp := &ast.ComprehensionExpression{Token: tok, Kind: kind, Key: key, Element: element}
+
+	for p.peekTokenIs(token.FOR) {
+		p.nextToken()
+		clause := &ast.ComprehensionClause{Token: p.curToken}
+		for {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			clause.Targets = append(clause.Targets, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.IN) {
+			return nil
+		}
+		p.nextToken()
+		// if после источника — условие включения, а не условное выражение
+		clause.Iterable = p.parseExpression(CONDITIONAL)
+
+		for p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			clause.Conditions = append(clause.Conditions, p.parseExpression(CONDITIONAL))
+		}
+		comp.Clauses = append(comp.Clauses, clause)
+	}
+
+	if end != "" && !p.expectPeek(end) {
+		return nil
+	}
+	return comp
+}
+
+// parseHashLiteral разбирает литерал словаря {key: value, ...}. Литерал без
+// двоеточия после первого элемента — множество {a, b}; {} — пустой словарь
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+		// {x for x in xs} — включение множества
+		if len(hash.Keys) == 0 && p.peekTokenIs(token.FOR) {
+			return p.parseComprehension(hash.Token, ast.SetComprehension, nil, key, token.RBRACE)
+		}
+		if len(hash.Keys) == 0 && (p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.RBRACE)) {
+			return p.parseSetLiteral(hash.Token, key)
+		}
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		// {k: v for k in ks} — включение словаря
+		if len(hash.Keys) == 0 && p.peekTokenIs(token.FOR) {
+			return p.parseComprehension(hash.Token, ast.DictComprehension, key, value, token.RBRACE)
+		}
+
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+// parseSetLiteral разбирает оставшиеся элементы литерала множества {first, ...}
+func (p *Parser) parseSetLiteral(tok token.Token, first ast.Expression) ast.Expression {
+	set := &ast.SetLiteral{Token: tok, Elements: []ast.Expression{first}}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RBRACE) {
+			break
+		}
+		p.nextToken()
+		set.Elements = append(set.Elements, p.parseExpression(LOWEST))
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return set
+}
+
+// parseLambdaExpression разбирает lambda x, y: <выражение>
+func (p *Parser) parseLambdaExpression() ast.Expression {
+	lambda := &ast.LambdaExpression{Token: p.curToken}
+	lambda.Parameters = []*ast.Identifier{}
+
+	for p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lambda.Parameters = append(lambda.Parameters, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+
+	lambda.Body = p.parseExpression(LOWEST)
+
+	return lambda
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
@@ -296,7 +582,8 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	lit.Parameters, lit.ParameterTypes = p.parseFunctionParameters()
+	lit.ReturnType, lit.ReturnTypes = p.parseResultTypes()
 
 	if !p.expectPeek(token.NEWLINE) {
 		return nil
@@ -310,31 +597,123 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+// parseFunctionStatement разбирает def <name>(...) ...
+func (p *Parser) parseFunctionStatement() ast.Statement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	fn, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok || fn == nil {
+		return nil
+	}
+	fn.Token = stmt.Token
+	stmt.Function = fn
+
+	return stmt
+}
+
+// parseFunctionParameters разбирает список параметров вместе с необязательными
+// аннотациями типов: (a, b: int)
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, map[string]*ast.Identifier) {
 	identifiers := []*ast.Identifier{}
+	types := make(map[string]*ast.Identifier)
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, types
 	}
 
 	p.nextToken()
 
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
+	p.parseParameterType(ident, types)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, ident)
+		p.parseParameterType(ident, types)
 	}
 
 	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	return identifiers, types
+}
+
+// parseParameterType разбирает необязательную аннотацию ": <type>" после параметра
+func (p *Parser) parseParameterType(param *ast.Identifier, types map[string]*ast.Identifier) {
+	if !p.peekTokenIs(token.COLON) {
+		return
+	}
+	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return
+	}
+	types[param.Value] = p.parseTypeName()
+}
+
+// parseTypeName разбирает имя типа в текущем токене. Тип из модуля Gopy
+// записывается через точку: utils.Point
+func (p *Parser) parseTypeName() *ast.Identifier {
+	typ := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		typ.Value += "." + p.curToken.Literal
+	}
+	return typ
+}
+
+// parseReturnType разбирает необязательный тип результата "-> <type>"
+func (p *Parser) parseReturnType() *ast.Identifier {
+	if !p.peekTokenIs(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
+	return p.parseTypeName()
+}
+
+// parseResultTypes разбирает тип результата функции: "-> int" или "-> (int, str)"
+// для функции, возвращающей несколько значений
+func (p *Parser) parseResultTypes() (*ast.Identifier, []*ast.Identifier) {
+	if !p.peekTokenIs(token.ARROW) {
+		return nil, nil
+	}
+	p.nextToken()
+	if !p.peekTokenIs(token.LPAREN) {
+		if !p.expectPeek(token.IDENT) {
+			return nil, nil
+		}
+		return p.parseTypeName(), nil
+	}
+	p.nextToken()
 
-	return identifiers
+	types := []*ast.Identifier{}
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil, nil
+		}
+		types = append(types, p.parseTypeName())
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+	return nil, types
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -343,7 +722,21 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 		Operator: p.curToken.Literal,
 	}
 	p.nextToken()
-	exp.Right = p.parseExpression(PREFIX)
+	precedence := PREFIX
+	if exp.Operator == "not" {
+		// not связывает слабее сравнений, но сильнее and и or:
+		// not x in xs — not (x in xs), not a and b — (not a) and b
+		precedence = ANDOR
+	}
+	exp.Right = p.parseExpression(precedence)
+	return exp
+}
+
+// parseReceiveExpression разбирает получение из канала: <-ch
+func (p *Parser) parseReceiveExpression() ast.Expression {
+	exp := &ast.ReceiveExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Channel = p.parseExpression(PREFIX)
 	return exp
 }
 
@@ -361,10 +754,45 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseNotInExpression разбирает x not in xs
+func (p *Parser) parseNotInExpression(left ast.Expression) ast.Expression {
+	exp := &ast.InfixExpression{Token: p.curToken, Operator: "not in", Left: left}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+	precedence := p.curPrecedence()
+	p.nextToken()
+	exp.Right = p.parseExpression(precedence)
+	return exp
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	tok := p.curToken
+	// () — пустой кортеж
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return &ast.TupleLiteral{Token: tok, Elements: []ast.Expression{}}
+	}
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
+	// (x for x in xs) — генераторное выражение
+	if p.peekTokenIs(token.FOR) {
+		return p.parseComprehension(tok, ast.GeneratorComprehension, nil, exp, token.RPAREN)
+	}
+	// (a, b) и (a,) — кортеж
+	if p.peekTokenIs(token.COMMA) {
+		tuple := &ast.TupleLiteral{Token: tok, Elements: []ast.Expression{exp}}
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			if p.peekTokenIs(token.RPAREN) {
+				break
+			}
+			p.nextToken()
+			tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+		}
+		exp = tuple
+	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -389,12 +817,12 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 
 	p.nextToken()
 
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, p.parseCallArgument())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, p.parseCallArgument())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -404,14 +832,61 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 	return args
 }
 
+// parseCallArgument разбирает позиционный аргумент или именованный name=value
+func (p *Parser) parseCallArgument() ast.Expression {
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+		arg := &ast.KeywordArgument{Token: p.curToken}
+		arg.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.nextToken() // =
+		p.nextToken()
+		arg.Value = p.parseExpression(LOWEST)
+		return arg
+	}
+	tok := p.curToken
+	arg := p.parseExpression(LOWEST)
+	// sum(x for x in xs) — генераторное выражение без собственных скобок
+	if p.peekTokenIs(token.FOR) {
+		return p.parseComprehension(tok, ast.GeneratorComprehension, nil, arg, "")
+	}
+	return arg
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+
+	var start ast.Expression
+	if !p.curTokenIs(token.COLON) {
+		start = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: tok, Left: left, Index: start}
+		}
+		p.nextToken()
+	}
+
+	// Срез: xs[start:stop:step]
+	slice := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+	slice.Stop = p.parseSliceBound()
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		slice.Step = p.parseSliceBound()
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
-	return exp
+	return slice
+}
+
+// parseSliceBound разбирает необязательную границу среза после ':'
+func (p *Parser) parseSliceBound() ast.Expression {
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+		return nil
+	}
+	p.nextToken()
+	return p.parseExpression(LOWEST)
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
@@ -444,6 +919,23 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseConditionalExpression разбирает a if cond else b. Условие не может само быть
+// условным выражением без скобок, а ветка else может: a if x else b if y else c
+func (p *Parser) parseConditionalExpression(consequence ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.curToken, Consequence: consequence}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(CONDITIONAL)
+
+	if !p.expectPeek(token.ELSE) {
+		return nil
+	}
+	p.nextToken()
+	expression.Alternative = p.parseExpression(LOWEST)
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
@@ -524,11 +1016,24 @@ func (p *Parser) parseClassStatement() ast.Statement {
 	}
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	// class ParseError(ValueError) — класс ошибки
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Base = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
 	// Разбор полей класса (идентификаторы до NEWLINE)
-	stmt.Fields = []*ast.Identifier{}
+	stmt.Fields = []*ast.ClassField{}
 	for p.peekTokenIs(token.IDENT) {
 		p.nextToken()
-		stmt.Fields = append(stmt.Fields, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		stmt.Fields = append(stmt.Fields, &ast.ClassField{Token: p.curToken, Name: name})
 	}
 
 	if !p.expectPeek(token.NEWLINE) {
@@ -552,12 +1057,47 @@ func (p *Parser) parseClassStatement() ast.Statement {
 			p.nextToken()
 			continue
 		}
+		if p.curTokenIs(token.IDENT) {
+			field := p.parseClassField()
+			if field != nil {
+				stmt.Fields = append(stmt.Fields, field)
+			}
+			p.nextToken()
+			continue
+		}
 		p.nextToken()
 	}
 	
 	return stmt
 }
 
+// parseClassField разбирает поле в теле класса: name, name = value, name: type = value
+func (p *Parser) parseClassField() *ast.ClassField {
+	field := &ast.ClassField{Token: p.curToken}
+	field.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field.Type = p.parseTypeName()
+	}
+
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		field.Default = p.parseExpression(LOWEST)
+	}
+
+	if !p.peekTokenIs(token.NEWLINE) && !p.peekTokenIs(token.DEDENT) && !p.peekTokenIs(token.EOF) {
+		p.peekError(token.NEWLINE)
+		return nil
+	}
+
+	return field
+}
+
 // parseMethodStatement разбирает def <name>(...) ...
 func (p *Parser) parseMethodStatement() *ast.MethodStatement {
 	ms := &ast.MethodStatement{Token: p.curToken}
@@ -568,7 +1108,8 @@ func (p *Parser) parseMethodStatement() *ast.MethodStatement {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	ms.Parameters = p.parseFunctionParameters()
+	ms.Parameters, ms.ParameterTypes = p.parseFunctionParameters()
+	ms.ReturnType, ms.ReturnTypes = p.parseResultTypes()
 	if !p.expectPeek(token.NEWLINE) {
 		return nil
 	}
@@ -576,15 +1117,72 @@ func (p *Parser) parseMethodStatement() *ast.MethodStatement {
 		return nil
 	}
 	ms.Body = p.parseBlockStatement()
-	
-func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
-	// curToken: .
+	return ms
+}
+
+// parseInterfaceStatement разбирает interface <name> с сигнатурами методов в теле
+func (p *Parser) parseInterfaceStatement() ast.Statement {
+	stmt := &ast.InterfaceStatement{Token: p.curToken}
+
 	if !p.expectPeek(token.IDENT) {
-		p.errors = append(p.errors, "expected identifier after dot")
 		return nil
 	}
-	return &ast.DotExpression{
-		Token: p.curToken,
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+
+	stmt.Methods = []*ast.MethodSignature{}
+	for !p.curTokenIs(token.DEDENT) && !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.DEF) {
+			method := p.parseMethodSignature()
+			if method != nil {
+				stmt.Methods = append(stmt.Methods, method)
+			}
+		}
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseMethodSignature разбирает def <name>(...) [-> <type>] без тела
+func (p *Parser) parseMethodSignature() *ast.MethodSignature {
+	ms := &ast.MethodSignature{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	ms.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	ms.Parameters, ms.ParameterTypes = p.parseFunctionParameters()
+	ms.ReturnType = p.parseReturnType()
+
+	// self в сигнатуре интерфейса необязателен
+	if len(ms.Parameters) > 0 && ms.Parameters[0].Value == "self" {
+		ms.Parameters = ms.Parameters[1:]
+	}
+
+	if !p.peekTokenIs(token.NEWLINE) && !p.peekTokenIs(token.DEDENT) && !p.peekTokenIs(token.EOF) {
+		p.peekError(token.NEWLINE)
+		return nil
+	}
+	return ms
+}
+
+func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
+	// curToken: .
+	if !p.expectPeek(token.IDENT) {
+		p.errors = append(p.errors, "expected identifier after dot")
+		return nil
+	}
+	return &ast.DotExpression{
+		Token: p.curToken,
 		Left:  left,
 		Right: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
 	}
@@ -616,4 +1214,399 @@ func (p *Parser) parseForStatement() ast.Statement {
 	stmt.Body = p.parseBlockStatement()
 
 	return stmt
-}
\ No newline at end of file
+}
+
+// parseGoStatement разбирает go f(x)
+func (p *Parser) parseGoStatement() ast.Statement {
+	stmt := &ast.GoStatement{Token: p.curToken}
+	p.nextToken()
+	call, ok := p.parseExpression(LOWEST).(*ast.CallExpression)
+	if !ok {
+		p.errors = append(p.errors, "expected function call after go")
+		return nil
+	}
+	stmt.Call = call
+	return stmt
+}
+
+// parseDeferStatement разбирает defer f(x)
+func (p *Parser) parseDeferStatement() ast.Statement {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+	p.nextToken()
+	call, ok := p.parseExpression(LOWEST).(*ast.CallExpression)
+	if !ok {
+		p.errors = append(p.errors, "expected function call after defer")
+		return nil
+	}
+	stmt.Call = call
+	return stmt
+}
+
+// parseWithStatement разбирает with <выражение> [as <имя>] с блоком
+func (p *Parser) parseWithStatement() ast.Statement {
+	stmt := &ast.WithStatement{Token: p.curToken}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	return stmt
+}
+
+// parseSelectStatement разбирает select с ветками case и необязательной default
+func (p *Parser) parseSelectStatement() ast.Statement {
+	stmt := &ast.SelectStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+	p.nextToken()
+
+	for !p.curTokenIs(token.DEDENT) && !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.NEWLINE) {
+			p.nextToken()
+			continue
+		}
+		c := p.parseSelectCase()
+		if c == nil {
+			return nil
+		}
+		stmt.Cases = append(stmt.Cases, c)
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseSelectCase() *ast.SelectCase {
+	c := &ast.SelectCase{Token: p.curToken}
+	switch {
+	case p.curTokenIs(token.IDENT) && p.curToken.Literal == "default":
+	case p.curTokenIs(token.CASE):
+		p.nextToken()
+		c.Comm = p.parseStatement()
+		if !isChannelOperation(c.Comm) {
+			p.errors = append(p.errors, "expected channel send or receive in select case")
+			return nil
+		}
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("expected case or default in select body, got %s instead", p.curToken.Type))
+		return nil
+	}
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+	c.Body = p.parseBlockStatement()
+	return c
+}
+
+// isChannelOperation сообщает, что инструкция — отправка или получение из канала
+func isChannelOperation(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.SendStatement:
+		return true
+	case *ast.ExpressionStatement:
+		_, ok := stmt.Expression.(*ast.ReceiveExpression)
+		return ok
+	case *ast.AssignmentStatement:
+		_, ok := stmt.Value.(*ast.ReceiveExpression)
+		return ok
+	}
+	return false
+}
+
+// parseOrElseExpression разбирает value or else default
+func (p *Parser) parseOrElseExpression(value ast.Expression) ast.Expression {
+	expression := &ast.OrElseExpression{Token: p.curToken, Value: value}
+	p.nextToken()
+	expression.Default = p.parseExpression(CONDITIONAL)
+	return expression
+}
+
+// parseTryStatement разбирает try, ветки except и finally
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	for p.peekTokenIs(token.EXCEPT) {
+		p.nextToken()
+		clause := p.parseExceptClause()
+		if clause == nil {
+			return nil
+		}
+		stmt.Handlers = append(stmt.Handlers, clause)
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.NEWLINE) {
+			return nil
+		}
+		if !p.expectPeek(token.INDENT) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if len(stmt.Handlers) == 0 && stmt.Finally == nil {
+		p.errors = append(p.errors, "expected except or finally after try block")
+		return nil
+	}
+	return stmt
+}
+
+// parseExceptClause разбирает except, except ValueError, except (KeyError, IndexError) as e
+func (p *Parser) parseExceptClause() *ast.ExceptClause {
+	clause := &ast.ExceptClause{Token: p.curToken}
+
+	if !p.peekTokenIs(token.NEWLINE) && !p.peekTokenIs(token.AS) {
+		p.nextToken()
+		grouped := p.curTokenIs(token.LPAREN)
+		if grouped {
+			p.nextToken()
+		}
+		clause.Kinds = append(clause.Kinds, p.parseExpression(LOWEST))
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			clause.Kinds = append(clause.Kinds, p.parseExpression(LOWEST))
+		}
+		if grouped && !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		clause.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+	clause.Body = p.parseBlockStatement()
+	return clause
+}
+
+// parseMatchStatement разбирает match <subject> и вложенные ветки case
+func (p *Parser) parseMatchStatement() ast.Statement {
+	stmt := &ast.MatchStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+	p.nextToken()
+
+	for !p.curTokenIs(token.DEDENT) && !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.NEWLINE) {
+			p.nextToken()
+			continue
+		}
+		if !p.curTokenIs(token.CASE) {
+			p.errors = append(p.errors, fmt.Sprintf("expected case in match body, got %s instead", p.curToken.Type))
+			return nil
+		}
+		c := p.parseMatchCase()
+		if c == nil {
+			return nil
+		}
+		stmt.Cases = append(stmt.Cases, c)
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseMatchCase() *ast.MatchCase {
+	c := &ast.MatchCase{Token: p.curToken}
+
+	p.nextToken()
+	c.Pattern = p.parsePattern()
+	if c.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		c.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.NEWLINE) {
+		return nil
+	}
+	if !p.expectPeek(token.INDENT) {
+		return nil
+	}
+	c.Body = p.parseBlockStatement()
+	return c
+}
+
+// parsePattern разбирает образец, в том числе альтернативы через |
+func (p *Parser) parsePattern() ast.Pattern {
+	first := p.parseClosedPattern()
+	if first == nil || !p.peekTokenIs(token.PIPE) {
+		return first
+	}
+	or := &ast.OrPattern{Token: p.peekToken, Alternatives: []ast.Pattern{first}}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken()
+		p.nextToken()
+		alternative := p.parseClosedPattern()
+		if alternative == nil {
+			return nil
+		}
+		or.Alternatives = append(or.Alternatives, alternative)
+	}
+	return or
+}
+
+func (p *Parser) parseClosedPattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.INT, token.STRING, token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Token: p.curToken, Value: p.prefixParseFns[p.curToken.Type]()}
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) {
+			p.errors = append(p.errors, fmt.Sprintf("expected number after - in pattern, got %s instead", p.peekToken.Type))
+			return nil
+		}
+		return &ast.LiteralPattern{Token: p.curToken, Value: p.parsePrefixExpression()}
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		if p.peekTokenIs(token.LPAREN) {
+			return p.parseClassPattern()
+		}
+		return &ast.CapturePattern{Token: p.curToken, Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+	case token.LBRACKET:
+		return p.parseListPattern()
+	case token.LPAREN:
+		p.nextToken()
+		pattern := p.parsePattern()
+		if pattern == nil || !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		return pattern
+	}
+	p.errors = append(p.errors, fmt.Sprintf("unexpected %s in pattern", p.curToken.Type))
+	return nil
+}
+
+// parseClassPattern разбирает User(x, name=n): сначала позиционные образцы, затем именованные
+func (p *Parser) parseClassPattern() ast.Pattern {
+	pattern := &ast.ClassPattern{Token: p.curToken, Class: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+	p.nextToken() // (
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return pattern
+	}
+	for {
+		p.nextToken()
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+			keyword := &ast.KeywordPattern{Token: p.curToken, Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+			p.nextToken()
+			p.nextToken()
+			keyword.Pattern = p.parsePattern()
+			if keyword.Pattern == nil {
+				return nil
+			}
+			pattern.Keywords = append(pattern.Keywords, keyword)
+		} else {
+			if len(pattern.Keywords) > 0 {
+				p.errors = append(p.errors, "positional pattern follows keyword pattern")
+				return nil
+			}
+			argument := p.parsePattern()
+			if argument == nil {
+				return nil
+			}
+			pattern.Arguments = append(pattern.Arguments, argument)
+		}
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return pattern
+}
+
+// parseListPattern разбирает [a, b, *rest]; элемент со звёздочкой допускается один
+func (p *Parser) parseListPattern() ast.Pattern {
+	pattern := &ast.ListPattern{Token: p.curToken}
+	if p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		return pattern
+	}
+	starred := false
+	for {
+		p.nextToken()
+		if p.curTokenIs(token.ASTERISK) {
+			if starred {
+				p.errors = append(p.errors, "multiple starred names in list pattern")
+				return nil
+			}
+			starred = true
+			star := &ast.StarPattern{Token: p.curToken}
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			star.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			pattern.Elements = append(pattern.Elements, star)
+		} else {
+			element := p.parsePattern()
+			if element == nil {
+				return nil
+			}
+			pattern.Elements = append(pattern.Elements, element)
+		}
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return pattern
+}
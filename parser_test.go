@@ -306,6 +306,931 @@ func TestDotExpressionParsing(t *testing.T) {
 	}
 }
 
+func TestClassFieldParsing(t *testing.T) {
+	input := `
+class User
+    name = "anon"
+    age: int = 0
+    email
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	classStmt, ok := program.Statements[0].(*ast.ClassStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ClassStatement. got=%T", program.Statements[0])
+	}
+
+	tests := []struct {
+		name         string
+		typ          string
+		defaultValue string
+	}{
+		{"name", "", "anon"},
+		{"age", "int", "0"},
+		{"email", "", ""},
+	}
+
+	if len(classStmt.Fields) != len(tests) {
+		t.Fatalf("class should have %d fields, got=%d", len(tests), len(classStmt.Fields))
+	}
+	for i, tt := range tests {
+		field := classStmt.Fields[i]
+		if field.Name.Value != tt.name {
+			t.Errorf("fields[%d] name wrong. want=%s, got=%s", i, tt.name, field.Name.Value)
+		}
+		typ := ""
+		if field.Type != nil {
+			typ = field.Type.Value
+		}
+		if typ != tt.typ {
+			t.Errorf("fields[%d] type wrong. want=%q, got=%q", i, tt.typ, typ)
+		}
+		defaultValue := ""
+		if field.Default != nil {
+			defaultValue = field.Default.String()
+		}
+		if defaultValue != tt.defaultValue {
+			t.Errorf("fields[%d] default wrong. want=%q, got=%q", i, tt.defaultValue, defaultValue)
+		}
+	}
+}
+
+func TestKeywordArgumentParsing(t *testing.T) {
+	input := `User("Bob", age=25)`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if len(call.Arguments) != 2 {
+		t.Fatalf("wrong number of arguments. want=2, got=%d", len(call.Arguments))
+	}
+	kw, ok := call.Arguments[1].(*ast.KeywordArgument)
+	if !ok {
+		t.Fatalf("second argument is not ast.KeywordArgument. got=%T", call.Arguments[1])
+	}
+	if kw.Name.Value != "age" || kw.Value.String() != "25" {
+		t.Errorf("keyword argument wrong. want age=25, got=%s", kw.String())
+	}
+}
+
+func TestInterfaceStatementParsing(t *testing.T) {
+	input := `
+interface Shape
+    def area() -> float
+    def scale(self, factor: float)
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	iface, ok := program.Statements[0].(*ast.InterfaceStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.InterfaceStatement. got=%T", program.Statements[0])
+	}
+	if iface.Name.Value != "Shape" {
+		t.Errorf("interface name wrong. want=Shape, got=%s", iface.Name.Value)
+	}
+	if len(iface.Methods) != 2 {
+		t.Fatalf("interface should have 2 methods, got=%d", len(iface.Methods))
+	}
+
+	tests := []string{
+		"def area() -> float",
+		"def scale(factor: float)",
+	}
+	for i, expected := range tests {
+		if iface.Methods[i].String() != expected {
+			t.Errorf("methods[%d] wrong. want=%q, got=%q", i, expected, iface.Methods[i].String())
+		}
+	}
+}
+
+func TestTypedFunctionParameters(t *testing.T) {
+	input := `def(s: Shape, n) -> int
+	return n
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(function.Parameters))
+	}
+	if typ := function.ParameterTypes["s"]; typ == nil || typ.Value != "Shape" {
+		t.Errorf("parameter s should have type Shape, got=%v", typ)
+	}
+	if typ := function.ParameterTypes["n"]; typ != nil {
+		t.Errorf("parameter n should be untyped, got=%s", typ.Value)
+	}
+	if function.ReturnType == nil || function.ReturnType.Value != "int" {
+		t.Errorf("return type wrong. want=int, got=%v", function.ReturnType)
+	}
+}
+
+func TestLambdaExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		params   int
+		expected string
+	}{
+		{"lambda x: x * 2", 1, "lambda x: (x * 2)"},
+		{"lambda x, y: x + y", 2, "lambda x, y: (x + y)"},
+		{"lambda: 42", 0, "lambda: 42"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		lambda, ok := stmt.Expression.(*ast.LambdaExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.LambdaExpression. got=%T", stmt.Expression)
+		}
+		if len(lambda.Parameters) != tt.params {
+			t.Errorf("lambda parameters wrong. want %d, got=%d", tt.params, len(lambda.Parameters))
+		}
+		if lambda.String() != tt.expected {
+			t.Errorf("lambda.String() wrong. want=%q, got=%q", tt.expected, lambda.String())
+		}
+	}
+}
+
+func TestHashLiteralParsing(t *testing.T) {
+	input := `{"double": lambda x: x * 2, "one": 1}`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+	if len(hash.Keys) != 2 {
+		t.Fatalf("hash.Keys has wrong length. want 2, got=%d", len(hash.Keys))
+	}
+	if _, ok := hash.Values[0].(*ast.LambdaExpression); !ok {
+		t.Errorf("hash.Values[0] is not ast.LambdaExpression. got=%T", hash.Values[0])
+	}
+	if hash.Keys[1].String() != "one" || hash.Values[1].String() != "1" {
+		t.Errorf("second pair wrong. got=%s: %s", hash.Keys[1], hash.Values[1])
+	}
+}
+
+func TestFunctionStatementParsing(t *testing.T) {
+	input := `
+def add(x: int, y) -> int
+    return x + y
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "add" {
+		t.Errorf("stmt.Name.Value not 'add'. got=%q", stmt.Name.Value)
+	}
+	if len(stmt.Function.Parameters) != 2 {
+		t.Fatalf("function parameters wrong. want 2, got=%d", len(stmt.Function.Parameters))
+	}
+	if stmt.Function.ParameterTypes["x"] == nil || stmt.Function.ParameterTypes["x"].Value != "int" {
+		t.Errorf("parameter x should have type int")
+	}
+	if stmt.Function.ReturnType == nil || stmt.Function.ReturnType.Value != "int" {
+		t.Errorf("function return type should be int")
+	}
+	if len(stmt.Function.Body.Statements) != 1 {
+		t.Errorf("function body does not contain 1 statement. got=%d", len(stmt.Function.Body.Statements))
+	}
+}
+
+func TestTupleParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a, b = b, a", "(a, b) = (b, a)"},
+		{"first, *rest = xs", "(first, *rest) = xs"},
+		{"x, y = f()", "(x, y) = f()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.AssignmentStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.AssignmentStatement. got=%T", program.Statements[0])
+		}
+		if _, ok := stmt.Name.(*ast.TupleLiteral); !ok {
+			t.Errorf("stmt.Name is not ast.TupleLiteral. got=%T", stmt.Name)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestMultipleReturnParsing(t *testing.T) {
+	input := `
+def divmod(a: int, b: int) -> (int, int)
+    return a / b, a - b
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.FunctionStatement)
+	if len(stmt.Function.ReturnTypes) != 2 {
+		t.Fatalf("function should have 2 result types. got=%d", len(stmt.Function.ReturnTypes))
+	}
+	if stmt.Function.ReturnTypes[0].Value != "int" || stmt.Function.ReturnTypes[1].Value != "int" {
+		t.Errorf("result types wrong. got=%s, %s", stmt.Function.ReturnTypes[0], stmt.Function.ReturnTypes[1])
+	}
+	ret := stmt.Function.Body.Statements[0].(*ast.ReturnStatement)
+	tuple, ok := ret.ReturnValue.(*ast.TupleLiteral)
+	if !ok {
+		t.Fatalf("ret.ReturnValue is not ast.TupleLiteral. got=%T", ret.ReturnValue)
+	}
+	if len(tuple.Elements) != 2 {
+		t.Errorf("tuple should have 2 elements. got=%d", len(tuple.Elements))
+	}
+}
+
+func TestSliceExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		isSlice  bool
+	}{
+		{"xs[1:3]", "(xs[1:3])", true},
+		{"xs[::2]", "(xs[::2])", true},
+		{"s[-3:]", "(s[(-3):])", true},
+		{"xs[:2]", "(xs[:2])", true},
+		{"xs[-1]", "(xs[(-1)])", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		_, isSlice := stmt.Expression.(*ast.SliceExpression)
+		if isSlice != tt.isSlice {
+			t.Errorf("%q: expected slice=%t, got %T", tt.input, tt.isSlice, stmt.Expression)
+		}
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expression.String() wrong. want=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
+func TestComprehensionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     string
+		expected string
+	}{
+		{"[x * 2 for x in xs if x > 0]", ast.ListComprehension, "[(x * 2) for x in xs if (x > 0)]"},
+		{"{k: v for k, v in pairs}", ast.DictComprehension, "{k: v for k, v in pairs}"},
+		{"{x for x in xs}", ast.SetComprehension, "{x for x in xs}"},
+		{"(x for x in xs)", ast.GeneratorComprehension, "(x for x in xs)"},
+		{"[i * j for i in a for j in b]", ast.ListComprehension, "[(i * j) for i in a for j in b]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		comp, ok := stmt.Expression.(*ast.ComprehensionExpression)
+		if !ok {
+			t.Fatalf("%q: expression is not ast.ComprehensionExpression. got=%T", tt.input, stmt.Expression)
+		}
+		if comp.Kind != tt.kind {
+			t.Errorf("%q: wrong kind. want=%q, got=%q", tt.input, tt.kind, comp.Kind)
+		}
+		if comp.String() != tt.expected {
+			t.Errorf("comp.String() wrong. want=%q, got=%q", tt.expected, comp.String())
+		}
+	}
+}
+
+func TestGeneratorArgumentParsing(t *testing.T) {
+	l := lexer.New("sum(x * x for x in xs)")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if len(call.Arguments) != 1 {
+		t.Fatalf("wrong number of arguments. want 1, got=%d", len(call.Arguments))
+	}
+	comp, ok := call.Arguments[0].(*ast.ComprehensionExpression)
+	if !ok || comp.Kind != ast.GeneratorComprehension {
+		t.Fatalf("argument is not a generator expression. got=%T", call.Arguments[0])
+	}
+}
+
+func TestConditionalExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = a if c else b", "x = (a if c else b)"},
+		{"x = a + 1 if a > 0 else a - 1", "x = ((a + 1) if (a > 0) else (a - 1))"},
+		{"x = a if c else b if d else e", "x = (a if c else (b if d else e))"},
+		{"xs = [x if x > 0 else 0 for x in ys if x != 1]", "xs = [(x if (x > 0) else 0) for x in ys if (x != 1)]"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("String() wrong. want=%q, got=%q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestMatchStatementParsing(t *testing.T) {
+	input := `
+match command
+    case "start" | "run"
+        launch()
+    case User(name, age=a) if a > 18
+        adult(name)
+    case [first, *rest]
+        head(first)
+    case -1
+        fail()
+    case _
+        other()
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.MatchStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.MatchStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Subject.String() != "command" {
+		t.Errorf("subject wrong. got=%q", stmt.Subject.String())
+	}
+
+	expected := []struct {
+		pattern string
+		guard   string
+	}{
+		{"start | run", ""},
+		{"User(name, age=a)", "(a > 18)"},
+		{"[first, *rest]", ""},
+		{"(-1)", ""},
+		{"_", ""},
+	}
+	if len(stmt.Cases) != len(expected) {
+		t.Fatalf("wrong number of cases. want %d, got=%d", len(expected), len(stmt.Cases))
+	}
+	for i, tt := range expected {
+		c := stmt.Cases[i]
+		if c.Pattern.String() != tt.pattern {
+			t.Errorf("case %d pattern wrong. want=%q, got=%q", i, tt.pattern, c.Pattern.String())
+		}
+		guard := ""
+		if c.Guard != nil {
+			guard = c.Guard.String()
+		}
+		if guard != tt.guard {
+			t.Errorf("case %d guard wrong. want=%q, got=%q", i, tt.guard, guard)
+		}
+		if len(c.Body.Statements) != 1 {
+			t.Errorf("case %d body has wrong number of statements. got=%d", i, len(c.Body.Statements))
+		}
+	}
+}
+
+func TestTryStatementParsing(t *testing.T) {
+	input := `
+try
+    data = os.ReadFile(path)
+except os.ErrNotExist as e
+    data = ""
+except (ValueError, KeyError)
+    fail()
+except
+    other()
+finally
+    close()
+x = f() or else default if ok else 0
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.TryStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.TryStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Body.Statements) != 1 {
+		t.Errorf("try body has wrong number of statements. got=%d", len(stmt.Body.Statements))
+	}
+
+	expected := []struct {
+		kinds []string
+		name  string
+	}{
+		{[]string{"os.ErrNotExist"}, "e"},
+		{[]string{"ValueError", "KeyError"}, ""},
+		{nil, ""},
+	}
+	if len(stmt.Handlers) != len(expected) {
+		t.Fatalf("wrong number of except clauses. want %d, got=%d", len(expected), len(stmt.Handlers))
+	}
+	for i, tt := range expected {
+		h := stmt.Handlers[i]
+		if len(h.Kinds) != len(tt.kinds) {
+			t.Errorf("except %d has wrong number of kinds. want %d, got=%d", i, len(tt.kinds), len(h.Kinds))
+			continue
+		}
+		for j, kind := range tt.kinds {
+			if h.Kinds[j].String() != kind {
+				t.Errorf("except %d kind %d wrong. want=%q, got=%q", i, j, kind, h.Kinds[j].String())
+			}
+		}
+		name := ""
+		if h.Name != nil {
+			name = h.Name.Value
+		}
+		if name != tt.name {
+			t.Errorf("except %d name wrong. want=%q, got=%q", i, tt.name, name)
+		}
+	}
+	if stmt.Finally == nil || len(stmt.Finally.Statements) != 1 {
+		t.Errorf("finally block not parsed")
+	}
+
+	assign, ok := program.Statements[1].(*ast.AssignmentStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.AssignmentStatement. got=%T", program.Statements[1])
+	}
+	if assign.Value.String() != "((f() or else default) if ok else 0)" {
+		t.Errorf("or else parsed wrong. got=%q", assign.Value.String())
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	l := lexer.New("try\n    f()\nx = 1\n")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "expected except or finally after try block" {
+		t.Errorf("wrong parser errors: %q", errors)
+	}
+}
+
+func TestRaiseStatementParsing(t *testing.T) {
+	input := `
+class ParseError(ValueError)
+    pos: int = 0
+def parse(s)
+    if s == ""
+        raise ParseError("empty", pos=0)
+    if s == "?"
+        raise
+    return s
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	class, ok := program.Statements[0].(*ast.ClassStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ClassStatement. got=%T", program.Statements[0])
+	}
+	if class.Base == nil || class.Base.Value != "ValueError" {
+		t.Errorf("class base not parsed. got=%v", class.Base)
+	}
+
+	fn := program.Statements[1].(*ast.FunctionStatement)
+	if len(fn.Function.Body.Statements) != 3 {
+		t.Fatalf("function body has wrong number of statements. got=%d", len(fn.Function.Body.Statements))
+	}
+	expected := []string{`raise ParseError(empty, pos=0)`, "raise"}
+	for i, want := range expected {
+		ifExpr := fn.Function.Body.Statements[i].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+		raise, ok := ifExpr.Consequence.Statements[0].(*ast.RaiseStatement)
+		if !ok {
+			t.Fatalf("if %d body is not ast.RaiseStatement. got=%T", i, ifExpr.Consequence.Statements[0])
+		}
+		if raise.String() != want {
+			t.Errorf("raise %d wrong. want=%q, got=%q", i, want, raise.String())
+		}
+	}
+}
+
+func TestConcurrencyParsing(t *testing.T) {
+	input := `
+go fetch(url, retries=3)
+ch <- x + 1
+v = <-ch
+select
+    case msg = <-inbox
+        handle(msg)
+    case out <- v
+        sent()
+    case <-done
+        stop()
+    default
+        idle()
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	goStmt, ok := program.Statements[0].(*ast.GoStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.GoStatement. got=%T", program.Statements[0])
+	}
+	if goStmt.String() != "go fetch(url, retries=3)" {
+		t.Errorf("go statement wrong. got=%q", goStmt.String())
+	}
+	send, ok := program.Statements[1].(*ast.SendStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.SendStatement. got=%T", program.Statements[1])
+	}
+	if send.String() != "ch <- (x + 1)" {
+		t.Errorf("send statement wrong. got=%q", send.String())
+	}
+	assign := program.Statements[2].(*ast.AssignmentStatement)
+	if _, ok := assign.Value.(*ast.ReceiveExpression); !ok {
+		t.Errorf("assignment value is not ast.ReceiveExpression. got=%T", assign.Value)
+	}
+
+	sel, ok := program.Statements[3].(*ast.SelectStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.SelectStatement. got=%T", program.Statements[3])
+	}
+	expected := []string{"msg = <-inbox", "out <- v", "<-done", ""}
+	if len(sel.Cases) != len(expected) {
+		t.Fatalf("wrong number of select cases. want %d, got=%d", len(expected), len(sel.Cases))
+	}
+	for i, want := range expected {
+		got := ""
+		if sel.Cases[i].Comm != nil {
+			got = sel.Cases[i].Comm.String()
+		}
+		if got != want {
+			t.Errorf("select case %d wrong. want=%q, got=%q", i, want, got)
+		}
+		if len(sel.Cases[i].Body.Statements) != 1 {
+			t.Errorf("select case %d body has wrong number of statements. got=%d", i, len(sel.Cases[i].Body.Statements))
+		}
+	}
+}
+
+func TestWithStatementParsing(t *testing.T) {
+	input := `
+with lock
+    count = count + 1
+with locks[0] as held
+    use(held)
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	tests := []struct {
+		value string
+		name  string
+	}{
+		{"lock", ""},
+		{"(locks[0])", "held"},
+	}
+	for i, tt := range tests {
+		stmt, ok := program.Statements[i].(*ast.WithStatement)
+		if !ok {
+			t.Fatalf("stmt %d is not ast.WithStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.Value.String() != tt.value {
+			t.Errorf("with value wrong. want=%q, got=%q", tt.value, stmt.Value.String())
+		}
+		name := ""
+		if stmt.Name != nil {
+			name = stmt.Name.Value
+		}
+		if name != tt.name {
+			t.Errorf("with name wrong. want=%q, got=%q", tt.name, name)
+		}
+		if len(stmt.Body.Statements) != 1 {
+			t.Errorf("with body has wrong number of statements. got=%d", len(stmt.Body.Statements))
+		}
+	}
+}
+
+func TestDeferStatementParsing(t *testing.T) {
+	l := lexer.New("defer f.close()\ndefer print(\"done\", x)\n")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{"defer f.close()", "defer print(done, x)"}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	for i, want := range expected {
+		stmt, ok := program.Statements[i].(*ast.DeferStatement)
+		if !ok {
+			t.Fatalf("stmt %d is not ast.DeferStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.String() != want {
+			t.Errorf("defer statement wrong. want=%q, got=%q", want, stmt.String())
+		}
+	}
+
+	l = lexer.New("defer x\n")
+	p = New(l)
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "expected function call after defer" {
+		t.Errorf("wrong parser errors: %q", errors)
+	}
+}
+
+func TestAssertStatementParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		condition string
+		message   string
+	}{
+		{"assert x > 0\n", "(x > 0)", ""},
+		{"assert ok(x), \"x must be ok\"\n", "ok(x)", "x must be ok"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.AssertStatement)
+		if !ok {
+			t.Fatalf("stmt is not ast.AssertStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Condition.String() != tt.condition {
+			t.Errorf("assert condition wrong. want=%q, got=%q", tt.condition, stmt.Condition.String())
+		}
+		message := ""
+		if stmt.Message != nil {
+			message = stmt.Message.String()
+		}
+		if message != tt.message {
+			t.Errorf("assert message wrong. want=%q, got=%q", tt.message, message)
+		}
+	}
+}
+
+func TestPrintStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"print x, y\n", "print(x, y)"},
+		{"print \"a\", 1, sep=\", \", end=\"\"\n", "print(a, 1, sep=, , end=)"},
+		{"print\n", "print()"},
+		{"print(x)\n", "print(x)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("stmt is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+		if _, ok := stmt.Expression.(*ast.CallExpression); !ok {
+			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("print statement wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestMembershipParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ok = x in xs", "ok = (x in xs)"},
+		{"ok = x + 1 not in xs", "ok = ((x + 1) not in xs)"},
+		{"ys = [x for x in xs if x not in seen]", "ys = [x for x in xs if (x not in seen)]"},
+		{"ok = not x in xs", "ok = (not(x in xs))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("String() wrong. want=%q, got=%q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestSetAndTupleParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"s = {1, 2, 3}", "s = {1, 2, 3}"},
+		{"s = {x,}", "s = {x}"},
+		{"d = {}", "d = {}"},
+		{"t = (1, \"a\")", "t = (1, a)"},
+		{"t = (x,)", "t = (x)"},
+		{"t = ()", "t = ()"},
+		{"d = {(0, 1): \"a\"}", "d = {(0, 1): a}"},
+		{"s = a | b & c ^ d", "s = (a | ((b & c) ^ d))"},
+		{"s = a - b | c", "s = ((a - b) | c)"},
+		{"ok = a | b == c", "ok = ((a | b) == c)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("String() wrong. want=%q, got=%q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestImportStatementParsing(t *testing.T) {
+	input := `
+import strings
+import math/rand
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{"strings", "math/rand"}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+	for i, path := range expected {
+		stmt, ok := program.Statements[i].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("statement %d is not *ast.ImportStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.Path != path {
+			t.Errorf("stmt.Path wrong. want=%q, got=%q", path, stmt.Path)
+		}
+	}
+}
+
+func TestModuleTypeAnnotationParsing(t *testing.T) {
+	input := `
+def move(p: shapes.Point, n: int) -> shapes.Point
+    return p
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.FunctionStatement. got=%T", program.Statements[0])
+	}
+	if typ := stmt.Function.ParameterTypes["p"]; typ == nil || typ.Value != "shapes.Point" {
+		t.Errorf("parameter p should have type shapes.Point, got=%v", typ)
+	}
+	if typ := stmt.Function.ReturnType; typ == nil || typ.Value != "shapes.Point" {
+		t.Errorf("return type wrong. want=shapes.Point, got=%v", typ)
+	}
+}
+
+func TestLogicalOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ok = a > 0 and b > 0", "ok = ((a > 0) and (b > 0))"},
+		{"ok = a >= 1 or b <= 2", "ok = ((a >= 1) or (b <= 2))"},
+		{"ok = not a and b.c", "ok = ((nota) and b.c)"},
+		{"x = 1 if a == 1 and b else 2", "x = (1 if ((a == 1) and b) else 2)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("String() wrong. want=%q, got=%q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
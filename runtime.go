@@ -0,0 +1,1570 @@
+package generator
+
+import (
+	"sort"
+)
+
+// runtimeHelper — вспомогательная функция времени выполнения. Она добавляется
+// в сгенерированную программу, только если сгенерированный код её использует
+type runtimeHelper struct {
+	imports []string // пакеты Go, которые нужны функции
+	deps    []string // другие вспомогательные функции, которые она вызывает
+	code    string
+}
+
+var runtimeHelpers = map[string]runtimeHelper{
+	// gopyCall вызывает значение-функцию, тип которого неизвестен при трансляции
+	// (например, функцию, взятую из списка или словаря)
+	"gopyCall": {
+		deps:    []string{"gopyError"},
+		imports: []string{"reflect"},
+		code: `func gopyCall(fn interface{}, args ...interface{}) interface{} {
+	f := reflect.ValueOf(fn)
+	if f.Kind() != reflect.Func {
+		panic(gopyErrorf(gopyTypeError, 0, "объект типа %T нельзя вызвать", fn))
+	}
+	if f.Type().NumIn() != len(args) && !f.Type().IsVariadic() {
+		panic(gopyErrorf(gopyTypeError, 0, "функция ожидает %d аргументов, получено %d", f.Type().NumIn(), len(args)))
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			in[i] = reflect.Zero(f.Type().In(i))
+		} else {
+			in[i] = reflect.ValueOf(arg)
+		}
+	}
+	out := f.Call(in)
+	if len(out) == 0 {
+		return nil
+	}
+	return out[0].Interface()
+}
+`,
+	},
+	// gopyTuple — кортеж. Кортеж каждой длины — отдельная сравнимая структура
+	// gopyTupleN, которую генерирует tupleTypes, поэтому кортежи можно сравнивать
+	// через == и использовать как ключи словаря и элементы множества
+	"gopyTuple": {
+		code: `type gopyTuple interface {
+	gopyItems() []interface{}
+}
+`,
+	},
+	// gopyNewTuple собирает кортеж из элементов, число которых известно только
+	// во время выполнения. Её код генерирует tupleTypes: ветви зависят от длин
+	// кортежей программы
+	"gopyNewTuple": {
+		deps: []string{"gopyTuple"},
+	},
+	// gopyList — список Gopy. Список передаётся по ссылке, как в Python: элементы,
+	// добавленные или изменённые через одну переменную, видны через все остальные
+	"gopyList": {
+		code: `type gopyList struct {
+	items []interface{}
+}
+
+func gopyNewList(items ...interface{}) *gopyList {
+	return &gopyList{items: items}
+}
+
+// gopyListCopy создаёт список с копией элементов items
+func gopyListCopy(items []interface{}) *gopyList {
+	return &gopyList{items: append([]interface{}{}, items...)}
+}
+
+func (l *gopyList) append(item interface{}) {
+	l.items = append(l.items, item)
+}
+`,
+	},
+	// gopyListMethods — методы списка, которые меняют его без проверок:
+	// extend, insert, reverse и clear
+	"gopyListMethods": {
+		deps: []string{"gopyList"},
+		code: `func (l *gopyList) extend(items []interface{}) {
+	l.items = append(l.items, items...)
+}
+
+// insert вставляет элемент перед позицией index; позиция за границами списка
+// вставляет элемент в начало или в конец
+func (l *gopyList) insert(index int64, item interface{}) {
+	n := int64(len(l.items))
+	if index < 0 {
+		index += n
+	}
+	index = max(0, min(index, n))
+	l.items = append(l.items, nil)
+	copy(l.items[index+1:], l.items[index:])
+	l.items[index] = item
+}
+
+func (l *gopyList) reverse() {
+	for i, j := 0, len(l.items)-1; i < j; i, j = i+1, j-1 {
+		l.items[i], l.items[j] = l.items[j], l.items[i]
+	}
+}
+
+func (l *gopyList) clear() {
+	l.items = nil
+}
+`,
+	},
+	// gopyListPop удаляет и возвращает элемент списка по индексу
+	"gopyListPop": {
+		deps: []string{"gopyError", "gopyList", "gopyPosition"},
+		code: `func (l *gopyList) pop(index int64, line int) interface{} {
+	if len(l.items) == 0 {
+		panic(gopyErrorf(gopyIndexError, line, "pop из пустого списка"))
+	}
+	i := gopyPosition(index, len(l.items), "списка", line)
+	item := l.items[i]
+	l.items = append(l.items[:i], l.items[i+1:]...)
+	return item
+}
+`,
+	},
+	// gopyListSearch — методы списка, которые сравнивают элементы: index, remove, count
+	"gopyListSearch": {
+		deps: []string{"gopyEqual", "gopyListPop", "gopyRepr"},
+		code: `func (l *gopyList) index(item interface{}, line int) int64 {
+	for i, x := range l.items {
+		if gopyEqual(x, item) {
+			return int64(i)
+		}
+	}
+	panic(gopyErrorf(gopyValueError, line, "%s нет в списке", gopyRepr(item)))
+}
+
+func (l *gopyList) remove(item interface{}, line int) {
+	l.pop(l.index(item, line), line)
+}
+
+func (l *gopyList) count(item interface{}) int64 {
+	n := int64(0)
+	for _, x := range l.items {
+		if gopyEqual(x, item) {
+			n++
+		}
+	}
+	return n
+}
+`,
+	},
+	// gopyListSort сортирует список на месте так же, как sorted
+	"gopyListSort": {
+		deps: []string{"gopyList", "gopySorted"},
+		code: `func (l *gopyList) sort(key interface{}, reverse bool, line int) {
+	l.items = gopySorted(l.items, key, reverse, line).items
+}
+`,
+	},
+	// gopyConcat соединяет два списка в новый: xs + ys
+	"gopyConcat": {
+		deps: []string{"gopyError", "gopyList"},
+		code: `func gopyConcat(a, b interface{}, line int) *gopyList {
+	x, ok := a.(*gopyList)
+	y, ok2 := b.(*gopyList)
+	if !ok || !ok2 {
+		panic(gopyErrorf(gopyTypeError, line, "нельзя сложить значения типов %T и %T", a, b))
+	}
+	items := append(append([]interface{}{}, x.items...), y.items...)
+	return &gopyList{items: items}
+}
+`,
+	},
+	// gopyRepeat повторяет элементы списка n раз: xs * 3
+	"gopyRepeat": {
+		deps: []string{"gopyError", "gopyList"},
+		code: `func gopyRepeat(value interface{}, n int64, line int) *gopyList {
+	xs, ok := value.(*gopyList)
+	if !ok {
+		panic(gopyErrorf(gopyTypeError, line, "значение типа %T нельзя умножить на число", value))
+	}
+	items := []interface{}{}
+	for i := int64(0); i < n; i++ {
+		items = append(items, xs.items...)
+	}
+	return &gopyList{items: items}
+}
+`,
+	},
+	// gopyContains проверяет x in c: элемент списка или кортежа, подстроку строки,
+	// ключ словаря или элемент множества
+	"gopyContains": {
+		deps:    []string{"gopyEqual", "gopyError", "gopyList", "gopyTuple"},
+		imports: []string{"strings"},
+		code: `func gopyContains(container, item interface{}, line int) bool {
+	if i, ok := item.(int); ok {
+		item = int64(i)
+	}
+	switch c := container.(type) {
+	case *gopyList:
+		return gopyContainsItem(c.items, item)
+	case gopyTuple:
+		return gopyContainsItem(c.gopyItems(), item)
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			panic(gopyErrorf(gopyTypeError, line, "в строке можно искать только строку, получено %T", item))
+		}
+		return strings.Contains(c, s)
+	case map[interface{}]interface{}:
+		_, ok := c[item]
+		return ok
+	case map[interface{}]struct{}:
+		_, ok := c[item]
+		return ok
+	}
+	panic(gopyErrorf(gopyTypeError, line, "оператор in не применим к значению типа %T", container))
+}
+
+func gopyContainsItem(items []interface{}, item interface{}) bool {
+	for _, x := range items {
+		if gopyEqual(x, item) {
+			return true
+		}
+	}
+	return false
+}
+`,
+	},
+	// gopyNewSet создаёт множество из элементов литерала {a, b}
+	"gopyNewSet": {
+		deps: []string{"gopyHashable"},
+		code: `func gopyNewSet(line int, items ...interface{}) map[interface{}]struct{} {
+	set := make(map[interface{}]struct{}, len(items))
+	for _, item := range items {
+		set[gopyHashable(item, line)] = struct{}{}
+	}
+	return set
+}
+`,
+	},
+	// gopyHashable проверяет, что значение может быть элементом множества или
+	// ключом словаря: изменяемые списки, словари и множества не хешируются
+	"gopyHashable": {
+		deps: []string{"gopyError", "gopyList", "gopyTuple"},
+		code: `func gopyHashable(value interface{}, line int) interface{} {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case *gopyList:
+		panic(gopyErrorf(gopyTypeError, line, "значение типа list нельзя хешировать"))
+	case map[interface{}]interface{}:
+		panic(gopyErrorf(gopyTypeError, line, "значение типа dict нельзя хешировать"))
+	case map[interface{}]struct{}:
+		panic(gopyErrorf(gopyTypeError, line, "значение типа set нельзя хешировать"))
+	case gopyTuple:
+		for _, item := range v.gopyItems() {
+			gopyHashable(item, line)
+		}
+	}
+	return value
+}
+`,
+	},
+	// gopySetOps — операции над множествами: a | b, a & b, a - b и a ^ b.
+	// Каждая операция возвращает новое множество
+	"gopySetOps": {
+		code: `func gopyUnion(a, b map[interface{}]struct{}) map[interface{}]struct{} {
+	out := make(map[interface{}]struct{}, len(a)+len(b))
+	for x := range a {
+		out[x] = struct{}{}
+	}
+	for x := range b {
+		out[x] = struct{}{}
+	}
+	return out
+}
+
+func gopyIntersection(a, b map[interface{}]struct{}) map[interface{}]struct{} {
+	out := map[interface{}]struct{}{}
+	for x := range a {
+		if _, ok := b[x]; ok {
+			out[x] = struct{}{}
+		}
+	}
+	return out
+}
+
+func gopyDifference(a, b map[interface{}]struct{}) map[interface{}]struct{} {
+	out := map[interface{}]struct{}{}
+	for x := range a {
+		if _, ok := b[x]; !ok {
+			out[x] = struct{}{}
+		}
+	}
+	return out
+}
+
+func gopySymmetricDifference(a, b map[interface{}]struct{}) map[interface{}]struct{} {
+	return gopyUnion(gopyDifference(a, b), gopyDifference(b, a))
+}
+`,
+	},
+	// gopyUnpack распаковывает кортеж, список или строку в count значений.
+	// Цель со звёздочкой (star >= 0) получает список оставшихся значений
+	"gopyUnpack": {
+		deps: []string{"gopyError", "gopyList", "gopyTuple"},
+		code: `func gopyUnpack(value interface{}, count int, star int) []interface{} {
+	var items []interface{}
+	switch v := value.(type) {
+	case gopyTuple:
+		items = v.gopyItems()
+	case *gopyList:
+		items = v.items
+	case string:
+		for _, r := range v {
+			items = append(items, string(r))
+		}
+	default:
+		panic(gopyErrorf(gopyTypeError, 0, "объект типа %T нельзя распаковать", value))
+	}
+	if star < 0 {
+		if len(items) != count {
+			panic(gopyErrorf(gopyValueError, 0, "ожидалось %d значений для распаковки, получено %d", count, len(items)))
+		}
+		return items
+	}
+	if len(items) < count-1 {
+		panic(gopyErrorf(gopyValueError, 0, "ожидалось не менее %d значений для распаковки, получено %d", count-1, len(items)))
+	}
+	rest := len(items) - (count - 1)
+	out := make([]interface{}, 0, count)
+	out = append(out, items[:star]...)
+	out = append(out, gopyListCopy(items[star:star+rest]))
+	out = append(out, items[star+rest:]...)
+	return out
+}
+`,
+	},
+	// gopyEqual сравнивает значения неизвестного типа; целые сравниваются как int64,
+	// списки и кортежи — поэлементно
+	"gopyEqual": {
+		deps:    []string{"gopyList", "gopyTuple"},
+		imports: []string{"reflect"},
+		code: `func gopyEqual(a, b interface{}) bool {
+	if n, ok := a.(int); ok {
+		a = int64(n)
+	}
+	if n, ok := b.(int); ok {
+		b = int64(n)
+	}
+	switch x := a.(type) {
+	case *gopyList:
+		if y, ok := b.(*gopyList); ok {
+			return gopyEqualItems(x.items, y.items)
+		}
+		return false
+	case gopyTuple:
+		if y, ok := b.(gopyTuple); ok {
+			return gopyEqualItems(x.gopyItems(), y.gopyItems())
+		}
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func gopyEqualItems(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !gopyEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+`,
+	},
+	// gopyFromGo превращает срез или map, которые вернула функция Go, в список
+	// или словарь Gopy; целые элементы становятся int64, []byte — строками
+	"gopyFromGo": {
+		deps:    []string{"gopyList"},
+		imports: []string{"reflect"},
+		code: `func gopyFromGo(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = gopyFromGo(v.Index(i).Interface())
+		}
+		return &gopyList{items: items}
+	case reflect.Map:
+		d := make(map[interface{}]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			d[gopyFromGo(key.Interface())] = gopyFromGo(v.MapIndex(key).Interface())
+		}
+		return d
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.Float32:
+		return v.Float()
+	}
+	return value
+}
+`,
+	},
+	// gopyToGo превращает значение Gopy в значение типа Go t для аргумента функции
+	// Go: списки и кортежи — в срезы, словари — в map, числа — в числа нужного размера
+	"gopyToGo": {
+		deps:    []string{"gopyError", "gopyList", "gopyTuple"},
+		imports: []string{"reflect"},
+		code: `func gopyToGo(value interface{}, t reflect.Type, line int) interface{} {
+	return gopyToGoValue(value, t, line).Interface()
+}
+
+func gopyToGoValue(value interface{}, t reflect.Type, line int) reflect.Value {
+	switch t.Kind() {
+	case reflect.Slice:
+		var items []interface{}
+		sequence := true
+		switch v := value.(type) {
+		case *gopyList:
+			items = v.items
+		case gopyTuple:
+			items = v.gopyItems()
+		case string:
+			if t.Elem().Kind() == reflect.Uint8 {
+				return reflect.ValueOf([]byte(v)).Convert(t)
+			}
+			sequence = false
+		default:
+			sequence = false
+		}
+		if sequence {
+			out := reflect.MakeSlice(t, len(items), len(items))
+			for i, item := range items {
+				out.Index(i).Set(gopyToGoValue(item, t.Elem(), line))
+			}
+			return out
+		}
+	case reflect.Map:
+		if d, ok := value.(map[interface{}]interface{}); ok {
+			out := reflect.MakeMapWithSize(t, len(d))
+			for key, item := range d {
+				out.SetMapIndex(gopyToGoValue(key, t.Key(), line), gopyToGoValue(item, t.Elem(), line))
+			}
+			return out
+		}
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return reflect.Zero(t)
+	}
+	numeric := func(k reflect.Kind) bool { return k >= reflect.Int && k <= reflect.Float64 }
+	switch {
+	case v.Type().AssignableTo(t):
+		out := reflect.New(t).Elem()
+		out.Set(v)
+		return out
+	case numeric(v.Kind()) && numeric(t.Kind()), v.Kind() == t.Kind() && v.Type().ConvertibleTo(t):
+		return v.Convert(t)
+	}
+	panic(gopyErrorf(gopyTypeError, line, "значение типа %T нельзя передать в Go как %s", value, t))
+}
+`,
+	},
+	// gopyError — ошибка времени выполнения Gopy. Вид ошибки (ValueError, IndexError...)
+	// проверяется через errors.Is; виды образуют иерархию с корнем Error
+	"gopyError": {
+		code: `type gopyErrorKind struct {
+	name   string
+	parent *gopyErrorKind
+}
+
+func (k *gopyErrorKind) Error() string { return k.name }
+
+var (
+	gopyBaseError      = &gopyErrorKind{name: "Error"}
+	gopyValueError     = &gopyErrorKind{name: "ValueError", parent: gopyBaseError}
+	gopyTypeError      = &gopyErrorKind{name: "TypeError", parent: gopyBaseError}
+	gopyIndexError     = &gopyErrorKind{name: "IndexError", parent: gopyBaseError}
+	gopyKeyError       = &gopyErrorKind{name: "KeyError", parent: gopyBaseError}
+	gopyOSError        = &gopyErrorKind{name: "OSError", parent: gopyBaseError}
+	gopyAssertionError = &gopyErrorKind{name: "AssertionError", parent: gopyBaseError}
+)
+
+type gopyError struct {
+	kind    *gopyErrorKind
+	message string
+	cause   error
+	file    string
+	line    int
+}
+
+func (e *gopyError) Error() string {
+	if e.line > 0 {
+		return fmt.Sprintf("%s (строка %d)", e.describe(), e.line)
+	}
+	return e.describe()
+}
+
+func (e *gopyError) describe() string {
+	if e.message == "" {
+		return e.kind.name
+	}
+	return e.kind.name + ": " + e.message
+}
+
+// raisedAt запоминает место первого raise ошибки
+func (e *gopyError) raisedAt(line int) {
+	if e.line == 0 {
+		e.file, e.line = gopySourceFile, line
+	}
+}
+
+// report описывает ошибку для сообщения об аварийном завершении программы
+func (e *gopyError) report() string {
+	switch {
+	case e.line > 0 && e.file != "":
+		return fmt.Sprintf("%s в файле %s на строке %d", e.describe(), e.file, e.line)
+	case e.line > 0:
+		return fmt.Sprintf("%s на строке %d", e.describe(), e.line)
+	}
+	return e.describe()
+}
+
+func (e *gopyError) Is(target error) bool {
+	for kind := e.kind; kind != nil; kind = kind.parent {
+		if error(kind) == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap возвращает исходную ошибку Go, чтобы except os.ErrNotExist перехватывал OSError
+func (e *gopyError) Unwrap() error { return e.cause }
+
+func gopyErrorf(kind *gopyErrorKind, line int, format string, args ...interface{}) error {
+	e := &gopyError{kind: kind, message: fmt.Sprintf(format, args...)}
+	e.raisedAt(line)
+	return e
+}
+`,
+	},
+	// gopyCheck превращает ошибку, которую вернула функция Go, в OSError
+	"gopyCheck": {
+		deps: []string{"gopyError"},
+		code: `func gopyCheck(err error, line int) {
+	if err != nil {
+		e := &gopyError{kind: gopyOSError, message: err.Error(), cause: err}
+		e.raisedAt(line)
+		panic(e)
+	}
+}
+`,
+	},
+	// gopyOpen открывает файл в режиме "r" (чтение), "w" (перезапись) или "a" (дозапись)
+	"gopyOpen": {
+		deps:    []string{"gopyCheck"},
+		imports: []string{"os"},
+		code: `func gopyOpen(path string, mode string, line int) *os.File {
+	flags := map[string]int{
+		"r": os.O_RDONLY,
+		"w": os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+		"a": os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+	}
+	flag, ok := flags[mode]
+	if !ok {
+		panic(gopyErrorf(gopyValueError, line, "неизвестный режим открытия файла %q", mode))
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	gopyCheck(err, line)
+	return f
+}
+`,
+	},
+	// gopyRead читает файл до конца
+	"gopyRead": {
+		deps:    []string{"gopyCheck"},
+		imports: []string{"io", "os"},
+		code: `func gopyRead(f *os.File, line int) string {
+	data, err := io.ReadAll(f)
+	gopyCheck(err, line)
+	return string(data)
+}
+`,
+	},
+	// gopyWrite записывает строку в файл
+	"gopyWrite": {
+		deps:    []string{"gopyCheck"},
+		imports: []string{"os"},
+		code: `func gopyWrite(f *os.File, s string, line int) {
+	_, err := f.WriteString(s)
+	gopyCheck(err, line)
+}
+`,
+	},
+	// gopyAssert описывает невыполненный assert: сообщение, текст условия
+	// и значения его операндов
+	"gopyAssert": {
+		deps:    []string{"gopyError"},
+		imports: []string{"strings"},
+		code: `func gopyAssert(line int, source string, message interface{}, operands ...interface{}) error {
+	text := "assert " + source + " не выполнено"
+	if message != nil {
+		text = fmt.Sprintf("%v (%s)", message, text)
+	}
+	values := []string{}
+	for i := 0; i+1 < len(operands); i += 2 {
+		format := "%s = %v"
+		if _, ok := operands[i+1].(string); ok {
+			format = "%s = %q"
+		}
+		values = append(values, fmt.Sprintf(format, operands[i], operands[i+1]))
+	}
+	if len(values) > 0 {
+		text += ": " + strings.Join(values, ", ")
+	}
+	return gopyErrorf(gopyAssertionError, line, "%s", text)
+}
+`,
+	},
+	// gopyLen возвращает длину значения неизвестного типа; длина строки — в символах
+	"gopyLen": {
+		deps: []string{"gopyError", "gopyList", "gopyTuple"},
+		code: `func gopyLen(value interface{}, line int) int64 {
+	switch v := value.(type) {
+	case string:
+		return int64(len([]rune(v)))
+	case *gopyList:
+		return int64(len(v.items))
+	case gopyTuple:
+		return int64(len(v.gopyItems()))
+	case map[interface{}]interface{}:
+		return int64(len(v))
+	case map[interface{}]struct{}:
+		return int64(len(v))
+	case chan interface{}:
+		return int64(len(v))
+	}
+	panic(gopyErrorf(gopyTypeError, line, "у объекта типа %T нет длины", value))
+}
+`,
+	},
+	// gopyInput выводит приглашение и читает строку из стандартного ввода
+	"gopyInput": {
+		deps:    []string{"gopyCheck"},
+		imports: []string{"bufio", "io", "os", "strings"},
+		code: `var gopyStdin = bufio.NewReader(os.Stdin)
+
+func gopyInput(prompt string, line int) string {
+	fmt.Print(prompt)
+	text, err := gopyStdin.ReadString('\n')
+	if err == io.EOF && text == "" {
+		panic(gopyErrorf(gopyOSError, line, "ввод закончился"))
+	}
+	if err != io.EOF {
+		gopyCheck(err, line)
+	}
+	return strings.TrimRight(text, "\r\n")
+}
+`,
+	},
+	// gopyToInt преобразует значение в целое число, как int() в Python
+	"gopyToInt": {
+		deps:    []string{"gopyError"},
+		imports: []string{"strconv", "strings"},
+		code: `func gopyToInt(value interface{}, line int) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			panic(gopyErrorf(gopyValueError, line, "%q не является целым числом", v))
+		}
+		return n
+	}
+	panic(gopyErrorf(gopyTypeError, line, "значение типа %T нельзя преобразовать в целое число", value))
+}
+`,
+	},
+	// gopyToFloat преобразует значение в число с плавающей точкой, как float() в Python
+	"gopyToFloat": {
+		deps:    []string{"gopyError"},
+		imports: []string{"strconv", "strings"},
+		code: `func gopyToFloat(value interface{}, line int) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			panic(gopyErrorf(gopyValueError, line, "%q не является числом", v))
+		}
+		return f
+	}
+	panic(gopyErrorf(gopyTypeError, line, "значение типа %T нельзя преобразовать в число", value))
+}
+`,
+	},
+	// gopyAbs возвращает модуль числа
+	"gopyAbs": {
+		code: `func gopyAbs[T int64 | float64](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+`,
+	},
+	// gopyLess сравнивает значения неизвестного типа: числа между собой, строки между собой
+	"gopyLess": {
+		deps: []string{"gopyError"},
+		code: `func gopyLess(a, b interface{}, line int) bool {
+	number := func(v interface{}) (float64, bool) {
+		switch n := v.(type) {
+		case int:
+			return float64(n), true
+		case int64:
+			return float64(n), true
+		case float64:
+			return n, true
+		}
+		return 0, false
+	}
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x < y
+		}
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return x < y
+		}
+	}
+	panic(gopyErrorf(gopyTypeError, line, "нельзя сравнить значения типов %T и %T", a, b))
+}
+`,
+	},
+	// gopyExtreme возвращает наименьший (или, если max, наибольший) элемент
+	"gopyExtreme": {
+		deps: []string{"gopyError", "gopyLess"},
+		code: `func gopyExtreme(name string, items []interface{}, max bool, line int) interface{} {
+	if len(items) == 0 {
+		panic(gopyErrorf(gopyValueError, line, "%s() от пустой последовательности", name))
+	}
+	best := items[0]
+	for _, item := range items[1:] {
+		if max && gopyLess(best, item, line) || !max && gopyLess(item, best, line) {
+			best = item
+		}
+	}
+	return best
+}
+`,
+	},
+	// gopySum складывает целые элементы списка
+	"gopySum": {
+		deps: []string{"gopyError"},
+		code: `func gopySum(items []interface{}, line int) int64 {
+	total := int64(0)
+	for _, item := range items {
+		switch n := item.(type) {
+		case int:
+			total += int64(n)
+		case int64:
+			total += n
+		default:
+			panic(gopyErrorf(gopyTypeError, line, "sum складывает целые числа, получено %T", item))
+		}
+	}
+	return total
+}
+`,
+	},
+	// gopySorted возвращает новый список с отсортированными элементами. Сортировка
+	// устойчивая, key — функция, значения которой сравниваются вместо элементов
+	"gopySorted": {
+		deps:    []string{"gopyCall", "gopyLess", "gopyList"},
+		imports: []string{"sort"},
+		code: `func gopySorted(items []interface{}, key interface{}, reverse bool, line int) *gopyList {
+	sorted := append([]interface{}{}, items...)
+	keys := sorted
+	if key != nil {
+		keys = make([]interface{}, len(sorted))
+		for i, item := range sorted {
+			keys[i] = gopyCall(key, item)
+		}
+	}
+	order := make([]int, len(sorted))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if reverse {
+			return gopyLess(keys[order[j]], keys[order[i]], line)
+		}
+		return gopyLess(keys[order[i]], keys[order[j]], line)
+	})
+	result := make([]interface{}, len(sorted))
+	for i, index := range order {
+		result[i] = sorted[index]
+	}
+	return &gopyList{items: result}
+}
+`,
+	},
+	// gopyStr форматирует значение для print и str так же, как Python:
+	// строки выводятся как есть, остальные значения — через gopyRepr
+	"gopyStr": {
+		deps: []string{"gopyRepr"},
+		code: `func gopyStr(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return gopyRepr(value)
+}
+`,
+	},
+	// gopyRepr форматирует значение так, как оно записывается в коде Python:
+	// [1, 'a'], {'k': True}, (1,), None, User(name='Bob', age=3)
+	"gopyRepr": {
+		deps:    []string{"gopyList", "gopyTuple"},
+		imports: []string{"reflect", "sort", "strconv", "strings"},
+		code: `func gopyRepr(value interface{}) string {
+	return gopyReprValue(reflect.ValueOf(value))
+}
+
+func gopyReprValue(v reflect.Value) string {
+	if v.IsValid() && v.CanInterface() && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		switch x := v.Interface().(type) {
+		case error:
+			return x.Error()
+		case fmt.Stringer:
+			// Значения типов Go выводятся так же, как в Go: 1.5s
+			return x.String()
+		case *gopyList:
+			return gopyReprValue(reflect.ValueOf(x.items))
+		case gopyTuple:
+			items := []string{}
+			for _, item := range x.gopyItems() {
+				items = append(items, gopyRepr(item))
+			}
+			if len(items) == 1 {
+				return "(" + items[0] + ",)"
+			}
+			return "(" + strings.Join(items, ", ") + ")"
+		}
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "None"
+	case reflect.Interface:
+		if v.IsNil() {
+			return "None"
+		}
+		return gopyReprValue(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			return "True"
+		}
+		return "False"
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		s := strconv.FormatFloat(v.Float(), 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return s
+	case reflect.String:
+		s := v.String()
+		if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+			return "\"" + s + "\""
+		}
+		s = strings.NewReplacer("\\", "\\\\", "'", "\\'", "\n", "\\n", "\t", "\\t").Replace(s)
+		return "'" + s + "'"
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = gopyReprValue(v.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		set := v.Type().Elem().Kind() == reflect.Struct
+		if set && v.Len() == 0 {
+			return "set()"
+		}
+		items := []string{}
+		for _, key := range v.MapKeys() {
+			item := gopyReprValue(key)
+			if !set {
+				item += ": " + gopyReprValue(v.MapIndex(key))
+			}
+			items = append(items, item)
+		}
+		// Порядок элементов map в Go случаен, поэтому выводим их отсортированными
+		sort.Strings(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "None"
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			// Объект класса: поля выводятся в порядке объявления
+			s := v.Elem()
+			fields := []string{}
+			for i := 0; i < s.NumField(); i++ {
+				field := s.Type().Field(i)
+				if field.Anonymous {
+					continue
+				}
+				name := field.Name
+				if source, ok := field.Tag.Lookup("gopy"); ok {
+					name = source
+				}
+				fields = append(fields, name+"="+gopyReprValue(s.Field(i)))
+			}
+			return s.Type().Name() + "(" + strings.Join(fields, ", ") + ")"
+		}
+		return gopyReprValue(v.Elem())
+	case reflect.Func:
+		return "<function>"
+	case reflect.Chan:
+		return "<chan>"
+	}
+	return fmt.Sprint(v)
+}
+`,
+	},
+	// gopySplit делит строку по разделителю sep или, если whitespace, по
+	// последовательностям пробельных символов; maxsplit < 0 — без ограничения
+	"gopySplit": {
+		deps:    []string{"gopyError", "gopyList"},
+		imports: []string{"strings", "unicode"},
+		code: `func gopySplit(s string, sep string, whitespace bool, maxsplit int64, line int) *gopyList {
+	parts := []string{}
+	if whitespace {
+		rest := strings.TrimLeftFunc(s, unicode.IsSpace)
+		for rest != "" {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 || (maxsplit >= 0 && int64(len(parts)) == maxsplit) {
+				parts = append(parts, rest)
+				break
+			}
+			parts = append(parts, rest[:end])
+			rest = strings.TrimLeftFunc(rest[end:], unicode.IsSpace)
+		}
+	} else {
+		if sep == "" {
+			panic(gopyErrorf(gopyValueError, line, "пустой разделитель"))
+		}
+		n := -1
+		if maxsplit >= 0 {
+			n = int(maxsplit) + 1
+		}
+		parts = strings.SplitN(s, sep, n)
+	}
+	result := make([]interface{}, len(parts))
+	for i, part := range parts {
+		result[i] = part
+	}
+	return &gopyList{items: result}
+}
+`,
+	},
+	// gopyJoin соединяет строки списка через sep
+	"gopyJoin": {
+		deps:    []string{"gopyError", "gopyRepr"},
+		imports: []string{"strings"},
+		code: `func gopyJoin(sep string, items []interface{}, line int) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			panic(gopyErrorf(gopyTypeError, line, "элемент %d в join должен быть строкой, получено %s", i, gopyRepr(item)))
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, sep)
+}
+`,
+	},
+	// gopyFind возвращает позицию подстроки в символах или -1
+	"gopyFind": {
+		imports: []string{"strings", "unicode/utf8"},
+		code: `func gopyFind(s, sub string) int64 {
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return -1
+	}
+	return int64(utf8.RuneCountInString(s[:i]))
+}
+`,
+	},
+	// gopyFormat подставляет аргументы в поля {}, {0} и {name} строки формата.
+	// После двоеточия поле может задавать выравнивание, ширину, точность
+	// и тип: {:>8}, {:.2f}, {:05d}
+	"gopyFormat": {
+		deps:    []string{"gopyError", "gopyStr"},
+		imports: []string{"regexp", "strconv", "strings", "unicode/utf8"},
+		code: `var gopyFormatSpec = regexp.MustCompile(` + "`" + `^(?:(.)?([<>^]))?(0)?(\d*)(?:\.(\d+))?([dfsx]?)$` + "`" + `)
+
+func gopyFormat(format string, args []interface{}, named map[string]interface{}, line int) string {
+	var out strings.Builder
+	next := 0
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c == '}' {
+			if i+1 < len(format) && format[i+1] == '}' {
+				i++
+				out.WriteByte('}')
+				continue
+			}
+			panic(gopyErrorf(gopyValueError, line, "одиночная } в строке формата"))
+		}
+		if c != '{' {
+			out.WriteByte(c)
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '{' {
+			i++
+			out.WriteByte('{')
+			continue
+		}
+		end := strings.IndexByte(format[i:], '}')
+		if end < 0 {
+			panic(gopyErrorf(gopyValueError, line, "незакрытая { в строке формата"))
+		}
+		name, spec, _ := strings.Cut(format[i+1:i+end], ":")
+		i += end
+		var value interface{}
+		if n, err := strconv.Atoi(name); name == "" || err == nil {
+			if name == "" {
+				n = next
+				next++
+			}
+			if n >= len(args) {
+				panic(gopyErrorf(gopyIndexError, line, "в format нет аргумента %d", n))
+			}
+			value = args[n]
+		} else {
+			v, ok := named[name]
+			if !ok {
+				panic(gopyErrorf(gopyKeyError, line, "в format нет аргумента %s", name))
+			}
+			value = v
+		}
+		out.WriteString(gopyFormatValue(value, spec, line))
+	}
+	return out.String()
+}
+
+func gopyFormatValue(value interface{}, spec string, line int) string {
+	if spec == "" {
+		return gopyStr(value)
+	}
+	m := gopyFormatSpec.FindStringSubmatch(spec)
+	if m == nil {
+		panic(gopyErrorf(gopyValueError, line, "неподдерживаемый формат {:%s}", spec))
+	}
+	fill, align, zero, width, precision, kind := m[1], m[2], m[3], m[4], m[5], m[6]
+	var n int64
+	var f float64
+	numeric := true
+	switch v := value.(type) {
+	case int:
+		n, f = int64(v), float64(v)
+	case int64:
+		n, f = v, float64(v)
+	case float64:
+		n, f = int64(v), v
+		if kind == "d" || kind == "x" {
+			panic(gopyErrorf(gopyValueError, line, "формат {:%s} требует целое число, получено %s", spec, gopyStr(value)))
+		}
+	default:
+		numeric = false
+		if kind == "d" || kind == "x" || kind == "f" {
+			panic(gopyErrorf(gopyValueError, line, "формат {:%s} требует число, получено %s", spec, gopyStr(value)))
+		}
+	}
+
+	s := gopyStr(value)
+	switch {
+	case kind == "d":
+		s = strconv.FormatInt(n, 10)
+	case kind == "x":
+		s = strconv.FormatInt(n, 16)
+	case kind == "f" || (precision != "" && numeric):
+		digits := 6
+		if precision != "" {
+			digits, _ = strconv.Atoi(precision)
+		}
+		s = strconv.FormatFloat(f, 'f', digits, 64)
+	case precision != "":
+		// Для строк точность ограничивает число символов
+		digits, _ := strconv.Atoi(precision)
+		if runes := []rune(s); len(runes) > digits {
+			s = string(runes[:digits])
+		}
+	}
+
+	if zero != "" && fill == "" && align == "" {
+		fill, align = "0", "="
+	}
+	if fill == "" {
+		fill = " "
+	}
+	if align == "" {
+		align = "<"
+		if numeric {
+			align = ">"
+		}
+	}
+	w, _ := strconv.Atoi(width)
+	pad := w - utf8.RuneCountInString(s)
+	if pad <= 0 {
+		return s
+	}
+	switch align {
+	case "<":
+		return s + strings.Repeat(fill, pad)
+	case "^":
+		return strings.Repeat(fill, pad/2) + s + strings.Repeat(fill, pad-pad/2)
+	case "=":
+		// Нули вставляются после знака: -0042
+		if strings.HasPrefix(s, "-") {
+			return "-" + strings.Repeat(fill, pad) + s[1:]
+		}
+	}
+	return strings.Repeat(fill, pad) + s
+}
+`,
+	},
+	// gopyRaise проверяет, что raise получил ошибку, и запоминает строку raise
+	"gopyRaise": {
+		deps: []string{"gopyError"},
+		code: `func gopyRaise(value interface{}, line int) error {
+	err, ok := value.(error)
+	if !ok {
+		return gopyErrorf(gopyTypeError, line, "raise ожидает ошибку, получено %T", value)
+	}
+	if e, ok := err.(interface{ raisedAt(int) }); ok {
+		e.raisedAt(line)
+	}
+	return err
+}
+`,
+	},
+	// gopyReport завершает программу понятным сообщением, если ошибку
+	// не перехватил ни один try. Вызывается отложенно в начале main
+	"gopyReport": {
+		deps:    []string{"gopyCatch"},
+		imports: []string{"os"},
+		code: `func gopyReport() {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	err := gopyCatch(recovered)
+	text := err.Error()
+	if e, ok := err.(interface{ report() string }); ok {
+		text = e.report()
+	}
+	fmt.Fprintf(os.Stderr, "Ошибка: %s.\n", text)
+	os.Exit(1)
+}
+`,
+	},
+	// gopyRecover возвращает ошибку Gopy из экспортированной функции библиотеки
+	// результатом error вместо паники
+	"gopyRecover": {
+		deps: []string{"gopyCatch"},
+		code: `func gopyRecover(err *error) {
+	if recovered := recover(); recovered != nil {
+		*err = gopyCatch(recovered)
+	}
+}
+`,
+	},
+	// gopyExport превращает значение Gopy в значение Go для кода, который вызывает
+	// библиотеку, и для параметров interface{} функций Go: списки, кортежи и
+	// множества — в []interface{}, словари — в map
+	"gopyExport": {
+		deps:    []string{"gopyList", "gopyRepr", "gopyTuple"},
+		imports: []string{"sort"},
+		code: `func gopyExport(value interface{}) interface{} {
+	var items []interface{}
+	switch v := value.(type) {
+	case *gopyList:
+		items = v.items
+	case gopyTuple:
+		items = v.gopyItems()
+	case map[interface{}]interface{}:
+		d := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			d[key] = gopyExport(item)
+		}
+		return d
+	case map[interface{}]struct{}:
+		for item := range v {
+			items = append(items, item)
+		}
+		// Порядок элементов map в Go случаен, поэтому сортируем их так же, как gopyRepr
+		sort.Slice(items, func(i, j int) bool { return gopyRepr(items[i]) < gopyRepr(items[j]) })
+	default:
+		return value
+	}
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		out[i] = gopyExport(item)
+	}
+	return out
+}
+`,
+	},
+	// gopyCatch превращает значение, перехваченное recover, в ошибку Go.
+	// Неудачное утверждение типа значения неизвестного типа становится TypeError
+	"gopyCatch": {
+		deps:    []string{"gopyError"},
+		imports: []string{"runtime"},
+		code: `func gopyCatch(recovered interface{}) error {
+	if err, ok := recovered.(*runtime.TypeAssertionError); ok {
+		return &gopyError{kind: gopyTypeError, message: err.Error(), cause: err}
+	}
+	if err, ok := recovered.(error); ok {
+		return err
+	}
+	return gopyErrorf(gopyBaseError, 0, "%v", recovered)
+}
+`,
+	},
+	// gopyTasks учитывает горутины, запущенные через go, чтобы main дождалась их
+	"gopyTasks": {
+		imports: []string{"sync"},
+		code: `var gopyTasks sync.WaitGroup
+`,
+	},
+	// gopyParallelMap применяет fn к элементам items в workers горутинах.
+	// Первая ошибка отменяет ещё не начатые вызовы: после того как все начатые
+	// вызовы завершились, она передаётся дальше в вызывающей горутине
+	"gopyParallelMap": {
+		deps:    []string{"gopyCall", "gopyError"},
+		imports: []string{"sync"},
+		code: `func gopyParallelMap(fn interface{}, items []interface{}, workers int64, line int) *gopyList {
+	if workers < 1 {
+		panic(gopyErrorf(gopyValueError, line, "workers должно быть положительным, получено %d", workers))
+	}
+	results := make([]interface{}, len(items))
+	next := make(chan int)
+	cancel := make(chan struct{})
+	var wg sync.WaitGroup
+	var once sync.Once
+	var failure interface{}
+	for w := int64(0); w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				func() {
+					defer func() {
+						if recovered := recover(); recovered != nil {
+							once.Do(func() {
+								failure = recovered
+								close(cancel)
+							})
+						}
+					}()
+					results[i] = gopyCall(fn, items[i])
+				}()
+			}
+		}()
+	}
+feed:
+	for i := range items {
+		select {
+		case next <- i:
+		case <-cancel:
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if failure != nil {
+		panic(failure)
+	}
+	return &gopyList{items: results}
+}
+`,
+	},
+	// gopyKey возвращает значение словаря по ключу или сообщает об отсутствии ключа
+	"gopyKey": {
+		deps: []string{"gopyError"},
+		code: `func gopyKey(d map[interface{}]interface{}, key interface{}, line int) interface{} {
+	value, ok := d[key]
+	if !ok {
+		panic(gopyErrorf(gopyKeyError, line, "ключ %v не найден", key))
+	}
+	return value
+}
+`,
+	},
+	// gopyInt приводит индекс или границу среза к int
+	"gopyInt": {
+		deps: []string{"gopyError"},
+		code: `func gopyInt(value interface{}, line int) int {
+	switch n := value.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	}
+	panic(gopyErrorf(gopyTypeError, line, "индекс должен быть целым числом, получено %T", value))
+}
+`,
+	},
+	// gopyPosition переводит индекс Python (в том числе отрицательный) в позицию
+	// в последовательности длины length
+	"gopyPosition": {
+		deps: []string{"gopyError", "gopyInt"},
+		code: `func gopyPosition(index interface{}, length int, kind string, line int) int {
+	i := gopyInt(index, line)
+	if i < 0 {
+		i += length
+	}
+	if i < 0 || i >= length {
+		panic(gopyErrorf(gopyIndexError, line, "индекс %s вне диапазона: %d при длине %d", kind, gopyInt(index, line), length))
+	}
+	return i
+}
+`,
+	},
+	// gopyIndex возвращает элемент списка, кортежа, строки или словаря
+	"gopyIndex": {
+		deps: []string{"gopyError", "gopyList", "gopyPosition", "gopyTuple"},
+		code: `func gopyIndex(value interface{}, index interface{}, line int) interface{} {
+	switch v := value.(type) {
+	case *gopyList:
+		return v.items[gopyPosition(index, len(v.items), "списка", line)]
+	case gopyTuple:
+		items := v.gopyItems()
+		return items[gopyPosition(index, len(items), "кортежа", line)]
+	case string:
+		runes := []rune(v)
+		return string(runes[gopyPosition(index, len(runes), "строки", line)])
+	case map[interface{}]interface{}:
+		if i, ok := index.(int); ok {
+			index = int64(i)
+		}
+		item, ok := v[index]
+		if !ok {
+			panic(gopyErrorf(gopyKeyError, line, "%v", index))
+		}
+		return item
+	}
+	panic(gopyErrorf(gopyTypeError, line, "объект типа %T не поддерживает индексацию", value))
+}
+`,
+	},
+	// gopySetIndex записывает элемент списка или словаря
+	"gopySetIndex": {
+		deps: []string{"gopyError", "gopyList", "gopyPosition"},
+		code: `func gopySetIndex(value interface{}, index interface{}, item interface{}, line int) {
+	switch v := value.(type) {
+	case *gopyList:
+		v.items[gopyPosition(index, len(v.items), "списка", line)] = item
+	case map[interface{}]interface{}:
+		if i, ok := index.(int); ok {
+			index = int64(i)
+		}
+		v[index] = item
+	default:
+		panic(gopyErrorf(gopyTypeError, line, "объект типа %T не поддерживает присваивание элементов", value))
+	}
+}
+`,
+	},
+	// gopySlice возвращает срез списка, кортежа или строки по правилам Python:
+	// границы могут быть отрицательными и выходить за пределы последовательности
+	"gopySlice": {
+		deps: []string{"gopyError", "gopyInt", "gopyList", "gopyNewTuple"},
+		code: `func gopySlice(value interface{}, start, stop, step interface{}, line int) interface{} {
+	switch v := value.(type) {
+	case *gopyList:
+		return &gopyList{items: gopySliceItems(v.items, start, stop, step, line)}
+	case gopyTuple:
+		return gopyNewTuple(gopySliceItems(v.gopyItems(), start, stop, step, line))
+	case string:
+		runes := []rune(v)
+		out := []rune{}
+		for _, i := range gopySliceIndices(len(runes), start, stop, step, line) {
+			out = append(out, runes[i])
+		}
+		return string(out)
+	}
+	panic(gopyErrorf(gopyTypeError, line, "объект типа %T не поддерживает срезы", value))
+}
+
+func gopySliceItems(items []interface{}, start, stop, step interface{}, line int) []interface{} {
+	out := []interface{}{}
+	for _, i := range gopySliceIndices(len(items), start, stop, step, line) {
+		out = append(out, items[i])
+	}
+	return out
+}
+
+func gopySliceIndices(length int, start, stop, step interface{}, line int) []int {
+	st := 1
+	if step != nil {
+		st = gopyInt(step, line)
+	}
+	if st == 0 {
+		panic(gopyErrorf(gopyValueError, line, "шаг среза не может быть равен нулю"))
+	}
+	lower, upper := 0, length
+	if st < 0 {
+		lower, upper = -1, length-1
+	}
+	bound := func(value interface{}, def int) int {
+		if value == nil {
+			return def
+		}
+		i := gopyInt(value, line)
+		if i < 0 {
+			i += length
+			if i < lower {
+				i = lower
+			}
+		} else if i > upper {
+			i = upper
+		}
+		return i
+	}
+	var from, to int
+	if st > 0 {
+		from, to = bound(start, lower), bound(stop, upper)
+	} else {
+		from, to = bound(start, upper), bound(stop, lower)
+	}
+	indices := []int{}
+	for i := from; (st > 0 && i < to) || (st < 0 && i > to); i += st {
+		indices = append(indices, i)
+	}
+	return indices
+}
+`,
+	},
+	// gopyIter возвращает элементы итерируемого значения: элементы списка или
+	// кортежа, символы строки, ключи словаря или множества
+	"gopyIter": {
+		deps: []string{"gopyError", "gopyList", "gopyTuple"},
+		code: `func gopyIter(value interface{}, line int) []interface{} {
+	switch v := value.(type) {
+	case *gopyList:
+		return v.items
+	case gopyTuple:
+		return v.gopyItems()
+	case string:
+		items := []interface{}{}
+		for _, r := range v {
+			items = append(items, string(r))
+		}
+		return items
+	case map[interface{}]interface{}:
+		items := []interface{}{}
+		for key := range v {
+			items = append(items, key)
+		}
+		return items
+	case map[interface{}]struct{}:
+		items := []interface{}{}
+		for key := range v {
+			items = append(items, key)
+		}
+		return items
+	}
+	panic(gopyErrorf(gopyTypeError, line, "объект типа %T не является итерируемым", value))
+}
+`,
+	},
+	// gopyTruthy проверяет истинность значения по правилам Python:
+	// ложны None, False, ноль и пустые строки и контейнеры
+	"gopyTruthy": {
+		deps: []string{"gopyList", "gopyTuple"},
+		code: `func gopyTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case *gopyList:
+		return len(v.items) > 0
+	case gopyTuple:
+		return len(v.gopyItems()) > 0
+	case map[interface{}]interface{}:
+		return len(v) > 0
+	case map[interface{}]struct{}:
+		return len(v) > 0
+	}
+	return true
+}
+`,
+	},
+}
+
+// useHelper отмечает вспомогательную функцию и её зависимости как используемые
+func (g *Generator) useHelper(name string) {
+	if g.helpers[name] {
+		return
+	}
+	helper, ok := runtimeHelpers[name]
+	if !ok {
+		panic("неизвестная вспомогательная функция: " + name)
+	}
+	g.helpers[name] = true
+	for _, imp := range helper.imports {
+		g.imports[imp] = true
+	}
+	for _, dep := range helper.deps {
+		g.useHelper(dep)
+	}
+}
+
+// sortedKeys возвращает ключи множества в отсортированном порядке,
+// чтобы сгенерированный код не зависел от порядка обхода map
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
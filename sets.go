@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// setOperators сопоставляет операторы множеств с функциями gopySetOps
+var setOperators = map[string]string{
+	"|": "gopyUnion",
+	"&": "gopyIntersection",
+	"-": "gopyDifference",
+	"^": "gopySymmetricDifference",
+}
+
+// generateSet генерирует литерал множества {a, b}. Элементы добавляются во время
+// выполнения: повторяющиеся значения схлопываются, нехешируемые вызывают TypeError
+func (g *Generator) generateSet(set *ast.SetLiteral, inFunction bool) (string, error) {
+	elements := []string{fmt.Sprintf("%d", set.Token.Line)}
+	for _, el := range set.Elements {
+		code, err := g.generateExpressionWithCast(el, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		elements = append(elements, g.boxValue(code, el))
+	}
+	g.useHelper("gopyNewSet")
+	return fmt.Sprintf("gopyNewSet(%s)", strings.Join(elements, ", ")), nil
+}
+
+// generateSetOperator генерирует a | b, a & b, a - b и a ^ b, если хотя бы один
+// операнд — множество. ok == false — оператор относится к числам
+func (g *Generator) generateSetOperator(expr *ast.InfixExpression, left, right string) (string, bool, error) {
+	fn, isSetOperator := setOperators[expr.Operator]
+	leftType, rightType := g.staticType(expr.Left), g.staticType(expr.Right)
+	if !isSetOperator || (leftType != "set" && rightType != "set") {
+		return "", false, nil
+	}
+	for _, typ := range []string{leftType, rightType} {
+		if typ != "" && typ != "set" {
+			return "", false, fmt.Errorf("строка %d: оператор %s не применим к типам %s и %s",
+				expr.Token.Line, expr.Operator, leftType, rightType)
+		}
+	}
+	g.useHelper("gopySetOps")
+	return fmt.Sprintf("%s(%s, %s)", fn, g.coerce(left, expr.Left, "set"), g.coerce(right, expr.Right, "set")), true, nil
+}
@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+)
+
+// generateIndex генерирует обращение по индексу. Словари индексируются через gopyKey,
+// списки, кортежи и строки — через gopyIndex, который поддерживает отрицательные
+// индексы и сообщает о выходе за границы со строкой исходного файла Gopy
+func (g *Generator) generateIndex(expr *ast.IndexExpression, inFunction bool) (string, error) {
+	left, err := g.generateExpressionWithCast(expr.Left, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	index, err := g.generateExpressionWithCast(expr.Index, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	if g.staticType(expr.Left) == "dict" {
+		g.useHelper("gopyKey")
+		return fmt.Sprintf("gopyKey(%s, %s, %d)", left, g.boxValue(index, expr.Index), expr.Token.Line), nil
+	}
+	g.useHelper("gopyIndex")
+	code := fmt.Sprintf("gopyIndex(%s, %s, %d)", left, index, expr.Token.Line)
+	if g.staticType(expr.Left) == "str" {
+		code += ".(string)"
+	}
+	return code, nil
+}
+
+// generateSlice генерирует срез списка, кортежа или строки через gopySlice.
+// Отсутствующие границы передаются как nil
+func (g *Generator) generateSlice(expr *ast.SliceExpression, inFunction bool) (string, error) {
+	left, err := g.generateExpressionWithCast(expr.Left, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	bounds := []string{}
+	for _, bound := range []ast.Expression{expr.Start, expr.Stop, expr.Step} {
+		if bound == nil {
+			bounds = append(bounds, "nil")
+			continue
+		}
+		code, err := g.generateExpressionWithCast(bound, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		bounds = append(bounds, code)
+	}
+	g.useHelper("gopySlice")
+	code := fmt.Sprintf("gopySlice(%s, %s, %s, %s, %d)", left, bounds[0], bounds[1], bounds[2], expr.Token.Line)
+	// Срез значения известного типа имеет тот же тип
+	if typ := g.staticType(expr.Left); typ == "list" || typ == "str" || typ == "tuple" {
+		goTyp, err := g.goType(typ)
+		if err != nil {
+			return "", err
+		}
+		code += fmt.Sprintf(".(%s)", goTyp)
+	}
+	return code, nil
+}
+
+// generateIndexAssignment генерирует присваивание элементу: xs[-1] = v
+func (g *Generator) generateIndexAssignment(target *ast.IndexExpression, value ast.Expression, inFunction bool) (string, error) {
+	val, err := g.generateExpressionWithCast(value, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	return g.generateIndexStore(target, val, inFunction)
+}
+
+// generateIndexStore записывает уже сгенерированное значение в элемент списка или словаря
+func (g *Generator) generateIndexStore(target *ast.IndexExpression, val string, inFunction bool) (string, error) {
+	left, err := g.generateExpressionWithCast(target.Left, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	index, err := g.generateExpressionWithCast(target.Index, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	if g.staticType(target.Left) == "dict" {
+		return fmt.Sprintf("\t%s[%s] = %s\n", left, g.boxValue(index, target.Index), val), nil
+	}
+	g.useHelper("gopySetIndex")
+	return fmt.Sprintf("\tgopySetIndex(%s, %s, %s, %d)\n", left, index, val, target.Token.Line), nil
+}
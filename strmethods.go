@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+func init() {
+	methods["str"] = map[string]*builtin{
+		"split": {maxArgs: 2, params: []string{"str", "int"},
+			keywords: map[string]string{"sep": "str", "maxsplit": "int"},
+			result:   "list", generate: (*Generator).generateSplit},
+		"join":       {minArgs: 1, maxArgs: 1, params: []string{"list|tuple"}, result: "str", generate: (*Generator).generateJoin},
+		"strip":      {maxArgs: 1, params: []string{"str"}, result: "str", generate: (*Generator).generateStrip},
+		"lstrip":     {maxArgs: 1, params: []string{"str"}, result: "str", generate: (*Generator).generateStrip},
+		"rstrip":     {maxArgs: 1, params: []string{"str"}, result: "str", generate: (*Generator).generateStrip},
+		"replace":    {minArgs: 2, maxArgs: 3, params: []string{"str", "str", "int"}, result: "str", generate: (*Generator).generateReplace},
+		"upper":      {result: "str", generate: stringFunction("strings.ToUpper")},
+		"lower":      {result: "str", generate: stringFunction("strings.ToLower")},
+		"startswith": {minArgs: 1, maxArgs: 1, params: []string{"str"}, result: "bool", generate: stringFunction("strings.HasPrefix")},
+		"endswith":   {minArgs: 1, maxArgs: 1, params: []string{"str"}, result: "bool", generate: stringFunction("strings.HasSuffix")},
+		"find":       {minArgs: 1, maxArgs: 1, params: []string{"str"}, result: "int", generate: (*Generator).generateFind},
+		"count":      {minArgs: 1, maxArgs: 1, params: []string{"str"}, result: "int", generate: (*Generator).generateCount},
+		"format":     {maxArgs: -1, anyKeywords: true, result: "str", generate: (*Generator).generateFormat},
+	}
+}
+
+// methodReceiver генерирует значение, у которого вызывается метод, с типом receiver
+func (g *Generator) methodReceiver(call *ast.CallExpression, inFunction bool, receiver string) (string, error) {
+	dot := call.Function.(*ast.DotExpression)
+	code, err := g.generateExpressionWithCast(dot.Left, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	return g.coerce(code, dot.Left, receiver), nil
+}
+
+// methodArguments генерирует получателя метода и его позиционные аргументы.
+// Аргумент i приводится к типу types[i], если он задан
+func (g *Generator) methodArguments(call *ast.CallExpression, inFunction bool, receiver string, types ...string) (string, []string, error) {
+	self, err := g.methodReceiver(call, inFunction, receiver)
+	if err != nil {
+		return "", nil, err
+	}
+	args := []string{}
+	for i, arg := range positionalArguments(call) {
+		code, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return "", nil, err
+		}
+		if i < len(types) && types[i] != "" {
+			code = g.coerce(code, arg, types[i])
+		}
+		args = append(args, code)
+	}
+	return self, args, nil
+}
+
+// stringFunction генерирует метод строки, который соответствует функции пакета
+// strings с теми же аргументами: s.upper() — strings.ToUpper(s)
+func stringFunction(name string) func(g *Generator, call *ast.CallExpression, inFunction bool) (string, error) {
+	return func(g *Generator, call *ast.CallExpression, inFunction bool) (string, error) {
+		s, args, err := g.methodArguments(call, inFunction, "str", "str")
+		if err != nil {
+			return "", err
+		}
+		g.imports["strings"] = true
+		return fmt.Sprintf("%s(%s)", name, strings.Join(append([]string{s}, args...), ", ")), nil
+	}
+}
+
+// generateSplit генерирует s.split(sep, maxsplit). Без разделителя строка
+// делится по последовательностям пробельных символов, как в Python
+func (g *Generator) generateSplit(call *ast.CallExpression, inFunction bool) (string, error) {
+	s, args, err := g.methodArguments(call, inFunction, "str", "str", "int")
+	if err != nil {
+		return "", err
+	}
+	sep, maxsplit := `""`, "-1"
+	whitespace := len(args) == 0
+	if len(args) > 0 {
+		sep = args[0]
+	}
+	if len(args) > 1 {
+		maxsplit = args[1]
+	}
+	if expr := keywordArgument(call, "sep"); expr != nil {
+		code, err := g.generateExpressionWithCast(expr, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		sep, whitespace = g.coerce(code, expr, "str"), false
+	}
+	if expr := keywordArgument(call, "maxsplit"); expr != nil {
+		code, err := g.generateExpressionWithCast(expr, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		maxsplit = g.coerce(code, expr, "int")
+	}
+	g.useHelper("gopySplit")
+	return fmt.Sprintf("gopySplit(%s, %s, %t, %s, %d)", s, sep, whitespace, maxsplit, call.Token.Line), nil
+}
+
+// generateJoin генерирует sep.join(xs): элементы xs должны быть строками
+func (g *Generator) generateJoin(call *ast.CallExpression, inFunction bool) (string, error) {
+	sep, args, err := g.methodArguments(call, inFunction, "str")
+	if err != nil {
+		return "", err
+	}
+	g.useHelper("gopyJoin")
+	return fmt.Sprintf("gopyJoin(%s, %s, %d)", sep, g.sequenceValue(args[0], call.Arguments[0]), call.Token.Line), nil
+}
+
+// generateStrip генерирует strip, lstrip и rstrip: без аргумента удаляются
+// пробельные символы, с аргументом — символы из заданной строки
+func (g *Generator) generateStrip(call *ast.CallExpression, inFunction bool) (string, error) {
+	s, args, err := g.methodArguments(call, inFunction, "str", "str")
+	if err != nil {
+		return "", err
+	}
+	g.imports["strings"] = true
+	side := map[string]string{"strip": "", "lstrip": "Left", "rstrip": "Right"}[call.Function.(*ast.DotExpression).Right.Value]
+	if len(args) == 0 {
+		if side == "" {
+			return fmt.Sprintf("strings.TrimSpace(%s)", s), nil
+		}
+		g.imports["unicode"] = true
+		return fmt.Sprintf("strings.Trim%sFunc(%s, unicode.IsSpace)", side, s), nil
+	}
+	return fmt.Sprintf("strings.Trim%s(%s, %s)", side, s, args[0]), nil
+}
+
+// generateReplace генерирует s.replace(old, new, count)
+func (g *Generator) generateReplace(call *ast.CallExpression, inFunction bool) (string, error) {
+	s, args, err := g.methodArguments(call, inFunction, "str", "str", "str", "int")
+	if err != nil {
+		return "", err
+	}
+	g.imports["strings"] = true
+	if len(args) == 3 {
+		return fmt.Sprintf("strings.Replace(%s, %s, %s, int(%s))", s, args[0], args[1], args[2]), nil
+	}
+	return fmt.Sprintf("strings.ReplaceAll(%s, %s, %s)", s, args[0], args[1]), nil
+}
+
+// generateFind генерирует s.find(sub): позиция считается в символах, -1 — не найдено
+func (g *Generator) generateFind(call *ast.CallExpression, inFunction bool) (string, error) {
+	s, args, err := g.methodArguments(call, inFunction, "str", "str")
+	if err != nil {
+		return "", err
+	}
+	g.useHelper("gopyFind")
+	return fmt.Sprintf("gopyFind(%s, %s)", s, args[0]), nil
+}
+
+// generateCount генерирует s.count(sub): число непересекающихся вхождений
+func (g *Generator) generateCount(call *ast.CallExpression, inFunction bool) (string, error) {
+	s, args, err := g.methodArguments(call, inFunction, "str", "str")
+	if err != nil {
+		return "", err
+	}
+	g.imports["strings"] = true
+	return fmt.Sprintf("int64(strings.Count(%s, %s))", s, args[0]), nil
+}
+
+// generateFormat генерирует s.format(a, b, name=c): поля {}, {0} и {name}
+// подставляются во время выполнения
+func (g *Generator) generateFormat(call *ast.CallExpression, inFunction bool) (string, error) {
+	s, err := g.methodReceiver(call, inFunction, "str")
+	if err != nil {
+		return "", err
+	}
+	values := []string{}
+	named := []string{}
+	for _, arg := range call.Arguments {
+		value := arg
+		kw, isKeyword := arg.(*ast.KeywordArgument)
+		if isKeyword {
+			value = kw.Value
+		}
+		code, err := g.generateExpressionWithCast(value, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		if isKeyword {
+			named = append(named, fmt.Sprintf("%q: %s", kw.Name.Value, g.boxValue(code, value)))
+		} else {
+			values = append(values, g.boxValue(code, value))
+		}
+	}
+	g.useHelper("gopyFormat")
+	return fmt.Sprintf("gopyFormat(%s, []interface{}{%s}, map[string]interface{}{%s}, %d)",
+		s, strings.Join(values, ", "), strings.Join(named, ", "), call.Token.Line), nil
+}
@@ -7,6 +7,7 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // номер строки исходного файла (с 1)
 }
 
 const (
@@ -28,18 +29,29 @@ const (
 	SLASH    = "/"
 	LT       = "<"
 	GT       = ">"
+	LT_EQ    = "<="
+	GT_EQ    = ">="
 	EQ       = "=="
 	NOT_EQ   = "!="
 	AND      = "AND"
 	OR       = "OR"
+	ORELSE   = "ORELSE" // or else
 	NOT      = "NOT"
+	PIPE     = "|"
+	AMPERSAND = "&"
+	CARET     = "^"
 
 	// Разделители
 	COMMA     = ","
+	COLON     = ":"
+	ARROW     = "->"
+	LARROW    = "<-" // отправка в канал и получение из канала
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACKET  = "["
 	RBRACKET  = "]"
+	LBRACE    = "{"
+	RBRACE    = "}"
 	NEWLINE   = "NEWLINE" // Новый разделитель - новая строка
 
 	// Отступы
@@ -58,6 +70,21 @@ const (
 	DEF 	 = "DEF"
 	PRINT 	 = "PRINT"
 	LET      = "LET"
+	INTERFACE = "INTERFACE"
+	LAMBDA    = "LAMBDA"
+	MATCH     = "MATCH"
+	CASE      = "CASE"
+	TRY       = "TRY"
+	EXCEPT    = "EXCEPT"
+	FINALLY   = "FINALLY"
+	AS        = "AS"
+	RAISE     = "RAISE"
+	GO        = "GO"
+	SELECT    = "SELECT"
+	WITH      = "WITH"
+	DEFER     = "DEFER"
+	ASSERT    = "ASSERT"
+	IMPORT    = "IMPORT"
 )
 
 var keywords = map[string]TokenType{
@@ -72,6 +99,21 @@ var keywords = map[string]TokenType{
 	"in":     IN,
 	"print":  PRINT,
 	"let":    LET,
+	"interface": INTERFACE,
+	"lambda": LAMBDA,
+	"match":  MATCH,
+	"case":   CASE,
+	"try":    TRY,
+	"except": EXCEPT,
+	"finally": FINALLY,
+	"as":     AS,
+	"raise":  RAISE,
+	"go":     GO,
+	"select": SELECT,
+	"with":   WITH,
+	"defer":  DEFER,
+	"assert": ASSERT,
+	"import": IMPORT,
 	"and":    AND,
 	"or":     OR,
 	"not":    NOT,
@@ -0,0 +1,429 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// errorKinds сопоставляет встроенные виды ошибок Gopy переменным времени выполнения
+var errorKinds = map[string]string{
+	"Error":          "gopyBaseError",
+	"ValueError":     "gopyValueError",
+	"TypeError":      "gopyTypeError",
+	"IndexError":     "gopyIndexError",
+	"KeyError":       "gopyKeyError",
+	"OSError":        "gopyOSError",
+	"AssertionError": "gopyAssertionError",
+}
+
+// tryReturn описывает return внутри try или with. Их тело выполняется во вложенной
+// функции, поэтому return сохраняет значение в переменные результата и выходит
+// из неё, а код после инструкции возвращает сохранённое значение из самой функции
+type tryReturn struct {
+	flag    string
+	results []string
+}
+
+// enterBody начинает генерацию тела функции или метода и возвращает функцию,
+// восстанавливающую состояние объемлющей функции
+func (g *Generator) enterBody(body *ast.BlockStatement) func() {
+	ret, tail, reraise := g.tryReturn, g.tailStatement, g.reraise
+	g.tryReturn, g.tailStatement, g.reraise = nil, nil, ""
+	if n := len(body.Statements); n > 0 {
+		g.tailStatement = body.Statements[n-1]
+	}
+	return func() { g.tryReturn, g.tailStatement, g.reraise = ret, tail, reraise }
+}
+
+// returnCode превращает return X в код, который возвращает X из текущей функции
+// или, внутри try, сохраняет X и выходит из вложенной функции try
+func (g *Generator) returnCode(code string) string {
+	if g.tryReturn == nil {
+		return code
+	}
+	values := strings.TrimPrefix(code, "return ")
+	if g.tryReturn.flag == "" {
+		// Инструкция в конце функции: сохранённое значение возвращается без проверки флага
+		return fmt.Sprintf("%s = %s\n\treturn", strings.Join(g.tryReturn.results, ", "), values)
+	}
+	return fmt.Sprintf("%s = %s\n\t%s = true\n\treturn", strings.Join(g.tryReturn.results, ", "), values, g.tryReturn.flag)
+}
+
+// containsReturn сообщает, есть ли return среди инструкций, включая вложенные
+// блоки, но не считая вложенных функций
+func containsReturn(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStatement:
+			return true
+		case *ast.ExpressionStatement:
+			if ifExpr, ok := stmt.Expression.(*ast.IfExpression); ok {
+				if containsReturn(ifExpr.Consequence.Statements) ||
+					(ifExpr.Alternative != nil && containsReturn(ifExpr.Alternative.Statements)) {
+					return true
+				}
+			}
+		case *ast.ForStatement:
+			if containsReturn(stmt.Body.Statements) {
+				return true
+			}
+		case *ast.MatchStatement:
+			for _, c := range stmt.Cases {
+				if containsReturn(c.Body.Statements) {
+					return true
+				}
+			}
+		case *ast.TryStatement:
+			if blocksReturn(tryBlocks(stmt)) {
+				return true
+			}
+		case *ast.WithStatement:
+			if containsReturn(stmt.Body.Statements) {
+				return true
+			}
+		case *ast.SelectStatement:
+			for _, c := range stmt.Cases {
+				if containsReturn(c.Body.Statements) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// blocksReturn сообщает, есть ли return в одном из блоков
+func blocksReturn(blocks []*ast.BlockStatement) bool {
+	for _, block := range blocks {
+		if containsReturn(block.Statements) {
+			return true
+		}
+	}
+	return false
+}
+
+// tryBlocks возвращает все блоки try: тело, ветки except и finally
+func tryBlocks(stmt *ast.TryStatement) []*ast.BlockStatement {
+	blocks := []*ast.BlockStatement{stmt.Body}
+	for _, h := range stmt.Handlers {
+		blocks = append(blocks, h.Body)
+	}
+	if stmt.Finally != nil {
+		blocks = append(blocks, stmt.Finally)
+	}
+	return blocks
+}
+
+// generateTry генерирует try/except/finally. Ошибка внутри try — это panic
+// с ошибкой Go; тело выполняется во вложенной функции, отложенный вызов которой
+// перехватывает ошибку через recover и выбирает ветку except по errors.Is,
+// а finally выполняется отложенным вызовом после неё
+func (g *Generator) generateTry(stmt *ast.TryStatement, inFunction bool) (string, error) {
+	for i, h := range stmt.Handlers {
+		if len(h.Kinds) == 0 && i != len(stmt.Handlers)-1 {
+			return "", fmt.Errorf("строка %d: except без вида ошибки должен быть последним", h.Token.Line)
+		}
+	}
+
+	return g.generateClosure(stmt, tryBlocks(stmt), func() (string, error) {
+		return g.generateTryFunction(stmt, inFunction)
+	})
+}
+
+// generateClosure генерирует инструкцию, блоки которой выполняются во вложенной
+// функции Go (try, with). Переменные, которым впервые присваивают внутри блоков,
+// объявляются заранее, чтобы они были видны после инструкции, а return внутри
+// блоков возвращает значение из объемлющей функции
+func (g *Generator) generateClosure(stmt ast.Statement, blocks []*ast.BlockStatement, closure func() (string, error)) (string, error) {
+	var out strings.Builder
+	decl, err := g.predeclareAssignments(blocks)
+	if err != nil {
+		return "", err
+	}
+	out.WriteString(decl)
+
+	outer := g.tryReturn
+	var ret *tryReturn
+	if blocksReturn(blocks) {
+		if g.currentResults == nil {
+			return "", fmt.Errorf("строка %d: return вне функции", statementLine(stmt))
+		}
+		g.tempCounter++
+		ret = &tryReturn{flag: fmt.Sprintf("gopyReturned%d", g.tempCounter)}
+		for i, typ := range g.currentResults {
+			goTyp, err := g.goType(typ)
+			if err != nil {
+				return "", err
+			}
+			name := fmt.Sprintf("gopyResult%d", g.tempCounter)
+			if len(g.currentResults) > 1 {
+				name = fmt.Sprintf("gopyResult%d_%d", g.tempCounter, i)
+			}
+			ret.results = append(ret.results, name)
+			out.WriteString(fmt.Sprintf("\tvar %s %s\n", name, goTyp))
+		}
+		if stmt == g.tailStatement {
+			ret.flag = ""
+		} else {
+			out.WriteString(fmt.Sprintf("\t%s := false\n", ret.flag))
+		}
+		g.tryReturn = ret
+	}
+	code, err := closure()
+	g.tryReturn = outer
+	if err != nil {
+		return "", err
+	}
+	out.WriteString(code)
+
+	if ret != nil {
+		results := strings.Join(ret.results, ", ")
+		if stmt == g.tailStatement {
+			// Инструкция в конце функции: если return не выполнился, возвращаются нулевые значения
+			out.WriteString("\t" + g.returnCode("return "+results) + "\n")
+		} else {
+			out.WriteString(fmt.Sprintf("\tif %s {\n%s\t}\n", ret.flag, indentCode("\t"+g.returnCode("return "+results)+"\n")))
+		}
+	}
+	return out.String(), nil
+}
+
+// statementLine возвращает строку исходного файла, с которой начинается инструкция
+func statementLine(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.TryStatement:
+		return stmt.Token.Line
+	case *ast.WithStatement:
+		return stmt.Token.Line
+	}
+	return 0
+}
+
+func (g *Generator) generateTryFunction(stmt *ast.TryStatement, inFunction bool) (string, error) {
+	var out strings.Builder
+	out.WriteString("\tfunc() {\n")
+	if stmt.Finally != nil {
+		finally, err := g.generateBlockStatementWithCast(stmt.Finally, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString("\t\tdefer func() {\n")
+		out.WriteString(indentCode(indentCode(finally)))
+		out.WriteString("\t\t}()\n")
+	}
+	if len(stmt.Handlers) > 0 {
+		handlers, err := g.generateHandlers(stmt.Handlers, inFunction)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString("\t\tdefer func() {\n")
+		out.WriteString("\t\t\tif gopyRecovered := recover(); gopyRecovered != nil {\n")
+		out.WriteString(indentCode(indentCode(indentCode(handlers))))
+		out.WriteString("\t\t\t}\n")
+		out.WriteString("\t\t}()\n")
+	}
+	body, err := g.generateBlockStatementWithCast(stmt.Body, inFunction)
+	if err != nil {
+		return "", err
+	}
+	out.WriteString(indentCode(body))
+	out.WriteString("\t}()\n")
+	return out.String(), nil
+}
+
+// generateHandlers генерирует выбор ветки except по виду ошибки. Ошибка, которую
+// не перехватила ни одна ветка, передаётся дальше
+func (g *Generator) generateHandlers(handlers []*ast.ExceptClause, inFunction bool) (string, error) {
+	// raise без значения в ветке except выбрасывает перехваченную ошибку дальше
+	reraise := g.reraise
+	g.reraise = "gopyRecovered"
+	defer func() { g.reraise = reraise }()
+
+	// Единственная ветка except без вида и имени не проверяет ошибку
+	if len(handlers) == 1 && len(handlers[0].Kinds) == 0 && handlers[0].Name == nil {
+		return g.generateBlockStatementWithCast(handlers[0].Body, inFunction)
+	}
+
+	var out strings.Builder
+	g.useHelper("gopyCatch")
+	out.WriteString("\tgopyErr := gopyCatch(gopyRecovered)\n")
+	out.WriteString("\tswitch {\n")
+	caught := false
+	for _, h := range handlers {
+		if len(h.Kinds) == 0 {
+			caught = true
+			out.WriteString("\tdefault:\n")
+		} else {
+			conditions := []string{}
+			for _, kind := range h.Kinds {
+				condition, err := g.exceptCondition(kind)
+				if err != nil {
+					return "", err
+				}
+				conditions = append(conditions, condition)
+			}
+			out.WriteString(fmt.Sprintf("\tcase %s:\n", strings.Join(conditions, " || ")))
+		}
+
+		bindings := []patternBinding{}
+		if h.Name != nil && usesName(h.Body, h.Name.Value) {
+			// Ошибка класса ошибки связывается со своим типом, чтобы были доступны её поля
+			if len(h.Kinds) == 1 && g.isErrorClass(h.Kinds[0].String()) {
+				name := h.Kinds[0].String()
+				bindings = append(bindings, patternBinding{name: h.Name.Value, typ: name})
+				out.WriteString(fmt.Sprintf("\t\tvar %s *%s\n\t\terrors.As(gopyErr, &%s)\n", h.Name.Value, name, h.Name.Value))
+			} else {
+				bindings = append(bindings, patternBinding{name: h.Name.Value})
+				out.WriteString(fmt.Sprintf("\t\t%s := gopyErr\n", h.Name.Value))
+			}
+		}
+		restore := g.bindPattern(bindings)
+		body, err := g.generateBlockStatementWithCast(h.Body, inFunction)
+		restore()
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(indentCode(body))
+	}
+	if !caught {
+		out.WriteString("\tdefault:\n\t\tpanic(gopyRecovered)\n")
+	}
+	out.WriteString("\t}\n")
+	return out.String(), nil
+}
+
+// exceptCondition возвращает проверку вида ошибки: встроенный вид Gopy
+// или ошибка-значение пакета Go, например os.ErrNotExist
+func (g *Generator) exceptCondition(kind ast.Expression) (string, error) {
+	g.imports["errors"] = true
+	switch kind := kind.(type) {
+	case *ast.Identifier:
+		if name, ok := g.errorKind(kind.Value); ok {
+			g.useHelper("gopyError")
+			return fmt.Sprintf("errors.Is(gopyErr, %s)", name), nil
+		}
+	case *ast.DotExpression:
+		if pkg, ok := kind.Left.(*ast.Identifier); ok {
+			g.imports[pkg.Value] = true
+			return fmt.Sprintf("errors.Is(gopyErr, %s.%s)", pkg.Value, kind.Right.Value), nil
+		}
+	}
+	return "", fmt.Errorf("неизвестный вид ошибки в except: %s", kind.String())
+}
+
+// predeclareAssignments объявляет переменные, которые впервые получают значение
+// внутри блоков, выполняемых во вложенной функции (try, except, finally, with)
+func (g *Generator) predeclareAssignments(blocks []*ast.BlockStatement) (string, error) {
+	inMain := g.currentResults == nil
+	var out strings.Builder
+	var walk func(stmts []ast.Statement) error
+	declare := func(name string, value ast.Expression) error {
+		if _, ok := g.variableTypes[name]; ok || name == "_" || (inMain && g.declaredVariables[name]) {
+			return nil
+		}
+		typ := ""
+		if value != nil {
+			typ = g.staticType(value)
+		}
+		goTyp, err := g.goType(typ)
+		if err != nil {
+			return err
+		}
+		if _, isCall := value.(*ast.CallExpression); inMain && typ == "int" && !isCall {
+			// Целые переменные main имеют тип int, а вызовы функций возвращают int64
+			goTyp = "int"
+		}
+		out.WriteString(fmt.Sprintf("\tvar %s %s\n", name, goTyp))
+		g.variableTypes[name] = typ
+		delete(g.localFunctions, name)
+		if inMain {
+			g.declaredVariables[name] = true
+		}
+		return nil
+	}
+	walk = func(stmts []ast.Statement) error {
+		for _, s := range stmts {
+			var err error
+			switch s := s.(type) {
+			case *ast.AssignmentStatement:
+				switch target := s.Name.(type) {
+				case *ast.Identifier:
+					err = declare(target.Value, s.Value)
+				case *ast.TupleLiteral:
+					for _, el := range target.Elements {
+						if starred, ok := el.(*ast.StarredExpression); ok {
+							el = starred.Value
+						}
+						if ident, ok := el.(*ast.Identifier); ok {
+							if err = declare(ident.Value, nil); err != nil {
+								break
+							}
+						}
+					}
+				}
+			case *ast.LetStatement:
+				err = declare(s.Name.Value, s.Value)
+			case *ast.ExpressionStatement:
+				if ifExpr, ok := s.Expression.(*ast.IfExpression); ok {
+					if err = walk(ifExpr.Consequence.Statements); err == nil && ifExpr.Alternative != nil {
+						err = walk(ifExpr.Alternative.Statements)
+					}
+				}
+			case *ast.TryStatement:
+				for _, block := range tryBlocks(s) {
+					if err = walk(block.Statements); err != nil {
+						break
+					}
+				}
+			case *ast.WithStatement:
+				err = walk(s.Body.Statements)
+			case *ast.MatchStatement:
+				for _, c := range s.Cases {
+					if err = walk(c.Body.Statements); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, block := range blocks {
+		if err := walk(block.Statements); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+// generateOrElse генерирует value or else default: ошибка при вычислении value
+// перехватывается, и результатом становится default
+func (g *Generator) generateOrElse(expr *ast.OrElseExpression, inFunction bool) (string, error) {
+	typ := g.staticType(expr)
+	goTyp, err := g.goType(typ)
+	if err != nil {
+		return "", err
+	}
+	value, err := g.generateExpressionWithCast(expr.Value, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	def, err := g.generateExpressionWithCast(expr.Default, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("func() (gopyValue %s) {\n\tdefer func() {\n\t\tif recover() != nil {\n\t\t\tgopyValue = %s\n\t\t}\n\t}()\n\treturn %s\n}()",
+		goTyp, g.storeValue(def, expr.Default, typ), g.storeValue(value, expr.Value, typ)), nil
+}
+
+// orElseType возвращает общий тип значения и значения по умолчанию
+func (g *Generator) orElseType(expr *ast.OrElseExpression) string {
+	typ := g.staticType(expr.Value)
+	if typ != g.staticType(expr.Default) {
+		return ""
+	}
+	return typ
+}
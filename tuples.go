@@ -0,0 +1,418 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"sort"
+	"strings"
+)
+
+// resultTypes возвращает типы Gopy результатов функции. Функция без аннотации,
+// все return которой возвращают кортеж одной длины, возвращает несколько значений
+// неизвестного типа
+func resultTypes(returnType *ast.Identifier, returnTypes []*ast.Identifier, body *ast.BlockStatement) []string {
+	if len(returnTypes) > 0 {
+		types := []string{}
+		for _, t := range returnTypes {
+			types = append(types, t.Value)
+		}
+		return types
+	}
+	if returnType == nil && body != nil {
+		if n := tupleArity(body); n > 1 {
+			return make([]string, n)
+		}
+	}
+	return []string{typeName(returnType)}
+}
+
+// tupleArity возвращает длину кортежа, который возвращают все return тела,
+// или 0, если хотя бы один return возвращает что-то другое
+func tupleArity(body *ast.BlockStatement) int {
+	arity := 0
+	var walk func(block *ast.BlockStatement) bool
+	walk = func(block *ast.BlockStatement) bool {
+		for _, stmt := range block.Statements {
+			switch stmt := stmt.(type) {
+			case *ast.ReturnStatement:
+				tuple, ok := stmt.ReturnValue.(*ast.TupleLiteral)
+				if !ok || hasStarred(tuple.Elements) || (arity != 0 && arity != len(tuple.Elements)) {
+					return false
+				}
+				arity = len(tuple.Elements)
+			case *ast.ForStatement:
+				if !walk(stmt.Body) {
+					return false
+				}
+			case *ast.MatchStatement:
+				for _, c := range stmt.Cases {
+					if !walk(c.Body) {
+						return false
+					}
+				}
+			case *ast.TryStatement:
+				for _, block := range tryBlocks(stmt) {
+					if !walk(block) {
+						return false
+					}
+				}
+			case *ast.WithStatement:
+				if !walk(stmt.Body) {
+					return false
+				}
+			case *ast.SelectStatement:
+				for _, c := range stmt.Cases {
+					if !walk(c.Body) {
+						return false
+					}
+				}
+			case *ast.ExpressionStatement:
+				if ifExpr, ok := stmt.Expression.(*ast.IfExpression); ok {
+					if !walk(ifExpr.Consequence) {
+						return false
+					}
+					if ifExpr.Alternative != nil && !walk(ifExpr.Alternative) {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	}
+	if !walk(body) {
+		return 0
+	}
+	return arity
+}
+
+func hasStarred(elements []ast.Expression) bool {
+	for _, el := range elements {
+		if _, ok := el.(*ast.StarredExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// goResult возвращает список результатов функции Go: int64 или (int64, string)
+func (g *Generator) goResult(types []string) (string, error) {
+	results := []string{}
+	for _, typ := range types {
+		goTyp, err := g.goType(typ)
+		if err != nil {
+			return "", err
+		}
+		results = append(results, goTyp)
+	}
+	if len(results) == 1 {
+		return results[0], nil
+	}
+	return "(" + strings.Join(results, ", ") + ")", nil
+}
+
+// resultList форматирует типы результатов для сообщений об ошибках: int или (int, str)
+func resultList(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return "(" + strings.Join(types, ", ") + ")"
+}
+
+// resultCount возвращает число значений, которые возвращает вызов известной функции
+func (g *Generator) resultCount(call ast.Expression) int {
+	callExpr, ok := call.(*ast.CallExpression)
+	if !ok {
+		return 1
+	}
+	if sig := g.lookupSignature(callExpr.Function); sig != nil {
+		return len(sig.results)
+	}
+	return 1
+}
+
+// generateTuple генерирует кортеж как значение времени выполнения
+func (g *Generator) generateTuple(tuple *ast.TupleLiteral, inFunction bool) (string, error) {
+	if hasStarred(tuple.Elements) {
+		return "", fmt.Errorf("выражение со звёздочкой допустимо только в присваивании: %s", tuple.String())
+	}
+	elements := []string{}
+	for _, el := range tuple.Elements {
+		str, err := g.generateExpressionWithCast(el, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		elements = append(elements, g.boxValue(str, el))
+	}
+	// Значение имеет тип gopyTuple, а не структуры: переменной можно
+	// присвоить кортеж другой длины
+	return fmt.Sprintf("gopyTuple(%s{%s})", g.tupleType(len(elements)), strings.Join(elements, ", ")), nil
+}
+
+// packResults превращает вызов функции с несколькими результатами в кортеж,
+// когда он используется как одно значение: t = divmod(7, 2)
+func (g *Generator) packResults(call string, count int) string {
+	names := []string{}
+	for i := 0; i < count; i++ {
+		names = append(names, fmt.Sprintf("gopyR%d", i))
+	}
+	list := strings.Join(names, ", ")
+	return fmt.Sprintf("func() gopyTuple { %s := %s; return %s{%s} }()", list, call, g.tupleType(count), list)
+}
+
+// tupleType отмечает длину кортежа как используемую и возвращает имя структуры
+// кортежа этой длины
+func (g *Generator) tupleType(n int) string {
+	g.useHelper("gopyTuple")
+	g.tupleArities[n] = true
+	return fmt.Sprintf("gopyTuple%d", n)
+}
+
+// tupleTypes генерирует структуры кортежей всех использованных длин. Поля
+// структуры имеют тип interface{}, поэтому структура сравнима: два кортежа
+// равны, если равны их элементы, и кортеж может быть ключом map
+func (g *Generator) tupleTypes() string {
+	if g.helpers["gopyNewTuple"] {
+		// Срез кортежа может иметь любую длину не больше исходной
+		longest := 0
+		for n := range g.tupleArities {
+			if n > longest {
+				longest = n
+			}
+		}
+		for n := 0; n <= longest; n++ {
+			g.tupleArities[n] = true
+		}
+	}
+	arities := []int{}
+	for n := range g.tupleArities {
+		arities = append(arities, n)
+	}
+	sort.Ints(arities)
+
+	var out strings.Builder
+	for _, n := range arities {
+		fields, values := []string{}, []string{}
+		for i := 0; i < n; i++ {
+			fields = append(fields, fmt.Sprintf("v%d", i))
+			values = append(values, fmt.Sprintf("t.v%d", i))
+		}
+		if n == 0 {
+			out.WriteString("type gopyTuple0 struct{}\n\n")
+		} else {
+			out.WriteString(fmt.Sprintf("type gopyTuple%d struct {\n\t%s interface{}\n}\n\n", n, strings.Join(fields, ", ")))
+		}
+		out.WriteString(fmt.Sprintf("func (t gopyTuple%d) gopyItems() []interface{} {\n\treturn []interface{}{%s}\n}\n\n", n, strings.Join(values, ", ")))
+	}
+	if g.helpers["gopyNewTuple"] {
+		out.WriteString("func gopyNewTuple(items []interface{}) gopyTuple {\n\tswitch len(items) {\n")
+		for _, n := range arities {
+			items := []string{}
+			for i := 0; i < n; i++ {
+				items = append(items, fmt.Sprintf("items[%d]", i))
+			}
+			out.WriteString(fmt.Sprintf("\tcase %d:\n\t\treturn gopyTuple%d{%s}\n", n, n, strings.Join(items, ", ")))
+		}
+		out.WriteString("\t}\n\tpanic(\"gopy: кортеж неизвестной длины\")\n}\n\n")
+	}
+	return out.String()
+}
+
+// generateTupleReturn генерирует return a, b. Функция с несколькими результатами
+// возвращает их как значения Go, остальные функции возвращают кортеж
+func (g *Generator) generateTupleReturn(stmt *ast.ReturnStatement, inFunction bool) (string, error) {
+	if len(g.currentResults) < 2 {
+		val, err := g.generateExpressionWithCast(stmt.ReturnValue, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("return %s", val), nil
+	}
+
+	tuple, ok := stmt.ReturnValue.(*ast.TupleLiteral)
+	if !ok {
+		// return f(), где f возвращает столько же значений
+		if call, isCall := stmt.ReturnValue.(*ast.CallExpression); isCall && g.resultCount(call) == len(g.currentResults) {
+			val, err := g.generateCall(call, inFunction)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("return %s", val), nil
+		}
+		return "", fmt.Errorf("return должен вернуть %d значений, получено 1", len(g.currentResults))
+	}
+	if len(tuple.Elements) != len(g.currentResults) {
+		return "", fmt.Errorf("return должен вернуть %d значений, получено %d", len(g.currentResults), len(tuple.Elements))
+	}
+	values := []string{}
+	for i, el := range tuple.Elements {
+		val, err := g.generateExpressionWithCast(el, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, g.coerce(val, el, g.currentResults[i]))
+	}
+	return fmt.Sprintf("return %s", strings.Join(values, ", ")), nil
+}
+
+// generateTupleAssignment генерирует множественное присваивание: a, b = b, a,
+// x, y = f() и распаковку со звёздочкой first, *rest = xs. Возвращает код и имена
+// впервые объявленных переменных
+func (g *Generator) generateTupleAssignment(targets *ast.TupleLiteral, value ast.Expression, inFunction bool) (string, []string, error) {
+	star := -1
+	for i, target := range targets.Elements {
+		if _, ok := target.(*ast.StarredExpression); ok {
+			if star >= 0 {
+				return "", nil, fmt.Errorf("в присваивании может быть только одна цель со звёздочкой")
+			}
+			star = i
+		}
+	}
+
+	var out strings.Builder
+	var values, types []string
+	count := len(targets.Elements)
+	tuple, isTuple := value.(*ast.TupleLiteral)
+	switch {
+	case isTuple && star < 0 && !hasStarred(tuple.Elements):
+		// a, b = b, a — параллельное присваивание Go
+		if len(tuple.Elements) != count {
+			return "", nil, fmt.Errorf("нельзя распаковать %d значений в %d переменных", len(tuple.Elements), count)
+		}
+		for _, el := range tuple.Elements {
+			val, err := g.generateExpressionWithCast(el, inFunction, false)
+			if err != nil {
+				return "", nil, err
+			}
+			values = append(values, val)
+			types = append(types, g.staticType(el))
+		}
+	case star < 0 && g.resultCount(value) > 1:
+		// x, y = f() — функция с несколькими результатами
+		if n := g.resultCount(value); n != count {
+			return "", nil, fmt.Errorf("нельзя распаковать %d значений в %d переменных", n, count)
+		}
+		call, err := g.generateCall(value.(*ast.CallExpression), inFunction)
+		if err != nil {
+			return "", nil, err
+		}
+		values = []string{call}
+		types = g.lookupSignature(value.(*ast.CallExpression).Function).results
+	default:
+		// Кортеж или список, длина которого известна только во время выполнения
+		src, err := g.generateExpressionWithCast(value, inFunction, false)
+		if err != nil {
+			return "", nil, err
+		}
+		g.tempCounter++
+		tmp := fmt.Sprintf("gopyTmp%d", g.tempCounter)
+		g.useHelper("gopyUnpack")
+		out.WriteString(fmt.Sprintf("\t%s := gopyUnpack(%s, %d, %d)\n", tmp, src, count, star))
+		for i := range targets.Elements {
+			if i == star {
+				g.useHelper("gopyList")
+				values = append(values, fmt.Sprintf("%s[%d].(*gopyList)", tmp, i))
+				types = append(types, "list")
+			} else {
+				values = append(values, fmt.Sprintf("%s[%d]", tmp, i))
+				types = append(types, "")
+			}
+		}
+	}
+
+	names := []string{}
+	stores := []string{}
+	declared := []string{}
+	allNew := true
+	for i, target := range targets.Elements {
+		if starred, ok := target.(*ast.StarredExpression); ok {
+			target = starred.Value
+		}
+		switch target := target.(type) {
+		case *ast.Identifier:
+			if target.Value == "_" {
+				names = append(names, "_")
+				continue
+			}
+			if typ, ok := g.variableTypes[target.Value]; ok {
+				allNew = false
+				if len(values) == count {
+					values[i] = g.assertType(values[i], types[i], typ)
+				}
+			} else {
+				declared = append(declared, target.Value)
+			}
+			names = append(names, target.Value)
+		case *ast.IndexExpression:
+			// Элемент списка присваивается через временную переменную,
+			// чтобы сохранить поддержку отрицательных индексов
+			allNew = false
+			g.tempCounter++
+			tmp := fmt.Sprintf("gopyTmp%d", g.tempCounter)
+			out.WriteString(fmt.Sprintf("\tvar %s interface{}\n", tmp))
+			store, err := g.generateIndexStore(target, tmp, inFunction)
+			if err != nil {
+				return "", nil, err
+			}
+			stores = append(stores, store)
+			names = append(names, tmp)
+		case *ast.DotExpression:
+			allNew = false
+			code, err := g.generateExpressionWithCast(target, inFunction, false)
+			if err != nil {
+				return "", nil, err
+			}
+			names = append(names, code)
+		default:
+			return "", nil, fmt.Errorf("нельзя присвоить значение выражению %s", target.String())
+		}
+	}
+
+	op := "="
+	if allNew && len(declared) > 0 {
+		op = ":="
+	} else {
+		// Часть переменных уже объявлена: новые объявляем заранее,
+		// чтобы := не создал копии существующих
+		for _, name := range declared {
+			goTyp, err := g.goType(types[indexOfTarget(targets, name)])
+			if err != nil {
+				return "", nil, err
+			}
+			out.WriteString(fmt.Sprintf("\tvar %s %s\n", name, goTyp))
+		}
+	}
+	for _, name := range declared {
+		g.variableTypes[name] = types[indexOfTarget(targets, name)]
+		delete(g.localFunctions, name)
+	}
+	out.WriteString(fmt.Sprintf("\t%s %s %s\n", strings.Join(names, ", "), op, strings.Join(values, ", ")))
+	for _, store := range stores {
+		out.WriteString(store)
+	}
+	return out.String(), declared, nil
+}
+
+// indexOfTarget возвращает позицию переменной среди целей присваивания
+func indexOfTarget(targets *ast.TupleLiteral, name string) int {
+	for i, target := range targets.Elements {
+		if starred, ok := target.(*ast.StarredExpression); ok {
+			target = starred.Value
+		}
+		if ident, ok := target.(*ast.Identifier); ok && ident.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// assertType приводит значение неизвестного типа к типу переменной, которой оно присваивается
+func (g *Generator) assertType(code string, valueType string, typ string) string {
+	if valueType != "" || typ == "" {
+		return code
+	}
+	goTyp, err := g.goType(typ)
+	if err != nil || goTyp == "interface{}" {
+		return code
+	}
+	return fmt.Sprintf("%s.(%s)", code, goTyp)
+}
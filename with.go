@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"fmt"
+	"gopy/ast"
+	"strings"
+)
+
+// generateWith генерирует with. Блок выполняется во вложенной функции: ресурс
+// захватывается в её начале, а освобождение откладывается через defer, поэтому
+// выполняется и при ошибке, и при return. Поддерживаются Lock, файлы и объекты
+// классов с методами enter и exit
+func (g *Generator) generateWith(stmt *ast.WithStatement, inFunction bool) (string, error) {
+	typ := g.staticType(stmt.Value)
+	class := g.declaredClasses[typ]
+	bindType := typ
+	switch {
+	case typ == "Lock", typ == "file":
+	case class != nil:
+		enter, err := contextMethod(class, "enter")
+		if err != nil {
+			return "", err
+		}
+		if _, err := contextMethod(class, "exit"); err != nil {
+			return "", err
+		}
+		results := resultTypes(enter.ReturnType, enter.ReturnTypes, enter.Body)
+		if len(results) > 1 {
+			return "", fmt.Errorf("метод %s.enter должен возвращать одно значение", typ)
+		}
+		bindType = results[0]
+	case typ == "":
+		return "", fmt.Errorf("строка %d: тип значения в with неизвестен, укажите аннотацию типа", stmt.Token.Line)
+	default:
+		return "", fmt.Errorf("строка %d: with ожидает Lock, файл или объект с методами enter и exit, получено %s", stmt.Token.Line, typ)
+	}
+
+	return g.generateClosure(stmt, []*ast.BlockStatement{stmt.Body}, func() (string, error) {
+		var out strings.Builder
+		value, err := g.generateExpressionWithCast(stmt.Value, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString("\tfunc() {\n")
+
+		bound := stmt.Name != nil && usesName(stmt.Body, stmt.Name.Value)
+		handle := value
+		if _, ok := stmt.Value.(*ast.Identifier); !ok {
+			// Значение вычисляется один раз: оно нужно и для захвата, и для освобождения
+			if bound && class == nil {
+				handle = stmt.Name.Value
+			} else {
+				g.tempCounter++
+				handle = fmt.Sprintf("gopyTmp%d", g.tempCounter)
+			}
+			out.WriteString(fmt.Sprintf("\t\t%s := %s\n", handle, value))
+		}
+		switch {
+		case typ == "Lock":
+			out.WriteString(fmt.Sprintf("\t\t%s.Lock()\n\t\tdefer %s.Unlock()\n", handle, handle))
+		case typ == "file":
+			out.WriteString(fmt.Sprintf("\t\tdefer %s.Close()\n", handle))
+		case bound:
+			// Как в Python, имя после as получает результат enter
+			out.WriteString(fmt.Sprintf("\t\t%s := %s.enter()\n\t\tdefer %s.exit()\n", stmt.Name.Value, handle, handle))
+		default:
+			out.WriteString(fmt.Sprintf("\t\t%s.enter()\n\t\tdefer %s.exit()\n", handle, handle))
+		}
+		if bound && handle != stmt.Name.Value && class == nil {
+			out.WriteString(fmt.Sprintf("\t\t%s := %s\n", stmt.Name.Value, handle))
+		}
+
+		bindings := []patternBinding{}
+		if bound {
+			bindings = append(bindings, patternBinding{name: stmt.Name.Value, typ: bindType})
+		}
+		restore := g.bindPattern(bindings)
+		body, err := g.generateBlockStatementWithCast(stmt.Body, inFunction)
+		restore()
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(indentCode(body))
+		out.WriteString("\t}()\n")
+		return out.String(), nil
+	})
+}
+
+// contextMethod возвращает метод enter или exit класса, используемого в with
+func contextMethod(class *ast.ClassStatement, name string) (*ast.MethodStatement, error) {
+	m := findMethod(class, name)
+	if m == nil {
+		return nil, fmt.Errorf("класс %s нельзя использовать в with: нет метода %s", class.Name.Value, name)
+	}
+	if len(methodParameters(m)) > 0 {
+		return nil, fmt.Errorf("метод %s.%s не должен принимать аргументов", class.Name.Value, name)
+	}
+	return m, nil
+}
+
+// generateDefer генерирует defer f(x). Как в Go, аргументы вычисляются сразу,
+// а вызов выполняется при выходе из функции; внутри with и try — при выходе из блока
+func (g *Generator) generateDefer(stmt *ast.DeferStatement, inFunction bool) (string, error) {
+	code, err := g.generateExpressionWithCast(stmt.Call, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("\tdefer %s\n", code), nil
+}
+
+// generateOpen генерирует open(path, mode="r")
+func (g *Generator) generateOpen(call *ast.CallExpression, inFunction bool) (string, error) {
+	args := []string{}
+	for _, arg := range call.Arguments {
+		if lit, ok := arg.(*ast.StringLiteral); ok && arg != call.Arguments[0] {
+			if lit.Value != "r" && lit.Value != "w" && lit.Value != "a" {
+				return "", fmt.Errorf("неизвестный режим открытия файла %q", lit.Value)
+			}
+		}
+		code, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, g.coerce(code, arg, "str"))
+	}
+	if len(args) == 1 {
+		args = append(args, `"r"`)
+	}
+	g.useHelper("gopyOpen")
+	return fmt.Sprintf("gopyOpen(%s, %s, %d)", args[0], args[1], call.Token.Line), nil
+}
+
+// generateFileMethod генерирует вызов метода файла: f.read(), f.write(s), f.close()
+func (g *Generator) generateFileMethod(method *ast.DotExpression, call *ast.CallExpression, inFunction bool) (string, error) {
+	f, err := g.generateExpressionWithCast(method.Left, inFunction, false)
+	if err != nil {
+		return "", err
+	}
+	switch method.Right.Value {
+	case "read", "close":
+		if len(call.Arguments) != 0 {
+			return "", fmt.Errorf("метод файла %s не принимает аргументов", method.Right.Value)
+		}
+		if method.Right.Value == "close" {
+			return fmt.Sprintf("%s.Close()", f), nil
+		}
+		g.useHelper("gopyRead")
+		return fmt.Sprintf("gopyRead(%s, %d)", f, call.Token.Line), nil
+	case "write":
+		if len(call.Arguments) != 1 {
+			return "", fmt.Errorf("метод файла write принимает одну строку, получено аргументов: %d", len(call.Arguments))
+		}
+		arg := call.Arguments[0]
+		if typ := g.staticType(arg); typ != "" && typ != "str" {
+			return "", fmt.Errorf("метод файла write принимает строку, получено %s", typ)
+		}
+		s, err := g.generateExpressionWithCast(arg, inFunction, false)
+		if err != nil {
+			return "", err
+		}
+		g.useHelper("gopyWrite")
+		return fmt.Sprintf("gopyWrite(%s, %s, %d)", f, g.coerce(s, arg, "str"), call.Token.Line), nil
+	}
+	return "", fmt.Errorf("у файла нет метода %s", method.Right.Value)
+}